@@ -0,0 +1,15 @@
+-- +goose Up
+-- Store structured exceptions alongside the plain text stack trace
+
+ALTER TABLE error_events ADD COLUMN IF NOT EXISTS exception_type String DEFAULT '' AFTER stack_trace;
+ALTER TABLE error_events ADD COLUMN IF NOT EXISTS exception_value String DEFAULT '' AFTER exception_type;
+
+-- JSON encoded exception chain with frames, raised exception first
+ALTER TABLE error_events ADD COLUMN IF NOT EXISTS exceptions String DEFAULT '' CODEC(ZSTD(3)) AFTER exception_value;
+
+-- +goose Down
+-- Remove structured exception columns
+
+ALTER TABLE error_events DROP COLUMN IF EXISTS exceptions;
+ALTER TABLE error_events DROP COLUMN IF EXISTS exception_value;
+ALTER TABLE error_events DROP COLUMN IF EXISTS exception_type;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Record the grouping algorithm that created each issue. Existing issues were
+-- grouped by the legacy algorithm (version 1).
+
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS grouping_version UInt8 DEFAULT 1 AFTER tags;
+
+-- +goose Down
+-- Remove the grouping version
+
+ALTER TABLE issues DROP COLUMN IF EXISTS grouping_version;
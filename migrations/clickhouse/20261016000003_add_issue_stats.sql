@@ -0,0 +1,44 @@
+-- +goose Up
+-- Aggregate issue counters from the events themselves. The materialized view
+-- adds every inserted event to its (project, fingerprint) row, so counts stay
+-- exact when several API replicas ingest the same issue concurrently.
+
+CREATE TABLE IF NOT EXISTS issue_stats (
+    project_id String,
+    fingerprint String,
+    first_seen SimpleAggregateFunction(min, DateTime64(3)),
+    last_seen SimpleAggregateFunction(max, DateTime64(3)),
+    event_count SimpleAggregateFunction(sum, UInt64),
+    environments SimpleAggregateFunction(groupUniqArrayArray, Array(String))
+) ENGINE = AggregatingMergeTree()
+ORDER BY (project_id, fingerprint);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS issue_stats_mv TO issue_stats AS
+SELECT
+    project_id,
+    fingerprint,
+    min(timestamp) AS first_seen,
+    max(timestamp) AS last_seen,
+    count() AS event_count,
+    groupUniqArray(environment) AS environments
+FROM error_events
+GROUP BY project_id, fingerprint;
+
+-- Backfill from the stored events. Run with ingestion stopped, events inserted
+-- between the view creation and the backfill would be counted twice.
+INSERT INTO issue_stats
+SELECT
+    project_id,
+    fingerprint,
+    min(timestamp),
+    max(timestamp),
+    count(),
+    groupUniqArray(environment)
+FROM error_events
+GROUP BY project_id, fingerprint;
+
+-- +goose Down
+-- Remove the issue counters
+
+DROP VIEW IF EXISTS issue_stats_mv;
+DROP TABLE IF EXISTS issue_stats;
@@ -0,0 +1,60 @@
+-- +goose Up
+-- Count the unique users affected by each issue. Users are identified by
+-- their ID, then email, then IP address; events without any are not counted.
+
+ALTER TABLE issue_stats ADD COLUMN IF NOT EXISTS users AggregateFunction(uniq, Nullable(String));
+
+DROP VIEW IF EXISTS issue_stats_mv;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS issue_stats_mv TO issue_stats AS
+SELECT
+    project_id,
+    fingerprint,
+    min(timestamp) AS first_seen,
+    max(timestamp) AS last_seen,
+    count() AS event_count,
+    groupUniqArray(environment) AS environments,
+    uniqState(coalesce(
+        concat('id:', nullIf(user_id, '')),
+        concat('email:', nullIf(user_email, '')),
+        concat('ip:', nullIf(user_ip, ''))
+    )) AS users
+FROM error_events
+GROUP BY project_id, fingerprint;
+
+-- Backfill the users of stored events. The other columns only repeat values
+-- that are already aggregated, so they don't change the counters. Run with
+-- ingestion stopped like the previous migration.
+INSERT INTO issue_stats
+SELECT
+    project_id,
+    fingerprint,
+    min(timestamp),
+    max(timestamp),
+    toUInt64(0),
+    groupUniqArray(environment),
+    uniqState(coalesce(
+        concat('id:', nullIf(user_id, '')),
+        concat('email:', nullIf(user_email, '')),
+        concat('ip:', nullIf(user_ip, ''))
+    ))
+FROM error_events
+GROUP BY project_id, fingerprint;
+
+-- +goose Down
+-- Restore the view without users
+
+DROP VIEW IF EXISTS issue_stats_mv;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS issue_stats_mv TO issue_stats AS
+SELECT
+    project_id,
+    fingerprint,
+    min(timestamp) AS first_seen,
+    max(timestamp) AS last_seen,
+    count() AS event_count,
+    groupUniqArray(environment) AS environments
+FROM error_events
+GROUP BY project_id, fingerprint;
+
+ALTER TABLE issue_stats DROP COLUMN IF EXISTS users;
@@ -0,0 +1,116 @@
+-- +goose Up
+-- Hourly and daily rollups of error events for stats and time series, so
+-- they don't scan raw events. Rows are keyed by issue, environment, release
+-- and level within each bucket.
+
+CREATE TABLE IF NOT EXISTS event_rollups_hourly (
+    project_id String,
+    fingerprint String,
+    environment String,
+    release_version String,
+    level Enum8('error' = 1, 'warning' = 2, 'info' = 3, 'debug' = 4),
+    bucket DateTime('UTC'),
+    event_count SimpleAggregateFunction(sum, UInt64),
+    last_seen SimpleAggregateFunction(max, DateTime64(3)),
+    users AggregateFunction(uniq, Nullable(String))
+) ENGINE = AggregatingMergeTree()
+PARTITION BY toYYYYMM(bucket)
+ORDER BY (project_id, fingerprint, bucket, environment, release_version, level)
+TTL bucket + INTERVAL 90 DAY;
+
+CREATE TABLE IF NOT EXISTS event_rollups_daily (
+    project_id String,
+    fingerprint String,
+    environment String,
+    release_version String,
+    level Enum8('error' = 1, 'warning' = 2, 'info' = 3, 'debug' = 4),
+    bucket DateTime('UTC'),
+    event_count SimpleAggregateFunction(sum, UInt64),
+    last_seen SimpleAggregateFunction(max, DateTime64(3)),
+    users AggregateFunction(uniq, Nullable(String))
+) ENGINE = AggregatingMergeTree()
+PARTITION BY toYear(bucket)
+ORDER BY (project_id, fingerprint, bucket, environment, release_version, level)
+TTL bucket + INTERVAL 400 DAY;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS event_rollups_hourly_mv TO event_rollups_hourly AS
+SELECT
+    project_id,
+    fingerprint,
+    environment,
+    ifNull(release_version, '') AS release_version,
+    level,
+    toStartOfHour(timestamp, 'UTC') AS bucket,
+    count() AS event_count,
+    max(timestamp) AS last_seen,
+    uniqState(coalesce(
+        concat('id:', nullIf(user_id, '')),
+        concat('email:', nullIf(user_email, '')),
+        concat('ip:', nullIf(user_ip, ''))
+    )) AS users
+FROM error_events
+GROUP BY project_id, fingerprint, environment, release_version, level, bucket;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS event_rollups_daily_mv TO event_rollups_daily AS
+SELECT
+    project_id,
+    fingerprint,
+    environment,
+    ifNull(release_version, '') AS release_version,
+    level,
+    toStartOfDay(timestamp, 'UTC') AS bucket,
+    count() AS event_count,
+    max(timestamp) AS last_seen,
+    uniqState(coalesce(
+        concat('id:', nullIf(user_id, '')),
+        concat('email:', nullIf(user_email, '')),
+        concat('ip:', nullIf(user_ip, ''))
+    )) AS users
+FROM error_events
+GROUP BY project_id, fingerprint, environment, release_version, level, bucket;
+
+-- Backfill from the stored events. Run with ingestion stopped, events inserted
+-- between the view creation and the backfill would be counted twice.
+INSERT INTO event_rollups_hourly
+SELECT
+    project_id,
+    fingerprint,
+    environment,
+    ifNull(release_version, '') AS release_version,
+    level,
+    toStartOfHour(timestamp, 'UTC') AS bucket,
+    count(),
+    max(timestamp),
+    uniqState(coalesce(
+        concat('id:', nullIf(user_id, '')),
+        concat('email:', nullIf(user_email, '')),
+        concat('ip:', nullIf(user_ip, ''))
+    ))
+FROM error_events
+GROUP BY project_id, fingerprint, environment, release_version, level, bucket;
+
+INSERT INTO event_rollups_daily
+SELECT
+    project_id,
+    fingerprint,
+    environment,
+    ifNull(release_version, '') AS release_version,
+    level,
+    toStartOfDay(timestamp, 'UTC') AS bucket,
+    count(),
+    max(timestamp),
+    uniqState(coalesce(
+        concat('id:', nullIf(user_id, '')),
+        concat('email:', nullIf(user_email, '')),
+        concat('ip:', nullIf(user_ip, ''))
+    ))
+FROM error_events
+GROUP BY project_id, fingerprint, environment, release_version, level, bucket;
+
+-- +goose Down
+-- Remove the rollups
+
+DROP VIEW IF EXISTS event_rollups_daily_mv;
+DROP VIEW IF EXISTS event_rollups_hourly_mv;
+DROP TABLE IF EXISTS event_rollups_daily;
+DROP TABLE IF EXISTS event_rollups_hourly;
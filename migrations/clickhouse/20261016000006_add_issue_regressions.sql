@@ -0,0 +1,13 @@
+-- +goose Up
+-- Track issues that reopened because events arrived after they were resolved
+
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS substatus LowCardinality(String) DEFAULT '' AFTER status;
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS regressed_at Nullable(DateTime64(3)) AFTER grouping_version;
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS regressed_in_release String DEFAULT '' AFTER regressed_at;
+
+-- +goose Down
+-- Remove regression tracking
+
+ALTER TABLE issues DROP COLUMN IF EXISTS regressed_in_release;
+ALTER TABLE issues DROP COLUMN IF EXISTS regressed_at;
+ALTER TABLE issues DROP COLUMN IF EXISTS substatus;
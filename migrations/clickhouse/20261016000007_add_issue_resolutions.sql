@@ -0,0 +1,11 @@
+-- +goose Up
+-- Resolve issues in a release: events from older releases don't reopen them
+
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS resolution LowCardinality(String) DEFAULT '' AFTER substatus;
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS resolved_in_release String DEFAULT '' AFTER resolution;
+
+-- +goose Down
+-- Remove release resolutions
+
+ALTER TABLE issues DROP COLUMN IF EXISTS resolved_in_release;
+ALTER TABLE issues DROP COLUMN IF EXISTS resolution;
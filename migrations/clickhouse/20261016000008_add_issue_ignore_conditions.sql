@@ -0,0 +1,18 @@
+-- +goose Up
+-- Ignore issues until a condition trips: a date passes, the issue reaches an
+-- event or user count, or its event rate exceeds a threshold
+
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS ignore_until Nullable(DateTime64(3)) AFTER resolved_in_release;
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS ignore_until_event_count UInt64 DEFAULT 0 AFTER ignore_until;
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS ignore_until_user_count UInt64 DEFAULT 0 AFTER ignore_until_event_count;
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS ignore_event_rate UInt64 DEFAULT 0 AFTER ignore_until_user_count;
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS ignore_rate_window UInt32 DEFAULT 0 AFTER ignore_event_rate;
+
+-- +goose Down
+-- Remove ignore conditions
+
+ALTER TABLE issues DROP COLUMN IF EXISTS ignore_rate_window;
+ALTER TABLE issues DROP COLUMN IF EXISTS ignore_event_rate;
+ALTER TABLE issues DROP COLUMN IF EXISTS ignore_until_user_count;
+ALTER TABLE issues DROP COLUMN IF EXISTS ignore_until_event_count;
+ALTER TABLE issues DROP COLUMN IF EXISTS ignore_until;
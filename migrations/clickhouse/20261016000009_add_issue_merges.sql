@@ -0,0 +1,12 @@
+-- +goose Up
+-- Merge issues: a merged issue points to the issue it was merged into, which
+-- lists the fingerprints folded into it so their events count towards it
+
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS merged_into String DEFAULT '' AFTER regressed_in_release;
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS merged_fingerprints Array(String) DEFAULT [] AFTER merged_into;
+
+-- +goose Down
+-- Remove issue merges
+
+ALTER TABLE issues DROP COLUMN IF EXISTS merged_fingerprints;
+ALTER TABLE issues DROP COLUMN IF EXISTS merged_into;
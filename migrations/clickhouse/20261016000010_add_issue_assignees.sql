@@ -0,0 +1,11 @@
+-- +goose Up
+-- Assign issues to a user or a team; both live in Postgres
+
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS assignee_type LowCardinality(String) DEFAULT '' AFTER merged_fingerprints;
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS assignee_id String DEFAULT '' AFTER assignee_type;
+
+-- +goose Down
+-- Remove issue assignees
+
+ALTER TABLE issues DROP COLUMN IF EXISTS assignee_id;
+ALTER TABLE issues DROP COLUMN IF EXISTS assignee_type;
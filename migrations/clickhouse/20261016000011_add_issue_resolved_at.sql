@@ -0,0 +1,9 @@
+-- +goose Up
+-- Record when issues were resolved, so events from before that don't reopen them
+
+ALTER TABLE issues ADD COLUMN IF NOT EXISTS resolved_at Nullable(DateTime64(3)) AFTER resolved_in_release;
+
+-- +goose Down
+-- Remove resolution times
+
+ALTER TABLE issues DROP COLUMN IF EXISTS resolved_at;
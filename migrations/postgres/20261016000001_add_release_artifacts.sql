@@ -0,0 +1,21 @@
+-- +goose Up
+-- Release artifacts such as source maps and minified files, keyed by the URL
+-- the browser loaded them from
+
+CREATE TABLE release_artifacts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    release VARCHAR(255) NOT NULL,
+    name TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    checksum VARCHAR(40) NOT NULL,
+    content_type VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(project_id, release, name)
+);
+
+CREATE INDEX idx_release_artifacts_release ON release_artifacts(project_id, release);
+
+-- +goose Down
+DROP TABLE IF EXISTS release_artifacts;
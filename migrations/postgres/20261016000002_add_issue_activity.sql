@@ -0,0 +1,18 @@
+-- +goose Up
+-- Activity log of issues, such as regressions. Issues live in ClickHouse, so
+-- issue_id has no foreign key.
+
+CREATE TABLE issue_activity (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    issue_id UUID NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    api_key_id UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_issue_activity_issue ON issue_activity(issue_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS issue_activity;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Bulk issue operations run in the background; their progress is kept here so
+-- any replica can report it
+
+CREATE TABLE issue_bulk_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    api_key_id UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    operation VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_issue_bulk_jobs_project ON issue_bulk_jobs(project_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS issue_bulk_jobs;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- The user behind each activity entry, taken from the API key that made the
+-- change, so comments and changes keep their author after keys rotate
+
+ALTER TABLE issue_activity ADD COLUMN user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE issue_activity DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- An issue has a single first_seen entry. Replicas that create the same issue
+-- concurrently both record it, and so does an issue created again with the
+-- same ID, so duplicates are dropped on insert.
+
+DELETE FROM issue_activity a
+USING issue_activity b
+WHERE a.type = 'first_seen'
+  AND b.type = 'first_seen'
+  AND a.issue_id = b.issue_id
+  AND (a.created_at, a.id) > (b.created_at, b.id);
+
+CREATE UNIQUE INDEX idx_issue_activity_first_seen ON issue_activity(issue_id, type) WHERE type = 'first_seen';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_issue_activity_first_seen;
@@ -17,6 +17,8 @@ import (
 	"server/internal/models"
 	"server/internal/repository"
 	"server/internal/services"
+	"server/internal/spool"
+	"server/internal/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -59,18 +61,89 @@ func main() {
 	projectsRepo := repository.NewProjectsRepository(postgresDB)
 	eventsRepo := repository.NewEventsRepository(clickhouseDB)
 	issuesRepo := repository.NewIssuesRepository(clickhouseDB)
+	artifactsRepo := repository.NewArtifactsRepository(postgresDB)
+	activityRepo := repository.NewActivityRepository(postgresDB)
+	bulkJobsRepo := repository.NewBulkJobsRepository(postgresDB)
+	assigneesRepo := repository.NewAssigneesRepository(postgresDB)
+
+	// Release artifacts such as source maps
+	var artifactStore storage.Store
+	switch cfg.Artifacts.Backend {
+	case "s3":
+		artifactStore, err = storage.NewS3Store(storage.S3Options{
+			Endpoint:  cfg.Artifacts.S3Endpoint,
+			Region:    cfg.Artifacts.S3Region,
+			Bucket:    cfg.Artifacts.S3Bucket,
+			AccessKey: cfg.Artifacts.S3AccessKey,
+			SecretKey: cfg.Artifacts.S3SecretKey,
+			PathStyle: cfg.Artifacts.S3PathStyle,
+			Prefix:    cfg.Artifacts.S3Prefix,
+		})
+	default:
+		artifactStore, err = storage.NewLocalStore(cfg.Artifacts.Dir)
+	}
+	if err != nil {
+		log.Fatalf("Failed to open artifact storage: %v", err)
+	}
+
+	// Local disk spool for events that can't be written to ClickHouse
+	var eventSpool *spool.Spool
+	if cfg.Spool.Enabled {
+		eventSpool, err = spool.Open(spool.Options{
+			Dir:         cfg.Spool.Dir,
+			SegmentSize: cfg.Spool.SegmentSize,
+			MaxSize:     cfg.Spool.MaxSize,
+			MaxAge:      cfg.Spool.MaxAge,
+		})
+		if err != nil {
+			log.Fatalf("Failed to open spool: %v", err)
+		}
+		defer eventSpool.Close()
+	}
 
 	// Initialize services
-	ingestService := services.NewIngestService(eventsRepo, issuesRepo)
+	var deduplicator *services.EventDeduplicator
+	if cfg.Ingest.DedupWindow > 0 {
+		deduplicator = services.NewEventDeduplicator(redisDB, cfg.Ingest.DedupWindow)
+	}
+	symbolicator := services.NewSymbolicator(artifactsRepo, artifactStore, cfg.Artifacts.CacheSize)
+	groupingRules := services.NewGroupingRules(projectsRepo, cfg.Ingest.GroupingRulesTTL)
+	ownershipRules := services.NewOwnershipRules(projectsRepo, assigneesRepo, cfg.Ingest.OwnershipRulesTTL)
+	ingestService := services.NewIngestService(eventsRepo, issuesRepo, eventSpool, clickhouseDB.Health, deduplicator, symbolicator, groupingRules, activityRepo, ownershipRules)
+
+	var spoolReplayer *services.SpoolReplayer
+	if eventSpool != nil {
+		spoolReplayer = services.NewSpoolReplayer(ingestService, clickhouseDB.Health, cfg.Spool.ReplayInterval)
+		spoolReplayer.Start(context.Background())
+	}
+
+	// Reopen ignored issues whose ignore conditions trip without new events
+	var ignoreSweeper *services.IgnoreSweeper
+	if cfg.Ingest.IgnoreSweep > 0 {
+		ignoreSweeper = services.NewIgnoreSweeper(ingestService, cfg.Ingest.IgnoreSweep)
+		ignoreSweeper.Start(context.Background())
+	}
+
+	// Optional asynchronous ingest pipeline backed by a Redis stream
+	var ingestQueue *services.IngestQueue
+	if cfg.Ingest.Async {
+		ingestQueue = services.NewIngestQueue(redisDB, ingestService, &cfg.Ingest)
+		if err := ingestQueue.Start(context.Background()); err != nil {
+			log.Fatalf("Failed to start ingest queue: %v", err)
+		}
+	}
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(apiKeysRepo, projectsRepo)
 	rateLimitMiddleware := middleware.NewRateLimitMiddleware(redisDB, &cfg.RateLimit)
 
 	// Initialize handlers
-	ingestHandler := handlers.NewIngestHandler(ingestService)
-	issuesHandler := handlers.NewIssuesHandler(issuesRepo, eventsRepo)
-	projectsHandler := handlers.NewProjectsHandler(projectsRepo, eventsRepo, issuesRepo)
+	ingestHandler := handlers.NewIngestHandler(ingestService, ingestQueue)
+	issueMerger := services.NewIssueMerger(issuesRepo, activityRepo)
+	bulkRunner := services.NewBulkRunner(bulkJobsRepo)
+	issuesHandler := handlers.NewIssuesHandler(issuesRepo, eventsRepo, activityRepo, bulkJobsRepo, assigneesRepo, issueMerger, bulkRunner)
+	projectsHandler := handlers.NewProjectsHandler(projectsRepo, eventsRepo, issuesRepo, groupingRules, ownershipRules)
+	artifactsHandler := handlers.NewArtifactsHandler(artifactsRepo, artifactStore, symbolicator, cfg.Artifacts.MaxUploadSize)
 
 	// Setup Gin
 	if cfg.IsProduction() {
@@ -92,7 +165,7 @@ func main() {
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = []string{"http://localhost:3000", "https://errly.dev"}
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-Sentry-Auth"}
 	corsConfig.ExposeHeaders = []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
 	corsConfig.AllowCredentials = true
 
@@ -115,6 +188,16 @@ func main() {
 		}
 
 		if err := clickhouseDB.Health(); err != nil {
+			// Ingestion keeps working through the spool, so report degraded
+			if spoolStats := ingestService.SpoolStats(); spoolStats != nil {
+				c.JSON(http.StatusOK, gin.H{
+					"status": "degraded",
+					"error":  "ClickHouse connection failed",
+					"spool":  spoolStats,
+				})
+				return
+			}
+
 			c.JSON(http.StatusServiceUnavailable, gin.H{
 				"status": "unhealthy",
 				"error":  "ClickHouse connection failed",
@@ -130,11 +213,23 @@ func main() {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		response := gin.H{
 			"status":    "healthy",
 			"timestamp": time.Now().Unix(),
 			"version":   "1.0.0",
-		})
+		}
+
+		if ingestQueue != nil {
+			if stats, err := ingestQueue.Stats(c.Request.Context()); err == nil {
+				response["ingest_queue"] = stats
+			}
+		}
+
+		if spoolStats := ingestService.SpoolStats(); spoolStats != nil {
+			response["spool"] = spoolStats
+		}
+
+		c.JSON(http.StatusOK, response)
 	})
 
 	// API v1 routes
@@ -158,6 +253,26 @@ func main() {
 		ingestGroup.GET("/health", ingestHandler.HealthCheck)
 	}
 
+	// Sentry-compatible ingestion endpoints. The project ID is taken from the
+	// DSN path and the API key is used as the DSN public key.
+	sentryGroup := router.Group("/api/:project")
+	sentryGroup.Use(authMiddleware.RequireSentryAuth(models.ScopeIngest))
+	sentryGroup.Use(rateLimitMiddleware.IngestRateLimit())
+	{
+		sentryGroup.POST("/envelope/", ingestHandler.SentryEnvelope)
+		sentryGroup.POST("/store/", ingestHandler.SentryStore)
+	}
+
+	// OpenTelemetry OTLP/HTTP receiver. Exporters authenticate with
+	// OTEL_EXPORTER_OTLP_HEADERS="Authorization=Bearer <api key>".
+	otlpGroup := router.Group("/v1")
+	otlpGroup.Use(authMiddleware.RequireAPIKey(models.ScopeIngest))
+	otlpGroup.Use(rateLimitMiddleware.IngestRateLimit())
+	{
+		otlpGroup.POST("/traces", ingestHandler.OTLPTraces)
+		otlpGroup.POST("/logs", ingestHandler.OTLPLogs)
+	}
+
 	// Issues endpoints (require read scope)
 	issuesGroup := v1.Group("/issues")
 	issuesGroup.Use(rateLimitMiddleware.RateLimit())
@@ -167,9 +282,18 @@ func main() {
 		issuesGroup.GET("/:id", issuesHandler.GetIssue)
 		issuesGroup.GET("/:id/events", issuesHandler.GetIssueEvents)
 		issuesGroup.GET("/:id/timeseries", issuesHandler.GetIssueTimeSeries)
+		issuesGroup.GET("/:id/activity", issuesHandler.GetIssueActivity)
 
-		// Status updates require admin scope
+		// Status updates, assignments and comments require admin scope
 		issuesGroup.PATCH("/:id/status", authMiddleware.RequireScope(models.ScopeAdmin), issuesHandler.UpdateIssueStatus)
+		issuesGroup.PUT("/:id/assignee", authMiddleware.RequireScope(models.ScopeAdmin), issuesHandler.AssignIssue)
+		issuesGroup.POST("/:id/activity", authMiddleware.RequireScope(models.ScopeAdmin), issuesHandler.AddIssueComment)
+
+		// Merges and bulk operations require admin scope, their progress doesn't
+		issuesGroup.POST("/merge", authMiddleware.RequireScope(models.ScopeAdmin), issuesHandler.MergeIssues)
+		issuesGroup.POST("/bulk", authMiddleware.RequireScope(models.ScopeAdmin), issuesHandler.BulkUpdateIssues)
+		issuesGroup.GET("/bulk/:job_id", issuesHandler.GetBulkJob)
+		issuesGroup.POST("/:id/unmerge", authMiddleware.RequireScope(models.ScopeAdmin), issuesHandler.UnmergeIssue)
 	}
 
 	// Projects endpoints (require read scope)
@@ -181,6 +305,18 @@ func main() {
 		projectsGroup.GET("/:id/stats", projectsHandler.GetProjectStats)
 		projectsGroup.GET("/:id/issues", projectsHandler.GetProjectIssues)
 		projectsGroup.GET("/:id/events", projectsHandler.GetProjectEvents)
+
+		// Fingerprint rules; previews are read-only, updates require admin scope
+		projectsGroup.GET("/:id/fingerprint-rules", projectsHandler.GetFingerprintRules)
+		projectsGroup.PUT("/:id/fingerprint-rules", authMiddleware.RequireScope(models.ScopeAdmin), projectsHandler.UpdateFingerprintRules)
+		projectsGroup.POST("/:id/fingerprint-rules/preview", projectsHandler.PreviewFingerprintRules)
+		projectsGroup.GET("/:id/ownership", projectsHandler.GetOwnershipRules)
+		projectsGroup.PUT("/:id/ownership", authMiddleware.RequireScope(models.ScopeAdmin), projectsHandler.UpdateOwnershipRules)
+
+		// Release artifacts; uploads and deletes require admin scope
+		projectsGroup.GET("/:id/releases/:release/artifacts", artifactsHandler.ListArtifacts)
+		projectsGroup.POST("/:id/releases/:release/artifacts", authMiddleware.RequireScope(models.ScopeAdmin), artifactsHandler.UploadArtifact)
+		projectsGroup.DELETE("/:id/releases/:release/artifacts/:artifact_id", authMiddleware.RequireScope(models.ScopeAdmin), artifactsHandler.DeleteArtifact)
 	}
 
 	// Rate limit info endpoint (for debugging)
@@ -224,5 +360,17 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Stop queue workers after the server stopped accepting events
+	if ingestQueue != nil {
+		ingestQueue.Stop()
+	}
+	if spoolReplayer != nil {
+		spoolReplayer.Stop()
+	}
+	if ignoreSweeper != nil {
+		ignoreSweeper.Stop()
+	}
+	bulkRunner.Stop()
+
 	log.Println("Server exited")
 }
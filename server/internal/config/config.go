@@ -15,6 +15,9 @@ type Config struct {
 	Redis      RedisConfig
 	Auth       AuthConfig
 	RateLimit  RateLimitConfig
+	Ingest     IngestConfig
+	Spool      SpoolConfig
+	Artifacts  ArtifactsConfig
 }
 
 // ServerConfig holds server configuration
@@ -70,6 +73,49 @@ type RateLimitConfig struct {
 	BurstSize    int // Burst size for rate limiter
 }
 
+// IngestConfig holds asynchronous ingestion pipeline configuration
+type IngestConfig struct {
+	Async             bool          // Queue events in Redis instead of processing them inline
+	Stream            string        // Redis stream holding queued events
+	DeadLetterStream  string        // Redis stream receiving events that exhausted their retries
+	ConsumerGroup     string        // Consumer group shared by all API replicas
+	Workers           int           // Number of queue workers per replica
+	BatchSize         int           // Maximum stream entries processed per batch
+	BlockTimeout      time.Duration // How long a worker waits for new entries
+	ClaimIdle         time.Duration // Idle time after which a pending entry is retried
+	MaxRetries        int           // Deliveries before an entry is dead-lettered
+	MaxQueueLength    int64         // Queue depth at which ingestion is rejected
+	DedupWindow       time.Duration // How long client event IDs are remembered, 0 disables deduplication
+	GroupingRulesTTL  time.Duration // How long project fingerprint rules are cached
+	OwnershipRulesTTL time.Duration // How long project ownership rules are cached
+	IgnoreSweep       time.Duration // How often ignore conditions are checked, 0 disables the sweeper
+}
+
+// SpoolConfig holds the local disk spool configuration
+type SpoolConfig struct {
+	Enabled        bool
+	Dir            string
+	SegmentSize    int64         // Bytes per segment file
+	MaxSize        int64         // Total bytes kept on disk
+	MaxAge         time.Duration // Age after which segments are dropped
+	ReplayInterval time.Duration // How often ClickHouse is probed for replay
+}
+
+// ArtifactsConfig holds release artifact storage configuration
+type ArtifactsConfig struct {
+	Backend       string // "local" or "s3"
+	Dir           string // Directory used by the local backend
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3PathStyle   bool   // Address the bucket in the path, as MinIO expects
+	S3Prefix      string // Key prefix inside the bucket
+	MaxUploadSize int64  // Maximum size of a single artifact in bytes
+	CacheSize     int    // Number of parsed source maps kept in memory
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -114,6 +160,43 @@ func Load() (*Config, error) {
 			APIRPMPerKey: getIntEnv("API_RPM_PER_KEY", 100),
 			BurstSize:    getIntEnv("BURST_SIZE", 50),
 		},
+		Ingest: IngestConfig{
+			Async:             getBoolEnv("INGEST_ASYNC", false),
+			Stream:            getEnv("INGEST_STREAM", "errly:ingest"),
+			DeadLetterStream:  getEnv("INGEST_DEAD_LETTER_STREAM", "errly:ingest:dead"),
+			ConsumerGroup:     getEnv("INGEST_CONSUMER_GROUP", "ingest-workers"),
+			Workers:           getIntEnv("INGEST_WORKERS", 4),
+			BatchSize:         getIntEnv("INGEST_BATCH_SIZE", 50),
+			BlockTimeout:      getDurationEnv("INGEST_BLOCK_TIMEOUT", 2*time.Second),
+			ClaimIdle:         getDurationEnv("INGEST_CLAIM_IDLE", 30*time.Second),
+			MaxRetries:        getIntEnv("INGEST_MAX_RETRIES", 5),
+			MaxQueueLength:    int64(getIntEnv("INGEST_MAX_QUEUE_LENGTH", 1000000)),
+			DedupWindow:       getDurationEnv("INGEST_DEDUP_WINDOW", 24*time.Hour),
+			GroupingRulesTTL:  getDurationEnv("INGEST_GROUPING_RULES_TTL", 30*time.Second),
+			OwnershipRulesTTL: getDurationEnv("INGEST_OWNERSHIP_RULES_TTL", 30*time.Second),
+			IgnoreSweep:       getDurationEnv("INGEST_IGNORE_SWEEP_INTERVAL", time.Minute),
+		},
+		Spool: SpoolConfig{
+			Enabled:        getBoolEnv("SPOOL_ENABLED", true),
+			Dir:            getEnv("SPOOL_DIR", "data/spool"),
+			SegmentSize:    int64(getIntEnv("SPOOL_SEGMENT_SIZE_MB", 64)) << 20,
+			MaxSize:        int64(getIntEnv("SPOOL_MAX_SIZE_MB", 2048)) << 20,
+			MaxAge:         getDurationEnv("SPOOL_MAX_AGE", 72*time.Hour),
+			ReplayInterval: getDurationEnv("SPOOL_REPLAY_INTERVAL", 10*time.Second),
+		},
+		Artifacts: ArtifactsConfig{
+			Backend:       getEnv("ARTIFACTS_BACKEND", "local"),
+			Dir:           getEnv("ARTIFACTS_DIR", "data/artifacts"),
+			S3Endpoint:    getEnv("ARTIFACTS_S3_ENDPOINT", "https://s3.amazonaws.com"),
+			S3Region:      getEnv("ARTIFACTS_S3_REGION", "us-east-1"),
+			S3Bucket:      getEnv("ARTIFACTS_S3_BUCKET", ""),
+			S3AccessKey:   getEnv("ARTIFACTS_S3_ACCESS_KEY", ""),
+			S3SecretKey:   getEnv("ARTIFACTS_S3_SECRET_KEY", ""),
+			S3PathStyle:   getBoolEnv("ARTIFACTS_S3_PATH_STYLE", false),
+			S3Prefix:      getEnv("ARTIFACTS_S3_PREFIX", ""),
+			MaxUploadSize: int64(getIntEnv("ARTIFACTS_MAX_UPLOAD_SIZE_MB", 40)) << 20,
+			CacheSize:     getIntEnv("ARTIFACTS_CACHE_SIZE", 100),
+		},
 	}
 
 	// Validate required configuration
@@ -141,6 +224,24 @@ func (c *Config) Validate() error {
 	if c.Auth.JWTSecret == "" {
 		return fmt.Errorf("JWT secret is required")
 	}
+	if c.Ingest.Async && c.Ingest.Workers < 1 {
+		return fmt.Errorf("at least one ingest worker is required")
+	}
+	if c.Ingest.Async && c.Ingest.ClaimIdle <= 0 {
+		return fmt.Errorf("ingest claim idle time must be positive")
+	}
+	if c.Spool.Enabled && c.Spool.ReplayInterval <= 0 {
+		return fmt.Errorf("spool replay interval must be positive")
+	}
+	switch c.Artifacts.Backend {
+	case "local":
+	case "s3":
+		if c.Artifacts.S3Bucket == "" {
+			return fmt.Errorf("artifacts S3 bucket is required")
+		}
+	default:
+		return fmt.Errorf("unknown artifacts backend: %s", c.Artifacts.Backend)
+	}
 	return nil
 }
 
@@ -184,6 +285,15 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
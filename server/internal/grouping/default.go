@@ -0,0 +1,183 @@
+package grouping
+
+import (
+	"crypto/md5"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"server/internal/models"
+)
+
+// Grouping algorithm versions. Issues store the version that created them so
+// events keep joining issues created by an older version.
+const (
+	// VersionLegacy hashes the message, the first three stack trace lines and
+	// the environment
+	VersionLegacy = 1
+
+	// VersionInApp hashes the module and function of in-app frames, or the
+	// normalized message when there is no stack trace, and the environment
+	VersionInApp = 2
+
+	// LatestVersion is used for new issues
+	LatestVersion = VersionInApp
+)
+
+// Message normalization patterns, applied in order
+var messageNormalizers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`"[^"]*"|` + "`[^`]*`"), "<quoted>"},
+	{singleQuotePattern, "${1}<quoted>${2}"},
+	{regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`), "<email>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`), "<timestamp>"},
+	{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b`), "<hex>"},
+	{hexWordPattern, "<hex>"},
+	{regexp.MustCompile(`(^|[^\pL\d_])\d+(?:\.\d+)?`), "${1}<num>"}, // Not digits within words such as "e2e"
+}
+
+// singleQuotePattern matches single quoted strings that start and end at word
+// boundaries, but not the text between apostrophes as in "can't find user's"
+var singleQuotePattern = regexp.MustCompile(`(^|[^\pL\d_])'[^']*'([^\pL\d_]|$)`)
+
+// hexWordPattern matches hex strings of at least 8 characters with both
+// digits and letters, such as hashes and object IDs, but not plain words
+var hexWordPattern = regexp.MustCompile(`(?i)\b(?:[0-9a-f]*[0-9][0-9a-f]*[a-f]|[0-9a-f]*[a-f][0-9a-f]*[0-9])[0-9a-f]*\b`)
+
+// anonymousFunctionPattern matches compiler generated parts of function names
+// that change between builds, e.g. "func1", "lambda$3" or "<anonymous>"
+var anonymousFunctionPattern = regexp.MustCompile(`(\$|\.func|lambda\$|\$\$Lambda\$)\d+(/0x[0-9a-f]+)?|<anonymous>|\[as [^\]]*\]`)
+
+// Fingerprint returns the default fingerprint of an event using the latest
+// grouping algorithm
+func Fingerprint(event *models.IngestEvent) string {
+	return FingerprintVersion(LatestVersion, event)
+}
+
+// FingerprintVersion returns the default fingerprint of an event using the
+// given grouping algorithm version
+func FingerprintVersion(version int, event *models.IngestEvent) string {
+	if version == VersionLegacy {
+		return legacyFingerprint(event)
+	}
+
+	components := inAppComponents(event)
+	if len(components) == 0 {
+		// Without usable frames the exception type and normalized message
+		// identify the error
+		for _, exception := range event.Exceptions {
+			components = append(components, "type:"+exception.Type)
+		}
+		components = append(components, "message:"+NormalizeMessage(event.Message))
+	}
+	// Like the legacy algorithm, every environment has its own issues
+	components = append(components, "environment:"+event.Environment)
+
+	hash := md5.Sum([]byte(strings.Join(components, "\n")))
+	return fmt.Sprintf("%x", hash)
+}
+
+// NormalizeMessage replaces the variable parts of a message, such as IDs,
+// numbers and quoted values, with placeholders
+func NormalizeMessage(message string) string {
+	for _, normalizer := range messageNormalizers {
+		if normalizer.pattern == singleQuotePattern {
+			// Adjacent quoted strings share the boundary between them, which
+			// one pass consumes for the first only
+			message = singleQuotePattern.ReplaceAllString(message, normalizer.replacement)
+			message = singleQuotePattern.ReplaceAllString(message, normalizer.replacement)
+			continue
+		}
+		if normalizer.pattern != hexWordPattern {
+			message = normalizer.pattern.ReplaceAllString(message, normalizer.replacement)
+			continue
+		}
+
+		// Short hex-looking words are more likely names, e.g. "add2"
+		message = hexWordPattern.ReplaceAllStringFunc(message, func(match string) string {
+			if len(match) < 8 {
+				return match
+			}
+			return normalizer.replacement
+		})
+	}
+	return strings.TrimSpace(message)
+}
+
+// inAppComponents returns the exception types and the module and function of
+// the in-app frames of the exception chain. Line numbers are left out so
+// deploys that shift code don't split issues. It returns nil when no frame
+// has a module, function or file name.
+func inAppComponents(event *models.IngestEvent) []string {
+	var components []string
+	hasFrames := false
+
+	for _, exception := range event.Exceptions {
+		components = append(components, "type:"+exception.Type)
+
+		for _, frame := range groupingFrames(exception.Frames()) {
+			function := anonymousFunctionPattern.ReplaceAllString(frame.Function, "")
+			switch {
+			case frame.Module != "" || function != "":
+				components = append(components, "frame:"+frame.Module+"|"+function)
+			case frame.Filename != "":
+				components = append(components, "file:"+path.Base(frame.Filename))
+			default:
+				continue
+			}
+			hasFrames = true
+		}
+	}
+
+	if !hasFrames {
+		return nil
+	}
+	return components
+}
+
+// groupingFrames returns the frames that contribute to the fingerprint: the
+// in-app frames when any frame is marked in-app, otherwise every frame that
+// isn't explicitly a library frame, and all frames as a last resort
+func groupingFrames(frames []models.StackFrame) []models.StackFrame {
+	var inApp, unknown []models.StackFrame
+	for _, frame := range frames {
+		switch {
+		case frame.InApp == nil:
+			unknown = append(unknown, frame)
+		case *frame.InApp:
+			inApp = append(inApp, frame)
+		}
+	}
+
+	switch {
+	case len(inApp) > 0:
+		return inApp
+	case len(unknown) > 0:
+		return unknown
+	default:
+		return frames
+	}
+}
+
+// legacyFingerprint is the version 1 algorithm
+func legacyFingerprint(event *models.IngestEvent) string {
+	fingerprintData := event.Message
+	if event.StackTrace != nil {
+		// Use first few lines of stack trace for fingerprint
+		lines := strings.Split(*event.StackTrace, "\n")
+		if len(lines) > 3 {
+			lines = lines[:3]
+		}
+		fingerprintData += strings.Join(lines, "\n")
+	}
+
+	// Add environment to make fingerprints environment-specific
+	fingerprintData += event.Environment
+
+	hash := md5.Sum([]byte(fingerprintData))
+	return fmt.Sprintf("%x", hash)
+}
@@ -0,0 +1,96 @@
+package grouping
+
+import (
+	"testing"
+
+	"server/internal/models"
+)
+
+func TestNormalizeMessage(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"User 12345 not found", "User <num> not found"},
+		{"Order 3f2b8c1e-9a4d-4e6b-8f7a-1c2d3e4f5a6b failed", "Order <uuid> failed"},
+		{"Invalid token for jane.doe@example.com", "Invalid token for <email>"},
+		{`Key "user:42" missing in 'cache'`, "Key <quoted> missing in <quoted>"},
+		{"Deadline at 2026-10-16T12:30:00Z exceeded", "Deadline at <timestamp> exceeded"},
+		{"Object 5f1d7a9c3b2e not in 0xdeadbeef", "Object <hex> not in <hex>"},
+		{"e2e check failed", "e2e check failed"},
+		{"Can't find user's profile", "Can't find user's profile"},
+		{"Can't load 'avatar.png' for user's profile", "Can't load <quoted> for user's profile"},
+		{"Fields 'a', 'b' 'c' are read-only", "Fields <quoted>, <quoted> <quoted> are read-only"},
+	}
+
+	for _, tt := range tests {
+		if normalized := NormalizeMessage(tt.message); normalized != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, normalized)
+		}
+	}
+}
+
+func TestFingerprint_IgnoresLineNumbersAndLibraryFrames(t *testing.T) {
+	event := func(line int, library string) *models.IngestEvent {
+		return &models.IngestEvent{
+			Message: "boom",
+			Exceptions: []models.Exception{{
+				Type: "ValueError",
+				Stacktrace: &models.Stacktrace{Frames: []models.StackFrame{
+					{Module: "app.views", Function: "checkout", Lineno: line, InApp: inApp(true)},
+					{Module: library, Function: "call", Lineno: 7, InApp: inApp(false)},
+				}},
+			}},
+		}
+	}
+
+	if Fingerprint(event(10, "requests")) != Fingerprint(event(42, "httpx")) {
+		t.Error("Expected line numbers and library frames to be ignored")
+	}
+
+	other := event(10, "requests")
+	other.Exceptions[0].Stacktrace.Frames[0].Function = "refund"
+	if Fingerprint(event(10, "requests")) == Fingerprint(other) {
+		t.Error("Expected different in-app functions to produce different fingerprints")
+	}
+}
+
+func TestFingerprint_NormalizesMessagesWithoutFrames(t *testing.T) {
+	first := &models.IngestEvent{Message: "User 17 not found", Environment: "production"}
+	second := &models.IngestEvent{Message: "User 4242 not found", Environment: "production"}
+
+	if Fingerprint(first) != Fingerprint(second) {
+		t.Error("Expected messages differing only in IDs to group together")
+	}
+	if FingerprintVersion(VersionLegacy, first) == FingerprintVersion(VersionLegacy, second) {
+		t.Error("Expected the legacy algorithm to keep splitting them")
+	}
+}
+
+func TestFingerprint_SplitsEnvironments(t *testing.T) {
+	event := func(environment string) *models.IngestEvent {
+		return &models.IngestEvent{
+			Message:     "boom",
+			Environment: environment,
+			Exceptions: []models.Exception{{
+				Type: "ValueError",
+				Stacktrace: &models.Stacktrace{Frames: []models.StackFrame{
+					{Module: "app.views", Function: "checkout", InApp: inApp(true)},
+				}},
+			}},
+		}
+	}
+	if Fingerprint(event("production")) == Fingerprint(event("staging")) {
+		t.Error("Expected events of different environments to group separately")
+	}
+
+	production := &models.IngestEvent{Message: "User 17 not found", Environment: "production"}
+	staging := &models.IngestEvent{Message: "User 17 not found", Environment: "staging"}
+	if Fingerprint(production) == Fingerprint(staging) {
+		t.Error("Expected messages of different environments to group separately")
+	}
+}
+
+func inApp(value bool) *bool {
+	return &value
+}
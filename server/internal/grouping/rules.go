@@ -0,0 +1,307 @@
+// Package grouping decides which issue an event is grouped into.
+package grouping
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"server/internal/models"
+)
+
+// SettingsKey is the project settings key holding the fingerprint rules
+const SettingsKey = "fingerprint_rules"
+
+// Rule limits
+const (
+	MaxRules                 = 50
+	MaxMatchersPerRule       = 10
+	MaxFingerprintComponents = 10
+	maxPatternLength         = 500
+)
+
+// Matcher types
+const (
+	MatchMessage  = "message"  // Regular expression on the event message
+	MatchType     = "type"     // Regular expression on any exception type
+	MatchModule   = "module"   // Regular expression on the module of any frame
+	MatchFunction = "function" // Regular expression on the function of any frame
+	MatchTag      = "tag"      // Regular expression on the value of the tag Key
+	MatchLevel    = "level"    // Exact event level
+)
+
+// templatePattern matches "{{ variable }}" placeholders in fingerprints
+var templatePattern = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// Matcher is a single condition of a rule
+type Matcher struct {
+	Type    string `json:"type"`
+	Key     string `json:"key,omitempty"` // Tag name for tag matchers
+	Pattern string `json:"pattern"`
+
+	regexp *regexp.Regexp
+}
+
+// Rule assigns a fingerprint to events matching all of its matchers. Each
+// fingerprint component may contain {{ default }}, {{ message }}, {{ type }},
+// {{ module }}, {{ function }}, {{ level }}, {{ environment }} and
+// {{ tags.<name> }} placeholders.
+type Rule struct {
+	Matchers    []Matcher `json:"matchers"`
+	Fingerprint []string  `json:"fingerprint"`
+}
+
+// ParseRules reads and compiles the fingerprint rules stored in project
+// settings. Projects without rules return nil.
+func ParseRules(settings map[string]interface{}) ([]Rule, error) {
+	raw, ok := settings[SettingsKey]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	// Settings are decoded generically, so round trip them through JSON
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fingerprint rules: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(encoded, &rules); err != nil {
+		return nil, fmt.Errorf("invalid fingerprint rules: %w", err)
+	}
+	if err := Compile(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Compile validates rules and compiles their patterns in place
+func Compile(rules []Rule) error {
+	if len(rules) > MaxRules {
+		return fmt.Errorf("at most %d fingerprint rules are allowed", MaxRules)
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if len(rule.Matchers) == 0 || len(rule.Matchers) > MaxMatchersPerRule {
+			return fmt.Errorf("rule %d: between 1 and %d matchers are required", i, MaxMatchersPerRule)
+		}
+		if len(rule.Fingerprint) == 0 || len(rule.Fingerprint) > MaxFingerprintComponents {
+			return fmt.Errorf("rule %d: between 1 and %d fingerprint components are required", i, MaxFingerprintComponents)
+		}
+
+		for j := range rule.Matchers {
+			if err := rule.Matchers[j].compile(); err != nil {
+				return fmt.Errorf("rule %d, matcher %d: %w", i, j, err)
+			}
+		}
+
+		for _, component := range rule.Fingerprint {
+			if err := validateTemplate(component); err != nil {
+				return fmt.Errorf("rule %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply returns the fingerprint of the first rule matching the event and the
+// index of that rule. When no rule matches it returns defaultFingerprint and -1.
+func Apply(rules []Rule, event *models.IngestEvent, defaultFingerprint string) (string, int) {
+	for i, rule := range rules {
+		if rule.matches(event) {
+			return rule.render(event, defaultFingerprint), i
+		}
+	}
+	return defaultFingerprint, -1
+}
+
+// ClientFingerprint returns the fingerprint for a client supplied override.
+// Components that are exactly {{ default }} expand to defaultFingerprint, so
+// ["{{ default }}", "payments"] splits the default group by upstream service.
+func ClientFingerprint(components []string, defaultFingerprint string) string {
+	if len(components) == 0 || (len(components) == 1 && isDefaultTemplate(components[0])) {
+		return defaultFingerprint
+	}
+
+	expanded := make([]string, len(components))
+	for i, component := range components {
+		if isDefaultTemplate(component) {
+			component = defaultFingerprint
+		}
+		expanded[i] = component
+	}
+
+	hash := md5.Sum([]byte(strings.Join(expanded, "\x00")))
+	return fmt.Sprintf("%x", hash)
+}
+
+// compile validates the matcher and compiles its pattern
+func (m *Matcher) compile() error {
+	if len(m.Pattern) > maxPatternLength {
+		return fmt.Errorf("pattern longer than %d characters", maxPatternLength)
+	}
+
+	switch m.Type {
+	case MatchLevel:
+		switch models.ErrorLevel(m.Pattern) {
+		case models.LevelError, models.LevelWarning, models.LevelInfo, models.LevelDebug:
+			return nil
+		default:
+			return fmt.Errorf("unknown level %q", m.Pattern)
+		}
+	case MatchTag:
+		if m.Key == "" {
+			return fmt.Errorf("tag matchers require a key")
+		}
+	case MatchMessage, MatchType, MatchModule, MatchFunction:
+	default:
+		return fmt.Errorf("unknown matcher type %q", m.Type)
+	}
+
+	compiled, err := regexp.Compile(m.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	m.regexp = compiled
+	return nil
+}
+
+// matches reports whether the event satisfies the matcher
+func (m *Matcher) matches(event *models.IngestEvent) bool {
+	switch m.Type {
+	case MatchLevel:
+		return string(event.Level) == m.Pattern
+	case MatchMessage:
+		return m.regexp.MatchString(event.Message)
+	case MatchTag:
+		value, ok := event.Tags[m.Key]
+		return ok && m.regexp.MatchString(value)
+	case MatchType:
+		for _, exception := range event.Exceptions {
+			if exception.Type != "" && m.regexp.MatchString(exception.Type) {
+				return true
+			}
+		}
+	case MatchModule, MatchFunction:
+		for _, exception := range event.Exceptions {
+			for _, frame := range exception.Frames() {
+				value := frame.Function
+				if m.Type == MatchModule {
+					value = frame.Module
+				}
+				if value != "" && m.regexp.MatchString(value) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether the event satisfies all matchers of the rule
+func (r *Rule) matches(event *models.IngestEvent) bool {
+	for i := range r.Matchers {
+		if !r.Matchers[i].matches(event) {
+			return false
+		}
+	}
+	return true
+}
+
+// render expands the fingerprint template of the rule. A fingerprint of just
+// {{ default }} keeps the default grouping.
+func (r *Rule) render(event *models.IngestEvent, defaultFingerprint string) string {
+	if len(r.Fingerprint) == 1 && isDefaultTemplate(r.Fingerprint[0]) {
+		return defaultFingerprint
+	}
+
+	components := make([]string, len(r.Fingerprint))
+	for i, component := range r.Fingerprint {
+		components[i] = templatePattern.ReplaceAllStringFunc(component, func(placeholder string) string {
+			name := templatePattern.FindStringSubmatch(placeholder)[1]
+			return variable(event, name, defaultFingerprint)
+		})
+	}
+
+	hash := md5.Sum([]byte(strings.Join(components, "\x00")))
+	return fmt.Sprintf("%x", hash)
+}
+
+// variable returns the value of a template variable
+func variable(event *models.IngestEvent, name, defaultFingerprint string) string {
+	if tag, ok := strings.CutPrefix(name, "tags."); ok {
+		return event.Tags[tag]
+	}
+
+	switch name {
+	case "default":
+		return defaultFingerprint
+	case "message":
+		return event.Message
+	case "level":
+		return string(event.Level)
+	case "environment":
+		return event.Environment
+	case "type":
+		if len(event.Exceptions) > 0 {
+			return event.Exceptions[0].Type
+		}
+	case "module", "function":
+		if frame, ok := topFrame(event); ok {
+			if name == "module" {
+				return frame.Module
+			}
+			return frame.Function
+		}
+	}
+	return ""
+}
+
+// topFrame returns the most recent frame of the raised exception, preferring
+// frames of the application over library frames
+func topFrame(event *models.IngestEvent) (models.StackFrame, bool) {
+	if len(event.Exceptions) == 0 {
+		return models.StackFrame{}, false
+	}
+
+	frames := event.Exceptions[0].Frames()
+	for i := len(frames) - 1; i >= 0; i-- {
+		if frames[i].InApp == nil || *frames[i].InApp {
+			return frames[i], true
+		}
+	}
+	if len(frames) > 0 {
+		return frames[len(frames)-1], true
+	}
+	return models.StackFrame{}, false
+}
+
+// validateTemplate checks that a fingerprint component only uses known
+// variables
+func validateTemplate(component string) error {
+	for _, match := range templatePattern.FindAllStringSubmatch(component, -1) {
+		name := match[1]
+		if tag, ok := strings.CutPrefix(name, "tags."); ok {
+			if tag == "" {
+				return fmt.Errorf("empty tag name in %q", component)
+			}
+			continue
+		}
+
+		switch name {
+		case "default", "message", "level", "environment", "type", "module", "function":
+		default:
+			return fmt.Errorf("unknown fingerprint variable %q", name)
+		}
+	}
+	return nil
+}
+
+// isDefaultTemplate reports whether a component is just {{ default }}
+func isDefaultTemplate(component string) bool {
+	match := templatePattern.FindStringSubmatch(strings.TrimSpace(component))
+	return match != nil && match[0] == strings.TrimSpace(component) && match[1] == "default"
+}
@@ -0,0 +1,139 @@
+package grouping
+
+import (
+	"encoding/json"
+	"testing"
+
+	"server/internal/models"
+)
+
+func testEvent() *models.IngestEvent {
+	return &models.IngestEvent{
+		Message:     "Timeout calling payments after 3000ms",
+		Environment: "production",
+		Level:       models.LevelError,
+		Tags:        map[string]string{"route": "/checkout", "service": "payments"},
+		Exceptions: []models.Exception{{
+			Type:  "TimeoutError",
+			Value: "Timeout calling payments after 3000ms",
+			Stacktrace: &models.Stacktrace{Frames: []models.StackFrame{
+				{Function: "main", Module: "app"},
+				{Function: "charge", Module: "app.billing"},
+				{Function: "request", Module: "http.client", InApp: new(bool)},
+			}},
+		}},
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    string
+		expected int
+	}{
+		{"message", `[{"matchers":[{"type":"message","pattern":"^Timeout"}],"fingerprint":["timeouts"]}]`, 0},
+		{"type", `[{"matchers":[{"type":"type","pattern":"Timeout"}],"fingerprint":["{{ type }}"]}]`, 0},
+		{"function", `[{"matchers":[{"type":"function","pattern":"^charge$"}],"fingerprint":["{{ function }}"]}]`, 0},
+		{"module", `[{"matchers":[{"type":"module","pattern":"^http\\."}],"fingerprint":["http"]}]`, 0},
+		{"tag and level", `[{"matchers":[{"type":"tag","key":"route","pattern":"checkout"},{"type":"level","pattern":"error"}],"fingerprint":["{{ tags.route }}"]}]`, 0},
+		{"first match wins", `[{"matchers":[{"type":"level","pattern":"warning"}],"fingerprint":["a"]},{"matchers":[{"type":"message","pattern":"payments"}],"fingerprint":["b"]}]`, 1},
+		{"no match", `[{"matchers":[{"type":"tag","key":"missing","pattern":".*"}],"fingerprint":["a"]}]`, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []Rule
+			if err := json.Unmarshal([]byte(tt.rules), &rules); err != nil {
+				t.Fatalf("Failed to decode rules: %v", err)
+			}
+			if err := Compile(rules); err != nil {
+				t.Fatalf("Failed to compile rules: %v", err)
+			}
+
+			fingerprint, index := Apply(rules, testEvent(), "default")
+			if index != tt.expected {
+				t.Errorf("Expected rule %d to match, got %d", tt.expected, index)
+			}
+			if index < 0 && fingerprint != "default" {
+				t.Errorf("Expected the default fingerprint, got %s", fingerprint)
+			}
+			if index >= 0 && fingerprint == "default" {
+				t.Error("Expected a custom fingerprint")
+			}
+		})
+	}
+}
+
+func TestApply_Templates(t *testing.T) {
+	render := func(fingerprint ...string) string {
+		rules := []Rule{{
+			Matchers:    []Matcher{{Type: MatchMessage, Pattern: ".*"}},
+			Fingerprint: fingerprint,
+		}}
+		if err := Compile(rules); err != nil {
+			t.Fatalf("Failed to compile rules: %v", err)
+		}
+		result, _ := Apply(rules, testEvent(), "abc123")
+		return result
+	}
+
+	if result := render("{{ default }}"); result != "abc123" {
+		t.Errorf("Expected {{ default }} alone to keep the default fingerprint, got %s", result)
+	}
+	if render("{{ default }}", "{{ tags.route }}") == render("{{ default }}", "{{ tags.service }}") {
+		t.Error("Expected different tag values to produce different fingerprints")
+	}
+	if render("{{function}}") != render("charge") {
+		t.Error("Expected {{ function }} to expand to the top in-app frame")
+	}
+}
+
+func TestClientFingerprint(t *testing.T) {
+	if result := ClientFingerprint([]string{"{{ default }}"}, "abc123"); result != "abc123" {
+		t.Errorf("Expected {{ default }} alone to keep the default fingerprint, got %s", result)
+	}
+	if ClientFingerprint([]string{"{{ default }}", "payments"}, "abc123") == ClientFingerprint([]string{"{{ default }}", "payments"}, "def456") {
+		t.Error("Expected {{ default }} to expand to the default fingerprint")
+	}
+	if ClientFingerprint([]string{"payments"}, "abc123") != ClientFingerprint([]string{"payments"}, "def456") {
+		t.Error("Expected overrides without {{ default }} to ignore the default fingerprint")
+	}
+}
+
+func TestCompile_RejectsInvalidRules(t *testing.T) {
+	tests := map[string]Rule{
+		"no matchers":      {Fingerprint: []string{"a"}},
+		"no fingerprint":   {Matchers: []Matcher{{Type: MatchMessage, Pattern: "a"}}},
+		"bad regexp":       {Matchers: []Matcher{{Type: MatchMessage, Pattern: "("}}, Fingerprint: []string{"a"}},
+		"unknown type":     {Matchers: []Matcher{{Type: "url", Pattern: "a"}}, Fingerprint: []string{"a"}},
+		"tag without key":  {Matchers: []Matcher{{Type: MatchTag, Pattern: "a"}}, Fingerprint: []string{"a"}},
+		"unknown level":    {Matchers: []Matcher{{Type: MatchLevel, Pattern: "fatal"}}, Fingerprint: []string{"a"}},
+		"unknown variable": {Matchers: []Matcher{{Type: MatchMessage, Pattern: "a"}}, Fingerprint: []string{"{{ user }}"}},
+	}
+
+	for name, rule := range tests {
+		if err := Compile([]Rule{rule}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	var settings map[string]interface{}
+	raw := `{"theme":"dark","fingerprint_rules":[{"matchers":[{"type":"level","pattern":"error"}],"fingerprint":["x"]}]}`
+	if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+		t.Fatalf("Failed to decode settings: %v", err)
+	}
+
+	rules, err := ParseRules(settings)
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("Expected 1 rule, got %d", len(rules))
+	}
+
+	if rules, err := ParseRules(map[string]interface{}{}); err != nil || rules != nil {
+		t.Errorf("Expected no rules for empty settings, got %v, %v", rules, err)
+	}
+}
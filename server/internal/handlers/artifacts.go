@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"server/internal/middleware"
+	"server/internal/models"
+	"server/internal/repository"
+	"server/internal/services"
+	"server/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// multipartOverhead is allowed on top of the artifact size for the form
+// fields and boundaries of an upload
+const multipartOverhead = 1 << 20
+
+// ArtifactsHandler handles release artifact endpoints
+type ArtifactsHandler struct {
+	artifactsRepo *repository.ArtifactsRepository
+	store         storage.Store
+	symbolicator  *services.Symbolicator
+	maxUploadSize int64
+}
+
+// NewArtifactsHandler creates a new artifacts handler
+func NewArtifactsHandler(
+	artifactsRepo *repository.ArtifactsRepository,
+	store storage.Store,
+	symbolicator *services.Symbolicator,
+	maxUploadSize int64,
+) *ArtifactsHandler {
+	return &ArtifactsHandler{
+		artifactsRepo: artifactsRepo,
+		store:         store,
+		symbolicator:  symbolicator,
+		maxUploadSize: maxUploadSize,
+	}
+}
+
+// UploadArtifact handles POST /api/v1/projects/:id/releases/:release/artifacts
+//
+// The artifact is sent as the "file" field of a multipart form. The optional
+// "name" field is the URL the file is served from, e.g. ~/static/app.js.map;
+// it defaults to "~/" followed by the uploaded file name.
+func (h *ArtifactsHandler) UploadArtifact(c *gin.Context) {
+	projectID, ok := authorizeProject(c)
+	if !ok {
+		return
+	}
+	release := c.Param("release")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUploadSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Artifact file is required",
+			"code":    "MISSING_FILE",
+			"details": err.Error(),
+		})
+		return
+	}
+	if fileHeader.Size > h.maxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("Artifact exceeds the maximum size of %d bytes", h.maxUploadSize),
+			"code":  "ARTIFACT_TOO_LARGE",
+		})
+		return
+	}
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		name = "~/" + fileHeader.Filename
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read artifact file",
+			"code":  "INVALID_FILE",
+		})
+		return
+	}
+	defer file.Close()
+
+	// Every upload gets a new key so readers never see a half replaced file
+	ctx := c.Request.Context()
+	storageKey := fmt.Sprintf("%s/%s", projectID, uuid.New())
+	checksum := sha1.New()
+	if err := h.store.Put(ctx, storageKey, io.TeeReader(file, checksum), fileHeader.Size); err != nil {
+		log.Printf("Failed to store artifact %s for project %s: %v", name, projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to store artifact",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	previous, err := h.artifactsRepo.GetByName(ctx, projectID, release, name)
+	if err != nil {
+		h.store.Delete(ctx, storageKey)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save artifact",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	artifact := &models.ReleaseArtifact{
+		ProjectID:  projectID,
+		Release:    release,
+		Name:       name,
+		StorageKey: storageKey,
+		Size:       fileHeader.Size,
+		Checksum:   hex.EncodeToString(checksum.Sum(nil)),
+	}
+	if contentType := fileHeader.Header.Get("Content-Type"); contentType != "" {
+		artifact.ContentType = &contentType
+	}
+
+	if err := h.artifactsRepo.Upsert(ctx, artifact); err != nil {
+		h.store.Delete(ctx, storageKey)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save artifact",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	// The replaced content is no longer referenced
+	if previous != nil {
+		if err := h.store.Delete(ctx, previous.StorageKey); err != nil {
+			log.Printf("Failed to delete replaced artifact %s: %v", previous.StorageKey, err)
+		}
+	}
+	h.invalidate(projectID, release)
+
+	c.JSON(http.StatusCreated, artifact)
+}
+
+// ListArtifacts handles GET /api/v1/projects/:id/releases/:release/artifacts
+func (h *ArtifactsHandler) ListArtifacts(c *gin.Context) {
+	projectID, ok := authorizeProject(c)
+	if !ok {
+		return
+	}
+
+	artifacts, err := h.artifactsRepo.List(c.Request.Context(), projectID, c.Param("release"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list artifacts",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": artifacts})
+}
+
+// DeleteArtifact handles DELETE /api/v1/projects/:id/releases/:release/artifacts/:artifact_id
+func (h *ArtifactsHandler) DeleteArtifact(c *gin.Context) {
+	projectID, ok := authorizeProject(c)
+	if !ok {
+		return
+	}
+
+	artifactID, err := uuid.Parse(c.Param("artifact_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid artifact ID format",
+			"code":  "INVALID_ARTIFACT_ID",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	artifact, err := h.artifactsRepo.GetByID(ctx, projectID, artifactID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get artifact",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+	if artifact == nil || artifact.Release != c.Param("release") {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Artifact not found",
+			"code":  "ARTIFACT_NOT_FOUND",
+		})
+		return
+	}
+
+	if err := h.artifactsRepo.Delete(ctx, projectID, artifactID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete artifact",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	if err := h.store.Delete(ctx, artifact.StorageKey); err != nil {
+		log.Printf("Failed to delete artifact %s: %v", artifact.StorageKey, err)
+	}
+	h.invalidate(projectID, artifact.Release)
+
+	c.Status(http.StatusNoContent)
+}
+
+// invalidate drops cached source maps of a release
+func (h *ArtifactsHandler) invalidate(projectID uuid.UUID, release string) {
+	if h.symbolicator != nil {
+		h.symbolicator.Invalidate(projectID, release)
+	}
+}
+
+// authorizeProject parses the :id path parameter and verifies the API key
+// belongs to that project. It writes the error response when it fails.
+func authorizeProject(c *gin.Context) (uuid.UUID, bool) {
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_REQUIRED",
+		})
+		return uuid.Nil, false
+	}
+
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid project ID format",
+			"code":  "INVALID_PROJECT_ID",
+		})
+		return uuid.Nil, false
+	}
+
+	if projectID != authCtx.Project.ID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Access denied to project",
+			"code":  "PROJECT_ACCESS_DENIED",
+		})
+		return uuid.Nil, false
+	}
+
+	return projectID, true
+}
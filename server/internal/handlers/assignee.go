@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"server/internal/middleware"
+	"server/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// assigneeRequest is the body of issue assignments
+type assigneeRequest struct {
+	Assignee *string `json:"assignee"` // "user:<id>", "team:<id>" or "me"; null unassigns the issue
+}
+
+// AssignIssue handles PUT /api/v1/issues/:id/assignee
+func (h *IssuesHandler) AssignIssue(c *gin.Context) {
+	// Get auth context
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_REQUIRED",
+		})
+		return
+	}
+
+	issueID := c.Param("id")
+	if issueID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Issue ID is required",
+			"code":  "MISSING_ISSUE_ID",
+		})
+		return
+	}
+
+	var request assigneeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"code":    "INVALID_REQUEST_BODY",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	assignee, ok := parseAssignee(c, authCtx, request.Assignee)
+	if !ok {
+		return
+	}
+
+	// Get issue to verify access
+	ctx := c.Request.Context()
+	issue, err := h.issuesRepo.GetIssueByID(ctx, issueID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get issue",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	if issue == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Issue not found",
+			"code":  "ISSUE_NOT_FOUND",
+		})
+		return
+	}
+
+	// Verify user has access to the issue's project
+	if issue.ProjectID != authCtx.Project.ID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Access denied to issue",
+			"code":  "ISSUE_ACCESS_DENIED",
+		})
+		return
+	}
+
+	if !h.assigneeExists(c, authCtx, assignee) {
+		return
+	}
+
+	if err := h.issuesRepo.AssignIssues(ctx, issue.ProjectID, []string{issue.ID}, assignee); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to assign issue",
+			"code":  "UPDATE_FAILED",
+		})
+		return
+	}
+
+	h.recordAssignment(ctx, issue, assignee, &authCtx.APIKey.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Issue assigned successfully",
+		"issue_id": issue.ID,
+		"assignee": assignee,
+	})
+}
+
+// parseAssignee parses the assignee of an assignment and responds with an
+// error when it is invalid. A nil value unassigns and returns a nil assignee.
+func parseAssignee(c *gin.Context, authCtx *models.AuthContext, value *string) (*models.Assignee, bool) {
+	if value == nil {
+		return nil, true
+	}
+
+	assignee, err := models.ParseAssignee(*value, authCtx.APIKey.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid assignee",
+			"code":    "INVALID_ASSIGNEE",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return assignee, true
+}
+
+// assigneeExists responds with an error unless the assignee is a user or team
+// of the project's space, since only those can own its issues. Unassigning is
+// always allowed.
+func (h *IssuesHandler) assigneeExists(c *gin.Context, authCtx *models.AuthContext, assignee *models.Assignee) bool {
+	if assignee == nil {
+		return true
+	}
+
+	exists, err := h.assigneesRepo.Exists(c.Request.Context(), authCtx.Project.SpaceID, assignee)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get assignee",
+			"code":  "INTERNAL_ERROR",
+		})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Assignee not found",
+			"code":    "ASSIGNEE_NOT_FOUND",
+			"details": assignee.String(),
+		})
+		return false
+	}
+	return true
+}
+
+// recordAssignment adds an assignment of an issue to its activity log.
+// Failures are logged only, since the issue is assigned either way.
+func (h *IssuesHandler) recordAssignment(ctx context.Context, issue *models.Issue, assignee *models.Assignee, apiKeyID *uuid.UUID) {
+	activity := &models.IssueActivity{
+		ProjectID: issue.ProjectID,
+		IssueID:   issue.ID,
+		Type:      models.ActivityAssigned,
+		APIKeyID:  apiKeyID,
+		Data: map[string]interface{}{
+			"assignee": assigneeString(assignee),
+			"previous": assigneeString(issue.Assignee),
+		},
+	}
+	if err := h.activityRepo.Create(ctx, activity); err != nil {
+		log.Printf("Failed to record assignment of issue %s: %v", issue.ID, err)
+	}
+}
+
+// parseAssignedTo parses the assigned_to filter of an issues query into its
+// Assignee and responds with an error when it is invalid
+func parseAssignedTo(c *gin.Context, authCtx *models.AuthContext, query *models.IssuesQuery) bool {
+	if query.AssignedTo == nil || *query.AssignedTo == "" {
+		return true
+	}
+
+	assignee, err := models.ParseAssigneeFilter(*query.AssignedTo, authCtx.APIKey.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid assigned_to filter",
+			"code":    "INVALID_ASSIGNEE",
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	query.Assignee = assignee
+	return true
+}
+
+// assigneeString returns the assignee as stored in activity entries, "" when
+// unassigned
+func assigneeString(assignee *models.Assignee) string {
+	if assignee == nil {
+		return ""
+	}
+	return assignee.String()
+}
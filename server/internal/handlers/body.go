@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"server/internal/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxZstdWindowSize bounds the memory a single zstd stream may allocate
+const maxZstdWindowSize = 32 << 20 // 32MB
+
+// errUnsupportedEncoding is returned for unknown Content-Encoding values
+var errUnsupportedEncoding = goerrors.New("unsupported content encoding")
+
+// supportedContentEncodings lists the request body encodings we can decode
+var supportedContentEncodings = []string{"gzip", "deflate", "zstd"}
+
+// openRequestBody returns the request body decoded according to its
+// Content-Encoding header. maxBytes limits both the bytes read from the wire
+// and the decompressed size, so compressed bodies can't expand without bound.
+func openRequestBody(c *gin.Context, maxBytes int64) (io.ReadCloser, error) {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+
+	decoded, err := decodeContentEncoding(body, c.GetHeader("Content-Encoding"))
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	if decoded == body {
+		return body, nil
+	}
+
+	return &decodedBody{
+		Reader:  io.LimitReader(decoded, maxBytes+1),
+		limit:   maxBytes,
+		closers: []io.Closer{decoded, body},
+	}, nil
+}
+
+// readRequestBody reads the whole decoded request body
+func readRequestBody(c *gin.Context, maxBytes int64) ([]byte, error) {
+	body, err := openRequestBody(c, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// decodeContentEncoding wraps body in a decompressor for encoding
+func decodeContentEncoding(body io.ReadCloser, encoding string) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		return newDeflateReader(body)
+	case "zstd":
+		decoder, err := zstd.NewReader(body,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxWindow(maxZstdWindowSize),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedEncoding, encoding)
+	}
+}
+
+// newDeflateReader reads a deflate body. HTTP deflate is zlib wrapped, but
+// plenty of clients send raw deflate streams, so both are accepted.
+func newDeflateReader(body io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(body)
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	// A zlib header uses the deflate method and is a multiple of 31
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// decodedBody limits the decompressed size of a request body
+type decodedBody struct {
+	io.Reader
+	limit   int64
+	read    int64
+	closers []io.Closer
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n, &http.MaxBytesError{Limit: b.limit}
+	}
+	return n, err
+}
+
+func (b *decodedBody) Close() error {
+	var firstErr error
+	for _, closer := range b.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// respondBodyError writes the error response for a body that couldn't be
+// opened or read
+func respondBodyError(c *gin.Context, err error, message string) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case goerrors.Is(err, errUnsupportedEncoding):
+		validationErr := errors.NewValidationError("content_encoding", "Unsupported content encoding", c.GetHeader("Content-Encoding"))
+		c.JSON(http.StatusUnsupportedMediaType, validationErr.ToJSON())
+	case goerrors.As(err, &maxBytesErr):
+		validationErr := errors.NewValidationError("request_body", "Request body too large", maxBytesErr.Limit)
+		c.JSON(http.StatusRequestEntityTooLarge, validationErr.ToJSON())
+	default:
+		validationErr := errors.NewValidationError("request_body", message, err.Error())
+		c.JSON(http.StatusBadRequest, validationErr.ToJSON())
+	}
+}
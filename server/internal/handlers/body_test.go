@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	goerrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+func compressBody(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "zlib":
+		writer = zlib.NewWriter(&buf)
+	case "deflate":
+		writer, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "zstd":
+		writer, _ = zstd.NewWriter(&buf)
+	default:
+		return data
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		t.Fatalf("Failed to compress body: %v", err)
+	}
+	writer.Close()
+	return buf.Bytes()
+}
+
+func newBodyContext(body []byte, encoding string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/ingest", bytes.NewReader(body))
+	if encoding != "" {
+		c.Request.Header.Set("Content-Encoding", encoding)
+	}
+	return c
+}
+
+func TestReadRequestBody_Encodings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	payload := []byte(`{"events":[{"message":"boom","environment":"production","level":"error"}]}`)
+
+	tests := []struct {
+		name        string
+		compression string
+		header      string
+	}{
+		{"identity", "", ""},
+		{"gzip", "gzip", "gzip"},
+		{"zlib deflate", "zlib", "deflate"},
+		{"raw deflate", "deflate", "deflate"},
+		{"zstd", "zstd", "zstd"},
+		{"header case", "gzip", "GZIP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newBodyContext(compressBody(t, tt.compression, payload), tt.header)
+
+			body, err := readRequestBody(c, 1<<20)
+			if err != nil {
+				t.Fatalf("Expected body to decode, got %v", err)
+			}
+			if !bytes.Equal(body, payload) {
+				t.Errorf("Expected %s, got %s", payload, body)
+			}
+		})
+	}
+}
+
+func TestReadRequestBody_UnsupportedEncoding(t *testing.T) {
+	c := newBodyContext([]byte("{}"), "br")
+
+	if _, err := readRequestBody(c, 1<<20); !goerrors.Is(err, errUnsupportedEncoding) {
+		t.Errorf("Expected unsupported encoding error, got %v", err)
+	}
+}
+
+func TestReadRequestBody_LimitsDecompressedSize(t *testing.T) {
+	// Highly compressible data stays far below the limit on the wire
+	payload := []byte(strings.Repeat("a", 64<<10))
+	c := newBodyContext(compressBody(t, "gzip", payload), "gzip")
+
+	_, err := readRequestBody(c, 1024)
+	var maxBytesErr *http.MaxBytesError
+	if !goerrors.As(err, &maxBytesErr) {
+		t.Errorf("Expected max bytes error, got %v", err)
+	}
+}
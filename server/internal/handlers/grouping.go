@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"net/http"
+
+	"server/internal/grouping"
+	"server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fingerprint rule preview limits
+const (
+	defaultPreviewEvents = 500
+	maxPreviewEvents     = 1000
+	maxPreviewSamples    = 20
+)
+
+// fingerprintRulesRequest is the body of fingerprint rule updates and previews
+type fingerprintRulesRequest struct {
+	Rules     []grouping.Rule `json:"rules"`
+	TimeRange string          `json:"time_range"` // Preview only
+	Limit     int             `json:"limit"`      // Preview only
+}
+
+// regroupedEvent is a sample event whose issue changes in a preview
+type regroupedEvent struct {
+	EventID            string `json:"event_id"`
+	Message            string `json:"message"`
+	DefaultFingerprint string `json:"default_fingerprint"`
+	NewFingerprint     string `json:"new_fingerprint"`
+	Rule               int    `json:"rule"` // -1 when no rule matched
+}
+
+// GetFingerprintRules handles GET /api/v1/projects/:id/fingerprint-rules
+func (h *ProjectsHandler) GetFingerprintRules(c *gin.Context) {
+	projectID, ok := authorizeProject(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectsRepo.GetByID(c.Request.Context(), projectID)
+	if err != nil || project == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get project",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	rules, err := grouping.ParseRules(project.Settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Stored fingerprint rules are invalid",
+			"code":    "INVALID_FINGERPRINT_RULES",
+			"details": err.Error(),
+		})
+		return
+	}
+	if rules == nil {
+		rules = []grouping.Rule{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// UpdateFingerprintRules handles PUT /api/v1/projects/:id/fingerprint-rules
+func (h *ProjectsHandler) UpdateFingerprintRules(c *gin.Context) {
+	projectID, ok := authorizeProject(c)
+	if !ok {
+		return
+	}
+
+	var request fingerprintRulesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid JSON format",
+			"code":    "INVALID_REQUEST",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := grouping.Compile(request.Rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid fingerprint rules",
+			"code":    "INVALID_FINGERPRINT_RULES",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Only the rules are written, other settings may change concurrently
+	var value interface{}
+	if len(request.Rules) > 0 {
+		value = request.Rules
+	}
+	if err := h.projectsRepo.UpdateSetting(c.Request.Context(), projectID, grouping.SettingsKey, value); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save fingerprint rules",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+	if h.groupingRules != nil {
+		h.groupingRules.Invalidate(projectID)
+	}
+
+	rules := request.Rules
+	if rules == nil {
+		rules = []grouping.Rule{}
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// PreviewFingerprintRules handles POST /api/v1/projects/:id/fingerprint-rules/preview
+//
+// It applies the given rules, or the stored rules when none are given, to
+// recent events and reports how their grouping differs from the default
+// grouping of the same events. Stored fingerprints aren't compared since they
+// may come from the legacy algorithm or from the SDK. Nothing is stored.
+func (h *ProjectsHandler) PreviewFingerprintRules(c *gin.Context) {
+	projectID, ok := authorizeProject(c)
+	if !ok {
+		return
+	}
+
+	var request fingerprintRulesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid JSON format",
+			"code":    "INVALID_REQUEST",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	rules := request.Rules
+	if rules == nil {
+		project, err := h.projectsRepo.GetByID(ctx, projectID)
+		if err != nil || project == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to get project",
+				"code":  "INTERNAL_ERROR",
+			})
+			return
+		}
+		rules, err = grouping.ParseRules(project.Settings)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Stored fingerprint rules are invalid",
+				"code":    "INVALID_FINGERPRINT_RULES",
+				"details": err.Error(),
+			})
+			return
+		}
+	} else if err := grouping.Compile(rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid fingerprint rules",
+			"code":    "INVALID_FINGERPRINT_RULES",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	limit := request.Limit
+	if limit < 1 || limit > maxPreviewEvents {
+		limit = defaultPreviewEvents
+	}
+	window, ok := parseTimeWindow(c, models.TimeWindowParams{TimeRange: request.TimeRange})
+	if !ok {
+		return
+	}
+
+	events, err := h.eventsRepo.GetEvents(ctx, &models.EventsQuery{
+		ProjectID: &projectID,
+		Window:    window,
+		Page:      1,
+		Limit:     limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get project events",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	ruleMatches := make([]int, len(rules))
+	groupsBefore := make(map[string]bool)
+	groupsAfter := make(map[string]bool)
+	samples := make([]regroupedEvent, 0)
+	regrouped := 0
+
+	for _, event := range events.Data {
+		ingestEvent := storedIngestEvent(event)
+		defaultFingerprint := grouping.Fingerprint(&ingestEvent)
+		fingerprint, rule := grouping.Apply(rules, &ingestEvent, defaultFingerprint)
+		if rule >= 0 {
+			ruleMatches[rule]++
+		}
+
+		groupsBefore[defaultFingerprint] = true
+		groupsAfter[fingerprint] = true
+
+		if fingerprint != defaultFingerprint {
+			regrouped++
+			if len(samples) < maxPreviewSamples {
+				samples = append(samples, regroupedEvent{
+					EventID:            event.ID,
+					Message:            event.Message,
+					DefaultFingerprint: defaultFingerprint,
+					NewFingerprint:     fingerprint,
+					Rule:               rule,
+				})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events_evaluated": len(events.Data),
+		"events_regrouped": regrouped,
+		"groups_before":    len(groupsBefore),
+		"groups_after":     len(groupsAfter),
+		"rule_matches":     ruleMatches,
+		"samples":          samples,
+	})
+}
+
+// storedIngestEvent rebuilds the ingest form of a stored event so it can be
+// fingerprinted again
+func storedIngestEvent(event models.ErrorEvent) models.IngestEvent {
+	return models.IngestEvent{
+		Message:        event.Message,
+		StackTrace:     event.StackTrace,
+		Environment:    event.Environment,
+		ReleaseVersion: event.ReleaseVersion,
+		UserID:         event.UserID,
+		URL:            event.URL,
+		Tags:           event.Tags,
+		Extra:          event.Extra,
+		Exceptions:     event.Exceptions,
+		Level:          event.Level,
+	}
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"context"
+	goerrors "errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"server/internal/errors"
 	"server/internal/middleware"
@@ -11,17 +16,37 @@ import (
 	"server/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Ingest limits. Events exceeding the length limits are truncated.
+const (
+	maxIngestBodySize     = 10 << 20 // Decompressed size of a JSON ingest request
+	maxEventsPerRequest   = 100
+	maxMessageLength      = 1000
+	maxStackTraceLength   = 10000
+	maxTags               = 20
+	maxTagKeyLength       = 100
+	maxTagValueLength     = 200
+	maxExceptions         = 10
+	maxFramesPerException = 250
+	maxFingerprintParts   = 10
+	maxFingerprintLength  = 200 // Per fingerprint component
+	maxEventAge           = 7 * 24 * time.Hour
 )
 
 // IngestHandler handles event ingestion endpoints
 type IngestHandler struct {
 	ingestService *services.IngestService
+	ingestQueue   *services.IngestQueue
 }
 
-// NewIngestHandler creates a new ingest handler
-func NewIngestHandler(ingestService *services.IngestService) *IngestHandler {
+// NewIngestHandler creates a new ingest handler. When ingestQueue is nil events
+// are processed synchronously within the request.
+func NewIngestHandler(ingestService *services.IngestService, ingestQueue *services.IngestQueue) *IngestHandler {
 	return &IngestHandler{
 		ingestService: ingestService,
+		ingestQueue:   ingestQueue,
 	}
 }
 
@@ -35,11 +60,24 @@ func (h *IngestHandler) IngestEvents(c *gin.Context) {
 		return
 	}
 
+	// Streaming mode for large backfills
+	if c.ContentType() == ndjsonContentType {
+		h.ingestNDJSON(c, authCtx)
+		return
+	}
+
+	body, err := openRequestBody(c, maxIngestBodySize)
+	if err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	defer body.Close()
+	c.Request.Body = body
+
 	// Parse request body
 	var request models.IngestRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		validationErr := errors.NewValidationError("request_body", "Invalid JSON format", err.Error())
-		c.JSON(http.StatusBadRequest, validationErr.ToJSON())
+		respondBodyError(c, err, "Invalid JSON format")
 		return
 	}
 
@@ -50,26 +88,64 @@ func (h *IngestHandler) IngestEvents(c *gin.Context) {
 		return
 	}
 
-	if len(request.Events) > 100 {
+	if len(request.Events) > maxEventsPerRequest {
 		validationErr := errors.NewValidationError("events", "Maximum 100 events per request", len(request.Events))
 		c.JSON(http.StatusBadRequest, validationErr.ToJSON())
 		return
 	}
 
-	// Validate each event
-	for i, event := range request.Events {
-		if err := h.validateEvent(&event); err != nil {
-			validationErr := errors.NewValidationError(fmt.Sprintf("events[%d]", i), err.Error(), event)
-			c.JSON(http.StatusBadRequest, validationErr.ToJSON())
-			return
+	// Validate each event. Invalid events are dropped individually so one
+	// bad event doesn't cost the rest of the batch.
+	results := make([]models.EventResult, len(request.Events))
+	acceptedEvents := make([]models.IngestEvent, 0, len(request.Events))
+	var rejectedCount, truncatedCount int
+	for i := range request.Events {
+		results[i] = models.EventResult{Index: i, Status: models.EventAccepted}
+
+		truncated, err := h.normalizeEvent(&request.Events[i])
+		if err != nil {
+			results[i].Status = models.EventRejected
+			results[i].Error = err.Error()
+			rejectedCount++
+			continue
+		}
+		if len(truncated) > 0 {
+			results[i].Status = models.EventTruncated
+			results[i].Truncated = truncated
+			truncatedCount++
 		}
+		acceptedEvents = append(acceptedEvents, request.Events[i])
 	}
 
-	// Process events
+	if len(acceptedEvents) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "All events were rejected",
+			"code":           "VALIDATION_ERROR",
+			"rejected_count": rejectedCount,
+			"results":        results,
+		})
+		return
+	}
+
+	// Process or queue events
 	ctx := c.Request.Context()
-	if err := h.ingestService.ProcessEvents(ctx, authCtx.Project.ID, request.Events); err != nil {
-		processingErr := errors.NewSecureError("Failed to process events", "PROCESSING_ERROR", err, nil)
-		c.JSON(http.StatusInternalServerError, processingErr.ToJSON())
+	queued, err := h.submitEvents(ctx, authCtx.Project.ID, acceptedEvents)
+	if err != nil {
+		h.respondSubmitError(c, err, http.StatusInternalServerError, nil)
+		return
+	}
+
+	if queued {
+		c.JSON(http.StatusAccepted, gin.H{
+			"success":         true,
+			"message":         "Events queued for processing",
+			"queued_count":    len(acceptedEvents),
+			"rejected_count":  rejectedCount,
+			"truncated_count": truncatedCount,
+			"results":         results,
+			"project_id":      authCtx.Project.ID,
+			"timestamp":       time.Now().Unix(),
+		})
 		return
 	}
 
@@ -77,24 +153,67 @@ func (h *IngestHandler) IngestEvents(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success":         true,
 		"message":         "Events processed successfully",
-		"processed_count": len(request.Events),
+		"processed_count": len(acceptedEvents),
+		"rejected_count":  rejectedCount,
+		"truncated_count": truncatedCount,
+		"results":         results,
 		"project_id":      authCtx.Project.ID,
 		"timestamp":       time.Now().Unix(),
 	})
 }
 
-// validateEvent validates a single ingest event
-func (h *IngestHandler) validateEvent(event *models.IngestEvent) error {
-	if event.Message == "" {
-		return fmt.Errorf("message is required")
+// submitEvents queues the events when the asynchronous pipeline is enabled and
+// processes them inline otherwise. It reports whether the events were queued.
+func (h *IngestHandler) submitEvents(ctx context.Context, projectID uuid.UUID, events []models.IngestEvent) (bool, error) {
+	if h.ingestQueue != nil {
+		return true, h.ingestQueue.Enqueue(ctx, projectID, events)
+	}
+	return false, h.ingestService.ProcessEvents(ctx, projectID, events)
+}
+
+// respondSubmitError writes the error response for a failed submitEvents call.
+// Fields, if any, are added to the response.
+func (h *IngestHandler) respondSubmitError(c *gin.Context, err error, status int, fields map[string]interface{}) {
+	response := errors.NewSecureError("Failed to process events", "PROCESSING_ERROR", err, nil).ToJSON()
+	if goerrors.Is(err, services.ErrQueueFull) {
+		c.Header("Retry-After", "30")
+		status = http.StatusServiceUnavailable
+		response = errors.NewSecureError("Ingest queue is full", "QUEUE_FULL", err, nil).ToJSON()
+	}
+
+	for key, value := range fields {
+		response[key] = value
+	}
+	c.JSON(status, response)
+}
+
+// normalizeEvent validates a single ingest event and truncates fields that
+// exceed their limits. It returns the names of the truncated fields, or an
+// error for events that can't be stored.
+func (h *IngestHandler) normalizeEvent(event *models.IngestEvent) ([]string, error) {
+	for i, exception := range event.Exceptions {
+		if exception.Type == "" && exception.Value == "" {
+			return nil, fmt.Errorf("exceptions[%d]: type or value is required", i)
+		}
 	}
 
-	if len(event.Message) > 1000 {
-		return fmt.Errorf("message too long (max 1000 characters)")
+	// Events that only carry exceptions are titled after the raised exception
+	if event.Message == "" && len(event.Exceptions) > 0 {
+		event.Message = event.Exceptions[0].Title()
+	}
+
+	if event.Message == "" {
+		return nil, fmt.Errorf("message is required")
 	}
 
 	if event.Environment == "" {
-		return fmt.Errorf("environment is required")
+		return nil, fmt.Errorf("environment is required")
+	}
+
+	if event.EventID != nil && *event.EventID != "" {
+		if _, err := uuid.Parse(*event.EventID); err != nil {
+			return nil, fmt.Errorf("invalid event_id: must be a UUID")
+		}
 	}
 
 	// Validate level
@@ -106,43 +225,119 @@ func (h *IngestHandler) validateEvent(event *models.IngestEvent) error {
 	}
 
 	if !validLevels[event.Level] {
-		return fmt.Errorf("invalid level: %s", event.Level)
+		return nil, fmt.Errorf("invalid level: %s", event.Level)
 	}
 
 	// Validate timestamp if provided
 	if event.Timestamp != nil {
 		now := time.Now()
 		// Don't allow events from more than 7 days in the past
-		if event.Timestamp.Before(now.Add(-7 * 24 * time.Hour)) {
-			return fmt.Errorf("timestamp too old (max 7 days)")
+		if event.Timestamp.Before(now.Add(-maxEventAge)) {
+			return nil, fmt.Errorf("timestamp too old (max 7 days)")
 		}
 		// Don't allow events from the future
 		if event.Timestamp.After(now.Add(1 * time.Hour)) {
-			return fmt.Errorf("timestamp in the future")
+			return nil, fmt.Errorf("timestamp in the future")
 		}
 	}
 
-	// Validate stack trace length
-	if event.StackTrace != nil && len(*event.StackTrace) > 10000 {
-		return fmt.Errorf("stack trace too long (max 10000 characters)")
+	// Validate the fingerprint override
+	if len(event.Fingerprint) > maxFingerprintParts {
+		return nil, fmt.Errorf("too many fingerprint components (max %d)", maxFingerprintParts)
+	}
+	for i, component := range event.Fingerprint {
+		if strings.TrimSpace(component) == "" {
+			return nil, fmt.Errorf("fingerprint[%d]: component is empty", i)
+		}
 	}
 
 	// Validate tags
-	if event.Tags != nil {
-		if len(event.Tags) > 20 {
-			return fmt.Errorf("too many tags (max 20)")
+	if len(event.Tags) > maxTags {
+		return nil, fmt.Errorf("too many tags (max %d)", maxTags)
+	}
+	for key := range event.Tags {
+		if len(key) > maxTagKeyLength {
+			return nil, fmt.Errorf("tag key too long (max %d characters)", maxTagKeyLength)
+		}
+	}
+
+	// Oversized values are shortened rather than rejected
+	var truncated []string
+	if message, ok := truncateString(event.Message, maxMessageLength); ok {
+		event.Message = message
+		truncated = append(truncated, "message")
+	}
+	if event.StackTrace != nil {
+		if stackTrace, ok := truncateString(*event.StackTrace, maxStackTraceLength); ok {
+			event.StackTrace = &stackTrace
+			truncated = append(truncated, "stack_trace")
+		}
+	}
+
+	truncated = append(truncated, truncateExceptions(event)...)
+
+	for i, component := range event.Fingerprint {
+		if component, ok := truncateString(component, maxFingerprintLength); ok {
+			event.Fingerprint[i] = component
+			truncated = append(truncated, fmt.Sprintf("fingerprint[%d]", i))
+		}
+	}
+
+	tagKeys := make([]string, 0, len(event.Tags))
+	for key := range event.Tags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		if value, ok := truncateString(event.Tags[key], maxTagValueLength); ok {
+			event.Tags[key] = value
+			truncated = append(truncated, "tags."+key)
+		}
+	}
+
+	return truncated, nil
+}
+
+// truncateExceptions trims long exception chains, deep stacks and long
+// exception values. It returns the names of the truncated fields.
+func truncateExceptions(event *models.IngestEvent) []string {
+	var truncated []string
+
+	if len(event.Exceptions) > maxExceptions {
+		// Keep the raised exception and its closest causes
+		event.Exceptions = event.Exceptions[:maxExceptions]
+		truncated = append(truncated, "exceptions")
+	}
+
+	for i := range event.Exceptions {
+		exception := &event.Exceptions[i]
+		if value, ok := truncateString(exception.Value, maxMessageLength); ok {
+			exception.Value = value
+			truncated = append(truncated, fmt.Sprintf("exceptions[%d].value", i))
 		}
-		for key, value := range event.Tags {
-			if len(key) > 100 {
-				return fmt.Errorf("tag key too long (max 100 characters)")
-			}
-			if len(value) > 200 {
-				return fmt.Errorf("tag value too long (max 200 characters)")
-			}
+
+		// Keep the frames closest to where the exception was raised
+		if frames := exception.Frames(); len(frames) > maxFramesPerException {
+			exception.Stacktrace.Frames = frames[len(frames)-maxFramesPerException:]
+			truncated = append(truncated, fmt.Sprintf("exceptions[%d].stacktrace", i))
 		}
 	}
 
-	return nil
+	return truncated
+}
+
+// truncateString shortens s to at most max bytes without splitting a UTF-8
+// sequence. It reports whether s was shortened.
+func truncateString(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true
 }
 
 // GetIngestInfo returns information about ingestion capabilities
@@ -161,14 +356,26 @@ func (h *IngestHandler) GetIngestInfo(c *gin.Context) {
 		"project_name":   authCtx.Project.Name,
 		"api_key_scopes": authCtx.APIKey.Scopes,
 		"limits": gin.H{
-			"max_events_per_request": 100,
-			"max_message_length":     1000,
-			"max_stack_trace_length": 10000,
-			"max_tags":               20,
-			"max_tag_key_length":     100,
-			"max_tag_value_length":   200,
+			"max_events_per_request": maxEventsPerRequest,
+			"max_message_length":     maxMessageLength,
+			"max_stack_trace_length": maxStackTraceLength,
+			"max_tags":               maxTags,
+			"max_tag_key_length":     maxTagKeyLength,
+			"max_tag_value_length":   maxTagValueLength,
+			"max_exceptions":         maxExceptions,
+			"max_frames":             maxFramesPerException,
+			"max_fingerprint_parts":  maxFingerprintParts,
+			"max_fingerprint_length": maxFingerprintLength,
 			"max_event_age_days":     7,
+			"max_request_body_bytes": maxIngestBodySize,
+			"max_ndjson_body_bytes":  maxNDJSONBodySize,
+			"max_ndjson_line_bytes":  maxNDJSONLineSize,
+		},
+		"supported_content_types": []string{
+			"application/json",
+			ndjsonContentType,
 		},
+		"supported_content_encodings": supportedContentEncodings,
 		"supported_levels": []string{
 			string(models.LevelError),
 			string(models.LevelWarning),
@@ -181,10 +388,24 @@ func (h *IngestHandler) GetIngestInfo(c *gin.Context) {
 
 // HealthCheck returns the health status of the ingestion service
 func (h *IngestHandler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"status":    "healthy",
 		"service":   "ingest",
+		"mode":      "sync",
 		"timestamp": time.Now().Unix(),
 		"version":   "1.0.0",
-	})
+	}
+
+	if h.ingestQueue != nil {
+		response["mode"] = "async"
+		stats, err := h.ingestQueue.Stats(c.Request.Context())
+		if err != nil {
+			response["status"] = "degraded"
+			response["queue_error"] = "Failed to read queue statistics"
+		} else {
+			response["queue"] = stats
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
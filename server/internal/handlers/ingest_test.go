@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/models"
+)
+
+func TestNormalizeEvent(t *testing.T) {
+	h := &IngestHandler{}
+	old := &models.FlexibleTime{Time: time.Now().Add(-8 * 24 * time.Hour)}
+
+	tests := []struct {
+		name          string
+		event         models.IngestEvent
+		wantErr       bool
+		wantTruncated []string
+	}{
+		{
+			name:  "valid event",
+			event: models.IngestEvent{Message: "boom", Environment: "production", Level: models.LevelError},
+		},
+		{
+			name:    "missing message",
+			event:   models.IngestEvent{Environment: "production", Level: models.LevelError},
+			wantErr: true,
+		},
+		{
+			name:    "invalid level",
+			event:   models.IngestEvent{Message: "boom", Environment: "production", Level: "fatal"},
+			wantErr: true,
+		},
+		{
+			name:    "timestamp too old",
+			event:   models.IngestEvent{Message: "boom", Environment: "production", Level: models.LevelError, Timestamp: old},
+			wantErr: true,
+		},
+		{
+			name: "oversized message and tag value",
+			event: models.IngestEvent{
+				Message:     strings.Repeat("m", maxMessageLength+1),
+				Environment: "production",
+				Level:       models.LevelError,
+				Tags:        map[string]string{"ok": "v", "big": strings.Repeat("v", maxTagValueLength+1)},
+			},
+			wantTruncated: []string{"message", "tags.big"},
+		},
+		{
+			name: "oversized tag key",
+			event: models.IngestEvent{
+				Message:     "boom",
+				Environment: "production",
+				Level:       models.LevelError,
+				Tags:        map[string]string{strings.Repeat("k", maxTagKeyLength+1): "v"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "long fingerprint component",
+			event: models.IngestEvent{
+				Message:     "boom",
+				Environment: "production",
+				Level:       models.LevelError,
+				Fingerprint: []string{"{{ default }}", strings.Repeat("f", maxFingerprintLength+1)},
+			},
+			wantTruncated: []string{"fingerprint[1]"},
+		},
+		{
+			name: "empty fingerprint component",
+			event: models.IngestEvent{
+				Message:     "boom",
+				Environment: "production",
+				Level:       models.LevelError,
+				Fingerprint: []string{"payments", " "},
+			},
+			wantErr: true,
+		},
+		{
+			name: "too many fingerprint components",
+			event: models.IngestEvent{
+				Message:     "boom",
+				Environment: "production",
+				Level:       models.LevelError,
+				Fingerprint: make([]string, maxFingerprintParts+1),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncated, err := h.normalizeEvent(&tt.event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if strings.Join(truncated, ",") != strings.Join(tt.wantTruncated, ",") {
+				t.Errorf("Expected truncated fields %v, got %v", tt.wantTruncated, truncated)
+			}
+			if len(tt.event.Message) > maxMessageLength {
+				t.Errorf("Expected message to be truncated, got %d bytes", len(tt.event.Message))
+			}
+		})
+	}
+}
+
+func TestTruncateString_KeepsRunesIntact(t *testing.T) {
+	// "é" is two bytes, so a cut at 3 bytes falls inside the second rune
+	truncated, ok := truncateString("éé", 3)
+	if !ok {
+		t.Fatal("Expected string to be truncated")
+	}
+	if truncated != "é" {
+		t.Errorf("Expected %q, got %q", "é", truncated)
+	}
+}
+
+func TestNormalizeEvent_EventID(t *testing.T) {
+	h := &IngestHandler{}
+
+	tests := []struct {
+		name    string
+		eventID string
+		wantErr bool
+	}{
+		{"dashed UUID", "0b8a8c5e-6f2a-4d4b-9c1e-2f3a4b5c6d7e", false},
+		{"sentry hex form", "fc6d8c0c43fc4630ad850ee518f1b9d0", false},
+		{"empty", "", false},
+		{"not a UUID", "retry-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventID := tt.eventID
+			event := models.IngestEvent{EventID: &eventID, Message: "boom", Environment: "production", Level: models.LevelError}
+
+			_, err := h.normalizeEvent(&event)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"server/internal/middleware"
+	"server/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// commentRequest is the body of issue comments
+type commentRequest struct {
+	Text string `json:"text" binding:"required"` // Markdown; @<email> mentions users and @<slug> teams
+}
+
+// GetIssueActivity handles GET /api/v1/issues/:id/activity. Entries are
+// returned oldest first.
+func (h *IssuesHandler) GetIssueActivity(c *gin.Context) {
+	// Get auth context
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_REQUIRED",
+		})
+		return
+	}
+
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	issue, ok := h.accessibleIssue(c, authCtx)
+	if !ok {
+		return
+	}
+
+	activities, total, err := h.activityRepo.ListByIssue(c.Request.Context(), issue.ProjectID, issue.ID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get issue activity",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse[models.IssueActivity]{
+		Data:    activities,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+		HasNext: page*limit < total,
+		HasPrev: page > 1,
+	})
+}
+
+// AddIssueComment handles POST /api/v1/issues/:id/activity. Mentions of
+// users and teams of the project's space are stored with the comment;
+// other mentions stay plain text. Comments mentioning more than
+// models.MaxCommentMentions users and teams are rejected.
+func (h *IssuesHandler) AddIssueComment(c *gin.Context) {
+	// Get auth context
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_REQUIRED",
+		})
+		return
+	}
+
+	var request commentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"code":    "INVALID_REQUEST_BODY",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	text := strings.TrimSpace(request.Text)
+	if text == "" || len(text) > models.MaxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid comment",
+			"code":    "INVALID_COMMENT",
+			"details": "comments must have 1 to 10000 characters",
+		})
+		return
+	}
+
+	parsed := models.ParseMentions(text)
+	if len(parsed) > models.MaxCommentMentions {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid comment",
+			"code":    "INVALID_COMMENT",
+			"details": fmt.Sprintf("comments can mention at most %d users and teams", models.MaxCommentMentions),
+		})
+		return
+	}
+
+	issue, ok := h.accessibleIssue(c, authCtx)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	assignees, err := h.assigneesRepo.ResolveMentions(ctx, authCtx.Project.SpaceID, parsed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to look up mentions",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+	mentions := make([]string, 0, len(assignees))
+	for _, assignee := range assignees {
+		mentions = append(mentions, assignee.String())
+	}
+
+	activity := &models.IssueActivity{
+		ProjectID: issue.ProjectID,
+		IssueID:   issue.ID,
+		Type:      models.ActivityComment,
+		APIKeyID:  &authCtx.APIKey.ID,
+		Data: map[string]interface{}{
+			"text":     text,
+			"mentions": mentions,
+		},
+	}
+	if err := h.activityRepo.Create(ctx, activity); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to add comment",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, activity)
+}
+
+// accessibleIssue returns the issue of the request path and responds with an
+// error when it doesn't exist or belongs to another project
+func (h *IssuesHandler) accessibleIssue(c *gin.Context, authCtx *models.AuthContext) (*models.Issue, bool) {
+	issueID := c.Param("id")
+	if issueID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Issue ID is required",
+			"code":  "MISSING_ISSUE_ID",
+		})
+		return nil, false
+	}
+
+	issue, err := h.issuesRepo.GetIssueByID(c.Request.Context(), issueID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get issue",
+			"code":  "INTERNAL_ERROR",
+		})
+		return nil, false
+	}
+
+	if issue == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Issue not found",
+			"code":  "ISSUE_NOT_FOUND",
+		})
+		return nil, false
+	}
+
+	// Verify user has access to the issue's project
+	if issue.ProjectID != authCtx.Project.ID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Access denied to issue",
+			"code":  "ISSUE_ACCESS_DENIED",
+		})
+		return nil, false
+	}
+
+	return issue, true
+}
+
+// recordStatusChange adds a status change of an issue to its activity log.
+// Failures are logged only, since the status changed either way.
+func (h *IssuesHandler) recordStatusChange(ctx context.Context, issue *models.Issue, update *models.StatusUpdate, apiKeyID *uuid.UUID) {
+	data := map[string]interface{}{
+		"status":   string(update.Status),
+		"previous": string(issue.Status),
+	}
+	if update.Resolution != "" {
+		data["resolution"] = string(update.Resolution)
+		data["resolved_in_release"] = update.ResolvedInRelease
+	}
+	if update.Ignore != nil {
+		data["ignore"] = update.Ignore
+	}
+
+	activity := &models.IssueActivity{
+		ProjectID: issue.ProjectID,
+		IssueID:   issue.ID,
+		Type:      models.ActivityStatus,
+		APIKeyID:  apiKeyID,
+		Data:      data,
+	}
+	if err := h.activityRepo.Create(ctx, activity); err != nil {
+		log.Printf("Failed to record status change of issue %s: %v", issue.ID, err)
+	}
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"server/internal/middleware"
+	"server/internal/models"
+	"server/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// bulkRequest is the body of bulk issue operations. The issues are selected
+// by ID or by the filters of the issues list.
+type bulkRequest struct {
+	Operation string              `json:"operation" binding:"required"`
+	IssueIDs  []string            `json:"issue_ids"`
+	Query     *models.IssuesQuery `json:"query"`
+	Status    *statusRequest      `json:"status"`     // Status operations only
+	Assignee  *string             `json:"assignee"`   // Assign operations only, like assigneeRequest; null unassigns the issues
+	PrimaryID string              `json:"primary_id"` // Merge operations only, defaults to the issue with the most events
+}
+
+// BulkUpdateIssues handles POST /api/v1/issues/bulk. The operation runs in the
+// background; the response holds the job to poll for progress.
+func (h *IssuesHandler) BulkUpdateIssues(c *gin.Context) {
+	// Get auth context
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_REQUIRED",
+		})
+		return
+	}
+
+	var request bulkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"code":    "INVALID_REQUEST_BODY",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if problem := request.problem(); problem != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid bulk operation",
+			"code":    "INVALID_BULK_OPERATION",
+			"details": problem,
+		})
+		return
+	}
+	if request.Status != nil && !request.Status.valid(c) {
+		return
+	}
+	assignee, ok := parseAssignee(c, authCtx, request.Assignee)
+	if !ok || !h.assigneeExists(c, authCtx, assignee) {
+		return
+	}
+
+	issues, ok := h.bulkIssues(c, authCtx, &request)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	projectID := authCtx.Project.ID
+	apiKeyID := &authCtx.APIKey.ID
+	chunkSize := models.BulkChunkSize
+	var task services.BulkTask
+
+	switch models.BulkOperation(request.Operation) {
+	case models.BulkStatus:
+		update, err := h.statusUpdate(ctx, projectID, request.Status)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to get releases",
+				"code":  "INTERNAL_ERROR",
+			})
+			return
+		}
+		task = func(ctx context.Context, chunk []*models.Issue) error {
+			if !relativeCounts(update) {
+				if err := h.issuesRepo.UpdateIssuesStatus(ctx, projectID, issueIDs(chunk), update); err != nil {
+					return err
+				}
+				for _, issue := range chunk {
+					h.recordStatusChange(ctx, issue, update, apiKeyID)
+				}
+				return nil
+			}
+			for _, issue := range chunk {
+				issueUpdate := forIssue(update, issue)
+				if err := h.issuesRepo.UpdateIssueStatus(ctx, issue.ID, issueUpdate); err != nil {
+					return err
+				}
+				h.recordStatusChange(ctx, issue, issueUpdate, apiKeyID)
+			}
+			return nil
+		}
+
+	case models.BulkAssign:
+		task = func(ctx context.Context, chunk []*models.Issue) error {
+			if err := h.issuesRepo.AssignIssues(ctx, projectID, issueIDs(chunk), assignee); err != nil {
+				return err
+			}
+			for _, issue := range chunk {
+				h.recordAssignment(ctx, issue, assignee, apiKeyID)
+			}
+			return nil
+		}
+
+	case models.BulkMerge:
+		if len(issues) < 2 || len(issues) > models.MaxMergeIssues {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid issues to merge",
+				"code":    "INVALID_MERGE",
+				"details": fmt.Sprintf("merges take 2 to %d issues, %d selected", models.MaxMergeIssues, len(issues)),
+			})
+			return
+		}
+		primary := mergePrimary(issues, request.PrimaryID)
+		if primary == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid issues to merge",
+				"code":    "INVALID_MERGE",
+				"details": "primary_id isn't one of the selected issues",
+			})
+			return
+		}
+		// Merges are a single change
+		chunkSize = len(issues)
+		task = func(ctx context.Context, chunk []*models.Issue) error {
+			return h.merger.Merge(ctx, primary, chunk, apiKeyID)
+		}
+
+	case models.BulkDelete:
+		task = func(ctx context.Context, chunk []*models.Issue) error {
+			// The issues merged into the deleted ones are deleted with them.
+			// Their activity goes first: issue IDs derive from fingerprints,
+			// so new events recreate the issues with the same IDs once their
+			// rows are gone, and must start a fresh activity log.
+			ids := issueIDs(chunk)
+			mergedIDs, err := h.issuesRepo.GetMergedIssueIDs(ctx, projectID, ids)
+			if err != nil {
+				return err
+			}
+			if err := h.activityRepo.DeleteByIssues(ctx, projectID, append(ids, mergedIDs...)); err != nil {
+				return err
+			}
+			return h.issuesRepo.DeleteIssues(ctx, projectID, chunk)
+		}
+	}
+
+	job := &models.BulkJob{
+		ProjectID: projectID,
+		APIKeyID:  apiKeyID,
+		Operation: models.BulkOperation(request.Operation),
+	}
+	if err := h.bulk.Start(ctx, job, issues, chunkSize, task); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start bulk operation",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetBulkJob handles GET /api/v1/issues/bulk/:job_id
+func (h *IssuesHandler) GetBulkJob(c *gin.Context) {
+	// Get auth context
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_REQUIRED",
+		})
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid job ID",
+			"code":  "INVALID_JOB_ID",
+		})
+		return
+	}
+
+	// Jobs of other projects aren't found
+	job, err := h.bulkJobsRepo.GetByID(c.Request.Context(), authCtx.Project.ID, jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get bulk job",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Bulk job not found",
+			"code":  "BULK_JOB_NOT_FOUND",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// problem returns what is wrong with the request, or "" when it is valid
+func (r *bulkRequest) problem() string {
+	switch models.BulkOperation(r.Operation) {
+	case models.BulkStatus:
+		if r.Status == nil {
+			return "status operations require status"
+		}
+	case models.BulkAssign, models.BulkMerge, models.BulkDelete:
+		if r.Status != nil {
+			return "status is only valid for status operations"
+		}
+	default:
+		return "operation must be status, assign, merge or delete"
+	}
+
+	switch {
+	case len(r.IssueIDs) == 0 && r.Query == nil:
+		return "issue_ids or query is required"
+	case len(r.IssueIDs) > 0 && r.Query != nil:
+		return "issue_ids and query are exclusive"
+	case len(r.IssueIDs) > models.MaxBulkIssues:
+		return fmt.Sprintf("at most %d issues can be changed at once", models.MaxBulkIssues)
+	case r.Assignee != nil && models.BulkOperation(r.Operation) != models.BulkAssign:
+		return "assignee is only valid for assign operations"
+	case r.PrimaryID != "" && models.BulkOperation(r.Operation) != models.BulkMerge:
+		return "primary_id is only valid for merge operations"
+	default:
+		return ""
+	}
+}
+
+// bulkIssues returns the issues selected by a bulk request and responds with
+// an error when they can't be found or don't all belong to the project of the
+// caller
+func (h *IssuesHandler) bulkIssues(c *gin.Context, authCtx *models.AuthContext, request *bulkRequest) ([]*models.Issue, bool) {
+	ctx := c.Request.Context()
+
+	if request.Query != nil {
+		query := request.Query
+		if query.ProjectID == nil {
+			query.ProjectID = &authCtx.Project.ID
+		} else if *query.ProjectID != authCtx.Project.ID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Access denied to project",
+				"code":  "PROJECT_ACCESS_DENIED",
+			})
+			return nil, false
+		}
+
+		window, ok := parseOptionalTimeWindow(c, query.TimeWindowParams)
+		if !ok {
+			return nil, false
+		}
+		query.Window = window
+
+		if !parseAssignedTo(c, authCtx, query) {
+			return nil, false
+		}
+
+		issues, err := h.issuesRepo.FindIssues(ctx, query, models.MaxBulkIssues+1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to get issues",
+				"code":  "INTERNAL_ERROR",
+			})
+			return nil, false
+		}
+		if len(issues) > models.MaxBulkIssues {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Too many issues match the query",
+				"code":    "TOO_MANY_ISSUES",
+				"details": fmt.Sprintf("at most %d issues can be changed at once", models.MaxBulkIssues),
+			})
+			return nil, false
+		}
+		return issues, true
+	}
+
+	return h.issuesByID(c, authCtx, uniqueStrings(request.IssueIDs))
+}
+
+// issueIDs returns the IDs of issues
+func issueIDs(issues []*models.Issue) []string {
+	ids := make([]string, len(issues))
+	for i, issue := range issues {
+		ids[i] = issue.ID
+	}
+	return ids
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"testing"
+
+	"server/internal/models"
+)
+
+func TestBulkRequest_Problem(t *testing.T) {
+	status := &statusRequest{Status: "resolved"}
+	query := &models.IssuesQuery{}
+	assignee := "me"
+
+	tests := []struct {
+		name    string
+		request bulkRequest
+		valid   bool
+	}{
+		{"status by IDs", bulkRequest{Operation: "status", IssueIDs: []string{"a"}, Status: status}, true},
+		{"delete by query", bulkRequest{Operation: "delete", Query: query}, true},
+		{"assign by IDs", bulkRequest{Operation: "assign", IssueIDs: []string{"a"}, Assignee: &assignee}, true},
+		{"unassign by query", bulkRequest{Operation: "assign", Query: query}, true},
+		{"merge with primary", bulkRequest{Operation: "merge", IssueIDs: []string{"a", "b"}, PrimaryID: "a"}, true},
+		{"unknown operation", bulkRequest{Operation: "archive", IssueIDs: []string{"a"}}, false},
+		{"status without status", bulkRequest{Operation: "status", IssueIDs: []string{"a"}}, false},
+		{"no selection", bulkRequest{Operation: "delete"}, false},
+		{"both selections", bulkRequest{Operation: "delete", IssueIDs: []string{"a"}, Query: query}, false},
+		{"assign with status", bulkRequest{Operation: "assign", IssueIDs: []string{"a"}, Status: status}, false},
+		{"assignee without assign", bulkRequest{Operation: "status", IssueIDs: []string{"a"}, Status: status, Assignee: &assignee}, false},
+		{"primary without merge", bulkRequest{Operation: "delete", IssueIDs: []string{"a"}, PrimaryID: "a"}, false},
+	}
+
+	for _, tt := range tests {
+		if problem := tt.request.problem(); (problem == "") != tt.valid {
+			t.Errorf("%s: expected valid %v, got problem %q", tt.name, tt.valid, problem)
+		}
+	}
+}
+
+func TestForIssue_AddsIssueCounts(t *testing.T) {
+	update := &models.StatusUpdate{
+		Status: models.StatusIgnored,
+		Ignore: &models.IgnoreConditions{UntilEventCount: 100, EventRate: 10},
+	}
+	issue := &models.Issue{EventCount: 400, UserCount: 20}
+
+	issueUpdate := forIssue(update, issue)
+	if issueUpdate.Ignore.UntilEventCount != 500 {
+		t.Errorf("Expected an event count of 500, got %d", issueUpdate.Ignore.UntilEventCount)
+	}
+	if issueUpdate.Ignore.UntilUserCount != 0 || issueUpdate.Ignore.EventRate != 10 {
+		t.Errorf("Expected the other conditions to be kept, got %+v", issueUpdate.Ignore)
+	}
+	if update.Ignore.UntilEventCount != 100 {
+		t.Error("Expected the shared update to stay relative")
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"net/http"
+
+	"server/internal/middleware"
+	"server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mergeRequest is the body of issue merges
+type mergeRequest struct {
+	IssueIDs  []string `json:"issue_ids" binding:"required,min=2"`
+	PrimaryID string   `json:"primary_id"` // Defaults to the issue with the most events
+}
+
+// unmergeRequest is the body of issue unmerges
+type unmergeRequest struct {
+	Fingerprints []string `json:"fingerprints" binding:"required,min=1"`
+}
+
+// MergeIssues handles POST /api/v1/issues/merge
+func (h *IssuesHandler) MergeIssues(c *gin.Context) {
+	// Get auth context
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_REQUIRED",
+		})
+		return
+	}
+
+	var request mergeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"code":    "INVALID_REQUEST_BODY",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	issueIDs := uniqueStrings(request.IssueIDs)
+	if request.PrimaryID != "" && !containsString(issueIDs, request.PrimaryID) {
+		issueIDs = append(issueIDs, request.PrimaryID)
+	}
+	if len(issueIDs) < 2 || len(issueIDs) > models.MaxMergeIssues {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid issues to merge",
+			"code":    "INVALID_MERGE",
+			"details": "merges take 2 to 100 distinct issues",
+		})
+		return
+	}
+
+	issues, ok := h.issuesByID(c, authCtx, issueIDs)
+	if !ok {
+		return
+	}
+	primary := mergePrimary(issues, request.PrimaryID)
+
+	ctx := c.Request.Context()
+	if err := h.merger.Merge(ctx, primary, issues, &authCtx.APIKey.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to merge issues",
+			"code":  "MERGE_FAILED",
+		})
+		return
+	}
+
+	merged := make([]string, 0, len(issues)-1)
+	for _, issue := range issues {
+		if issue.ID != primary.ID {
+			merged = append(merged, issue.ID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Issues merged successfully",
+		"issue_id": primary.ID,
+		"merged":   merged,
+		"status":   string(models.MergedStatus(issues)),
+	})
+}
+
+// UnmergeIssue handles POST /api/v1/issues/:id/unmerge
+func (h *IssuesHandler) UnmergeIssue(c *gin.Context) {
+	// Get auth context
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_REQUIRED",
+		})
+		return
+	}
+
+	issueID := c.Param("id")
+	if issueID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Issue ID is required",
+			"code":  "MISSING_ISSUE_ID",
+		})
+		return
+	}
+
+	var request unmergeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"code":    "INVALID_REQUEST_BODY",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Get issue to verify access
+	ctx := c.Request.Context()
+	issue, err := h.issuesRepo.GetIssueByID(ctx, issueID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get issue",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	if issue == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Issue not found",
+			"code":  "ISSUE_NOT_FOUND",
+		})
+		return
+	}
+
+	// Verify user has access to the issue's project
+	if issue.ProjectID != authCtx.Project.ID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Access denied to issue",
+			"code":  "ISSUE_ACCESS_DENIED",
+		})
+		return
+	}
+
+	fingerprints := uniqueStrings(request.Fingerprints)
+	var unknown []string
+	for _, fingerprint := range fingerprints {
+		if !issue.HasMergedFingerprint(fingerprint) {
+			unknown = append(unknown, fingerprint)
+		}
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "Fingerprints weren't merged into the issue",
+			"code":         "INVALID_FINGERPRINTS",
+			"fingerprints": unknown,
+		})
+		return
+	}
+
+	if err := h.merger.Unmerge(ctx, issue, fingerprints, &authCtx.APIKey.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to unmerge issue",
+			"code":  "UNMERGE_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"message":      "Fingerprints unmerged successfully",
+		"issue_id":     issue.ID,
+		"fingerprints": fingerprints,
+	})
+}
+
+// issuesByID returns the issues with the given IDs and responds with an error
+// when they can't be found or don't all belong to the project of the caller
+func (h *IssuesHandler) issuesByID(c *gin.Context, authCtx *models.AuthContext, ids []string) ([]*models.Issue, bool) {
+	found, err := h.issuesRepo.GetIssuesByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get issues",
+			"code":  "INTERNAL_ERROR",
+		})
+		return nil, false
+	}
+
+	issues := make([]*models.Issue, 0, len(ids))
+	var missing []string
+	for _, issueID := range ids {
+		issue, exists := found[issueID]
+		if !exists {
+			missing = append(missing, issueID)
+			continue
+		}
+		// Verify user has access to the issue's project
+		if issue.ProjectID != authCtx.Project.ID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Access denied to issue",
+				"code":  "ISSUE_ACCESS_DENIED",
+			})
+			return nil, false
+		}
+		issues = append(issues, issue)
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":     "Issue not found",
+			"code":      "ISSUE_NOT_FOUND",
+			"issue_ids": missing,
+		})
+		return nil, false
+	}
+
+	return issues, true
+}
+
+// mergePrimary returns the issue that issues merge into: the one with the
+// given ID, or the one with the most events when no ID is given. It returns
+// nil when no issue has the given ID.
+func mergePrimary(issues []*models.Issue, primaryID string) *models.Issue {
+	if primaryID != "" {
+		for _, issue := range issues {
+			if issue.ID == primaryID {
+				return issue
+			}
+		}
+		return nil
+	}
+
+	var primary *models.Issue
+	for _, issue := range issues {
+		if primary == nil || issue.EventCount > primary.EventCount {
+			primary = issue
+		}
+	}
+	return primary
+}
+
+// uniqueStrings returns the values without duplicates, in their first order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// containsString reports whether the values contain a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
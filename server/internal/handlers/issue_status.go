@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"server/internal/models"
+	"server/internal/release"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// statusRequest is the body of issue status updates
+type statusRequest struct {
+	Status        string `json:"status" binding:"required"`
+	InRelease     string `json:"in_release"`      // Resolve in this release
+	InNextRelease bool   `json:"in_next_release"` // Resolve in the release after the latest one
+
+	Ignore *ignoreRequest `json:"ignore"` // Conditions an ignored issue reopens on
+}
+
+// ignoreRequest holds the conditions of a conditional ignore. The issue
+// reopens when any of them trips.
+type ignoreRequest struct {
+	Minutes       int64      `json:"minutes"`        // Reopen after this many minutes
+	Until         *time.Time `json:"until"`          // Reopen at this time
+	EventCount    uint64     `json:"event_count"`    // Reopen after this many more events
+	UserCount     uint64     `json:"user_count"`     // Reopen after this many more affected users
+	EventRate     uint64     `json:"event_rate"`     // Reopen when more events than this arrive within the window
+	WindowMinutes int64      `json:"window_minutes"` // Window of the event rate, defaults to an hour
+}
+
+// problem returns what is wrong with the conditions, or "" when they are valid
+func (r *ignoreRequest) problem(now time.Time) string {
+	switch {
+	case r.Minutes == 0 && r.Until == nil && r.EventCount == 0 && r.UserCount == 0 && r.EventRate == 0:
+		return "ignore requires at least one condition"
+	case r.Minutes != 0 && r.Until != nil:
+		return "minutes and until are exclusive"
+	case r.Minutes < 0 || time.Duration(r.Minutes)*time.Minute > models.MaxIgnoreDuration:
+		return "minutes is out of range"
+	case r.Until != nil && !r.Until.After(now):
+		return "until must be in the future"
+	case r.Until != nil && r.Until.Sub(now) > models.MaxIgnoreDuration:
+		return "until is too far in the future"
+	case r.WindowMinutes != 0 && r.EventRate == 0:
+		return "window_minutes requires event_rate"
+	case r.WindowMinutes < 0 || time.Duration(r.WindowMinutes)*time.Minute > models.MaxIgnoreRateWindow:
+		return "window_minutes is out of range"
+	default:
+		return ""
+	}
+}
+
+// conditions returns the ignore conditions of the request. The counts are
+// relative until forIssue adds the counts of an issue to them.
+func (r *ignoreRequest) conditions(now time.Time) *models.IgnoreConditions {
+	conditions := &models.IgnoreConditions{
+		UntilEventCount: r.EventCount,
+		UntilUserCount:  r.UserCount,
+		EventRate:       r.EventRate,
+	}
+
+	switch {
+	case r.Until != nil:
+		until := r.Until.UTC()
+		conditions.Until = &until
+	case r.Minutes > 0:
+		until := now.Add(time.Duration(r.Minutes) * time.Minute).UTC()
+		conditions.Until = &until
+	}
+	if r.EventRate > 0 {
+		window := models.DefaultIgnoreRateWindow
+		if r.WindowMinutes > 0 {
+			window = time.Duration(r.WindowMinutes) * time.Minute
+		}
+		conditions.RateWindow = uint32(window / time.Second)
+	}
+
+	return conditions
+}
+
+// forIssue returns a status update built by statusUpdate for an issue. Its
+// relative ignore counts become totals by adding the counts of the issue.
+func forIssue(update *models.StatusUpdate, issue *models.Issue) *models.StatusUpdate {
+	if !relativeCounts(update) {
+		return update
+	}
+
+	ignore := *update.Ignore
+	if ignore.UntilEventCount > 0 {
+		ignore.UntilEventCount += issue.EventCount
+	}
+	if ignore.UntilUserCount > 0 {
+		ignore.UntilUserCount += issue.UserCount
+	}
+
+	issueUpdate := *update
+	issueUpdate.Ignore = &ignore
+	return &issueUpdate
+}
+
+// relativeCounts reports whether a status update built by statusUpdate has
+// ignore counts, which differ per issue
+func relativeCounts(update *models.StatusUpdate) bool {
+	return update.Ignore != nil && (update.Ignore.UntilEventCount > 0 || update.Ignore.UntilUserCount > 0)
+}
+
+// valid validates the request and responds with an error when it is invalid
+func (r *statusRequest) valid(c *gin.Context) bool {
+	switch models.IssueStatus(r.Status) {
+	case models.StatusUnresolved, models.StatusResolved, models.StatusIgnored:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "Invalid status",
+			"code":           "INVALID_STATUS",
+			"valid_statuses": []string{"unresolved", "resolved", "ignored"},
+		})
+		return false
+	}
+
+	if r.Ignore != nil {
+		problem := r.Ignore.problem(time.Now())
+		if problem == "" && models.IssueStatus(r.Status) != models.StatusIgnored {
+			problem = "ignore requires the ignored status"
+		}
+		if problem != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid ignore conditions",
+				"code":    "INVALID_IGNORE_CONDITIONS",
+				"details": problem,
+			})
+			return false
+		}
+	}
+
+	if r.InRelease == "" && !r.InNextRelease {
+		return true
+	}
+
+	var problem string
+	switch {
+	case models.IssueStatus(r.Status) != models.StatusResolved:
+		problem = "in_release and in_next_release require the resolved status"
+	case r.InRelease != "" && r.InNextRelease:
+		problem = "in_release and in_next_release are exclusive"
+	case len(r.InRelease) > 200:
+		problem = "in_release is too long"
+	default:
+		return true
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "Invalid resolution",
+		"code":    "INVALID_RESOLUTION",
+		"details": problem,
+	})
+	return false
+}
+
+// statusUpdate builds the status update of a valid request for issues of a
+// project. Resolving in the next release stores the latest release of the
+// project, so only events from newer releases reopen the issue. Ignore counts
+// are relative; forIssue applies them to an issue.
+func (h *IssuesHandler) statusUpdate(ctx context.Context, projectID uuid.UUID, request *statusRequest) (*models.StatusUpdate, error) {
+	update := &models.StatusUpdate{Status: models.IssueStatus(request.Status)}
+	if request.Ignore != nil {
+		update.Ignore = request.Ignore.conditions(time.Now())
+	}
+
+	switch {
+	case request.InRelease != "":
+		update.Resolution = models.ResolutionInRelease
+		update.ResolvedInRelease = request.InRelease
+	case request.InNextRelease:
+		firstSeen, err := h.eventsRepo.GetReleaseFirstSeen(ctx, projectID, nil)
+		if err != nil {
+			return nil, err
+		}
+		releases := make([]string, 0, len(firstSeen))
+		for name := range firstSeen {
+			releases = append(releases, name)
+		}
+		update.Resolution = models.ResolutionInNextRelease
+		update.ResolvedInRelease = release.Latest(releases, firstSeen)
+	}
+
+	return update, nil
+}
@@ -7,21 +7,32 @@ import (
 	"server/internal/middleware"
 	"server/internal/models"
 	"server/internal/repository"
+	"server/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 // IssuesHandler handles issues-related endpoints
 type IssuesHandler struct {
-	issuesRepo *repository.IssuesRepository
-	eventsRepo *repository.EventsRepository
+	issuesRepo    *repository.IssuesRepository
+	eventsRepo    *repository.EventsRepository
+	activityRepo  *repository.ActivityRepository
+	bulkJobsRepo  *repository.BulkJobsRepository
+	assigneesRepo *repository.AssigneesRepository
+	merger        *services.IssueMerger
+	bulk          *services.BulkRunner
 }
 
 // NewIssuesHandler creates a new issues handler
-func NewIssuesHandler(issuesRepo *repository.IssuesRepository, eventsRepo *repository.EventsRepository) *IssuesHandler {
+func NewIssuesHandler(issuesRepo *repository.IssuesRepository, eventsRepo *repository.EventsRepository, activityRepo *repository.ActivityRepository, bulkJobsRepo *repository.BulkJobsRepository, assigneesRepo *repository.AssigneesRepository, merger *services.IssueMerger, bulk *services.BulkRunner) *IssuesHandler {
 	return &IssuesHandler{
-		issuesRepo: issuesRepo,
-		eventsRepo: eventsRepo,
+		issuesRepo:    issuesRepo,
+		eventsRepo:    eventsRepo,
+		activityRepo:  activityRepo,
+		bulkJobsRepo:  bulkJobsRepo,
+		assigneesRepo: assigneesRepo,
+		merger:        merger,
+		bulk:          bulk,
 	}
 }
 
@@ -62,6 +73,16 @@ func (h *IssuesHandler) GetIssues(c *gin.Context) {
 		}
 	}
 
+	window, ok := parseOptionalTimeWindow(c, query.TimeWindowParams)
+	if !ok {
+		return
+	}
+	query.Window = window
+
+	if !parseAssignedTo(c, authCtx, &query) {
+		return
+	}
+
 	// Validate pagination
 	if query.Page < 1 {
 		query.Page = 1
@@ -158,9 +179,7 @@ func (h *IssuesHandler) UpdateIssueStatus(c *gin.Context) {
 	}
 
 	// Parse request body
-	var request struct {
-		Status string `json:"status" binding:"required"`
-	}
+	var request statusRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request body",
@@ -171,20 +190,7 @@ func (h *IssuesHandler) UpdateIssueStatus(c *gin.Context) {
 	}
 
 	// Validate status
-	var status models.IssueStatus
-	switch request.Status {
-	case "unresolved":
-		status = models.StatusUnresolved
-	case "resolved":
-		status = models.StatusResolved
-	case "ignored":
-		status = models.StatusIgnored
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":          "Invalid status",
-			"code":           "INVALID_STATUS",
-			"valid_statuses": []string{"unresolved", "resolved", "ignored"},
-		})
+	if !request.valid(c) {
 		return
 	}
 
@@ -216,20 +222,34 @@ func (h *IssuesHandler) UpdateIssueStatus(c *gin.Context) {
 		return
 	}
 
+	update, err := h.statusUpdate(ctx, issue.ProjectID, &request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get releases",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+	update = forIssue(update, issue)
+
 	// Update status
-	if err := h.issuesRepo.UpdateIssueStatus(ctx, issueID, status); err != nil {
+	if err := h.issuesRepo.UpdateIssueStatus(ctx, issueID, update); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to update issue status",
 			"code":  "UPDATE_FAILED",
 		})
 		return
 	}
+	h.recordStatusChange(ctx, issue, update, &authCtx.APIKey.ID)
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":    true,
-		"message":    "Issue status updated successfully",
-		"issue_id":   issueID,
-		"new_status": string(status),
+		"success":             true,
+		"message":             "Issue status updated successfully",
+		"issue_id":            issueID,
+		"new_status":          string(update.Status),
+		"resolution":          string(update.Resolution),
+		"resolved_in_release": update.ResolvedInRelease,
+		"ignore":              update.Ignore,
 	})
 }
 
@@ -254,7 +274,10 @@ func (h *IssuesHandler) GetIssueTimeSeries(c *gin.Context) {
 		return
 	}
 
-	timeRange := c.DefaultQuery("time_range", "24h")
+	window, ok := parseTimeWindow(c, timeWindowParams(c))
+	if !ok {
+		return
+	}
 
 	// Get issue to verify access
 	ctx := c.Request.Context()
@@ -285,7 +308,7 @@ func (h *IssuesHandler) GetIssueTimeSeries(c *gin.Context) {
 	}
 
 	// Get time series data
-	timeSeries, err := h.issuesRepo.GetIssueTimeSeries(ctx, issueID, timeRange)
+	timeSeries, err := h.issuesRepo.GetIssueTimeSeries(ctx, issueID, window)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get time series",
@@ -296,7 +319,11 @@ func (h *IssuesHandler) GetIssueTimeSeries(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"issue_id":   issueID,
-		"time_range": timeRange,
+		"time_range": window.Preset,
+		"start":      window.Start,
+		"end":        window.End,
+		"interval":   window.IntervalName(),
+		"tz":         window.Location.String(),
 		"data":       timeSeries,
 	})
 }
@@ -361,9 +388,15 @@ func (h *IssuesHandler) GetIssueEvents(c *gin.Context) {
 		return
 	}
 
+	window, ok := parseOptionalTimeWindow(c, timeWindowParams(c))
+	if !ok {
+		return
+	}
+
 	// Build events query
 	eventsQuery := &models.EventsQuery{
 		IssueID: &issueID,
+		Window:  window,
 		Page:    page,
 		Limit:   limit,
 	}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"server/internal/errors"
+	"server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+
+	// maxNDJSONBodySize limits the decompressed size of a streaming request
+	maxNDJSONBodySize = 1 << 30 // 1GB
+
+	// maxNDJSONLineSize limits the size of a single event line
+	maxNDJSONLineSize = 1 << 20 // 1MB
+
+	// ndjsonChunkSize is the number of events submitted at once
+	ndjsonChunkSize = 100
+
+	// maxReportedLineErrors limits the rejected lines listed in the response
+	maxReportedLineErrors = 20
+)
+
+// ndjsonIngest tracks the progress of a streaming ingest request
+type ndjsonIngest struct {
+	handler *IngestHandler
+	c       *gin.Context
+	authCtx *models.AuthContext
+
+	chunk      []models.IngestEvent
+	lines      int
+	accepted   int
+	rejected   int
+	truncated  int
+	queued     bool
+	lineErrors []gin.H
+}
+
+// ingestNDJSON handles POST /api/v1/ingest with an application/x-ndjson body.
+// Events are decoded one line at a time and submitted in chunks, so memory use
+// doesn't depend on the size of the body. Invalid lines are skipped and
+// reported in the response, oversized fields are truncated.
+func (h *IngestHandler) ingestNDJSON(c *gin.Context, authCtx *models.AuthContext) {
+	body, err := openRequestBody(c, maxNDJSONBodySize)
+	if err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	defer body.Close()
+
+	ingest := &ndjsonIngest{
+		handler: h,
+		c:       c,
+		authCtx: authCtx,
+		chunk:   make([]models.IngestEvent, 0, ndjsonChunkSize),
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxNDJSONLineSize)
+
+	for scanner.Scan() {
+		ingest.lines++
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var event models.IngestEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			ingest.reject(fmt.Sprintf("invalid JSON: %v", err))
+			continue
+		}
+		truncated, err := h.normalizeEvent(&event)
+		if err != nil {
+			ingest.reject(err.Error())
+			continue
+		}
+		if len(truncated) > 0 {
+			ingest.truncated++
+		}
+
+		ingest.chunk = append(ingest.chunk, event)
+		if len(ingest.chunk) >= ndjsonChunkSize {
+			if err := ingest.flush(); err != nil {
+				h.respondSubmitError(c, err, http.StatusInternalServerError, ingest.progress())
+				return
+			}
+		}
+	}
+
+	// Events from complete lines are kept even when the stream breaks off, so
+	// the response tells the client exactly how far it got
+	if err := ingest.flush(); err != nil {
+		h.respondSubmitError(c, err, http.StatusInternalServerError, ingest.progress())
+		return
+	}
+
+	if err := scanner.Err(); err != nil {
+		ingest.respondReadError(err)
+		return
+	}
+
+	if ingest.accepted == 0 && ingest.rejected == 0 {
+		validationErr := errors.NewValidationError("events", "At least one event is required", 0)
+		c.JSON(http.StatusBadRequest, validationErr.ToJSON())
+		return
+	}
+
+	response := ingest.progress()
+	response["success"] = true
+	response["timestamp"] = time.Now().Unix()
+	if ingest.queued {
+		response["message"] = "Events queued for processing"
+		response["queued_count"] = ingest.accepted
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	response["message"] = "Events processed successfully"
+	response["processed_count"] = ingest.accepted
+	c.JSON(http.StatusOK, response)
+}
+
+// reject records an invalid line
+func (n *ndjsonIngest) reject(reason string) {
+	n.rejected++
+	if len(n.lineErrors) < maxReportedLineErrors {
+		n.lineErrors = append(n.lineErrors, gin.H{"line": n.lines, "error": reason})
+	}
+}
+
+// flush submits the buffered events
+func (n *ndjsonIngest) flush() error {
+	if len(n.chunk) == 0 {
+		return nil
+	}
+
+	queued, err := n.handler.submitEvents(n.c.Request.Context(), n.authCtx.Project.ID, n.chunk)
+	if err != nil {
+		return err
+	}
+
+	n.queued = queued
+	n.accepted += len(n.chunk)
+	n.chunk = make([]models.IngestEvent, 0, ndjsonChunkSize)
+	return nil
+}
+
+// progress describes how much of the stream was handled
+func (n *ndjsonIngest) progress() map[string]interface{} {
+	return map[string]interface{}{
+		"project_id":      n.authCtx.Project.ID,
+		"lines_read":      n.lines,
+		"accepted_count":  n.accepted,
+		"rejected_count":  n.rejected,
+		"truncated_count": n.truncated,
+		"rejected_lines":  n.lineErrors,
+	}
+}
+
+// respondReadError reports a body that couldn't be read to the end
+func (n *ndjsonIngest) respondReadError(err error) {
+	status := http.StatusBadRequest
+	response := errors.NewValidationError("request_body", "Failed to read request body", err.Error()).ToJSON()
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case goerrors.As(err, &maxBytesErr):
+		status = http.StatusRequestEntityTooLarge
+		response = errors.NewValidationError("request_body", "Request body too large", maxBytesErr.Limit).ToJSON()
+	case goerrors.Is(err, bufio.ErrTooLong):
+		status = http.StatusRequestEntityTooLarge
+		response = errors.NewValidationError(fmt.Sprintf("line %d", n.lines+1), "Event line too long", maxNDJSONLineSize).ToJSON()
+	}
+
+	for key, value := range n.progress() {
+		response[key] = value
+	}
+	n.c.JSON(status, response)
+}
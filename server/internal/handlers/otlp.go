@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"server/internal/errors"
+	"server/internal/middleware"
+	"server/internal/models"
+	"server/internal/otlp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxOTLPBodySize limits the size of OTLP export requests
+const maxOTLPBodySize = 20 << 20 // 20MB
+
+// OTLPTraces handles POST /v1/traces
+func (h *IngestHandler) OTLPTraces(c *gin.Context) {
+	decoder, body, ok := h.readOTLPRequest(c)
+	if !ok {
+		return
+	}
+
+	data, err := decoder.DecodeTraces(body)
+	if err != nil {
+		validationErr := errors.NewValidationError("request_body", "Invalid OTLP traces payload", err.Error())
+		c.JSON(http.StatusBadRequest, validationErr.ToJSON())
+		return
+	}
+
+	h.processOTLPEvents(c, decoder, decoder.TraceEvents(data), otlp.RejectedSpans)
+}
+
+// OTLPLogs handles POST /v1/logs
+func (h *IngestHandler) OTLPLogs(c *gin.Context) {
+	decoder, body, ok := h.readOTLPRequest(c)
+	if !ok {
+		return
+	}
+
+	data, err := decoder.DecodeLogs(body)
+	if err != nil {
+		validationErr := errors.NewValidationError("request_body", "Invalid OTLP logs payload", err.Error())
+		c.JSON(http.StatusBadRequest, validationErr.ToJSON())
+		return
+	}
+
+	h.processOTLPEvents(c, decoder, decoder.LogEvents(data), otlp.RejectedLogRecords)
+}
+
+// readOTLPRequest reads the (optionally compressed) request body and
+// returns a decoder for its content type
+func (h *IngestHandler) readOTLPRequest(c *gin.Context) (*otlp.Decoder, []byte, bool) {
+	decoder, err := otlp.NewDecoder(c.ContentType())
+	if err != nil {
+		validationErr := errors.NewValidationError("content_type", "Unsupported content type", c.ContentType())
+		c.JSON(http.StatusUnsupportedMediaType, validationErr.ToJSON())
+		return nil, nil, false
+	}
+
+	body, err := readRequestBody(c, maxOTLPBodySize)
+	if err != nil {
+		respondBodyError(c, err, "Failed to read request body")
+		return nil, nil, false
+	}
+
+	return decoder, body, true
+}
+
+// processOTLPEvents validates and processes converted events. Invalid events
+// are dropped and reported through the OTLP partial success response.
+func (h *IngestHandler) processOTLPEvents(c *gin.Context, decoder *otlp.Decoder, events []models.IngestEvent, rejectedField string) {
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		authErr := errors.NewAuthenticationError("ingest", "Authentication required")
+		c.JSON(http.StatusUnauthorized, authErr.ToJSON())
+		return
+	}
+
+	validEvents := make([]models.IngestEvent, 0, len(events))
+	var rejectedReasons []string
+	for i := range events {
+		if _, err := h.normalizeEvent(&events[i]); err != nil {
+			rejectedReasons = append(rejectedReasons, err.Error())
+			continue
+		}
+		validEvents = append(validEvents, events[i])
+	}
+
+	if len(validEvents) > 0 {
+		ctx := c.Request.Context()
+		if _, err := h.submitEvents(ctx, authCtx.Project.ID, validEvents); err != nil {
+			// 503 tells OTLP exporters to retry the export
+			h.respondSubmitError(c, err, http.StatusServiceUnavailable, nil)
+			return
+		}
+	}
+
+	errorMessage := ""
+	if len(rejectedReasons) > 0 {
+		errorMessage = fmt.Sprintf("%d events rejected: %s", len(rejectedReasons), rejectedReasons[0])
+	}
+
+	body, contentType := decoder.EncodeResponse(int64(len(rejectedReasons)), errorMessage, rejectedField)
+	c.Data(http.StatusOK, contentType, body)
+}
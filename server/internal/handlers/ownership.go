@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"server/internal/ownership"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ownershipRulesRequest is the body of ownership rule updates
+type ownershipRulesRequest struct {
+	Rules string `json:"rules"` // One rule per line, see ownership.Rule
+}
+
+// GetOwnershipRules handles GET /api/v1/projects/:id/ownership
+func (h *ProjectsHandler) GetOwnershipRules(c *gin.Context) {
+	projectID, ok := authorizeProject(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectsRepo.GetByID(c.Request.Context(), projectID)
+	if err != nil || project == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get project",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	text, rules, err := ownership.ParseSettings(project.Settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Stored ownership rules are invalid",
+			"code":    "INVALID_OWNERSHIP_RULES",
+			"details": err.Error(),
+		})
+		return
+	}
+	if rules == nil {
+		rules = []ownership.Rule{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": text, "parsed": rules})
+}
+
+// UpdateOwnershipRules handles PUT /api/v1/projects/:id/ownership. Every
+// owner must be a user or team of the project's space.
+func (h *ProjectsHandler) UpdateOwnershipRules(c *gin.Context) {
+	projectID, ok := authorizeProject(c)
+	if !ok {
+		return
+	}
+
+	var request ownershipRulesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid JSON format",
+			"code":    "INVALID_REQUEST",
+			"details": err.Error(),
+		})
+		return
+	}
+	rules, err := ownership.Parse(request.Rules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid ownership rules",
+			"code":    "INVALID_OWNERSHIP_RULES",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	project, err := h.projectsRepo.GetByID(ctx, projectID)
+	if err != nil || project == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get project",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	owners, err := h.ownershipRules.Resolve(ctx, project.SpaceID, rules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to look up owners",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+	var unknown []string
+	for _, rule := range rules {
+		for _, owner := range rule.Owners {
+			if owners[owner] == nil {
+				unknown = append(unknown, fmt.Sprintf("line %d: unknown %s %s", rule.Line, owner.Type, owner))
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid ownership rules",
+			"code":    "UNKNOWN_OWNERS",
+			"details": strings.Join(unknown, "; "),
+		})
+		return
+	}
+
+	// Only the rules are written, other settings may change concurrently
+	var value interface{}
+	if len(rules) > 0 {
+		value = request.Rules
+	}
+	if err := h.projectsRepo.UpdateSetting(ctx, projectID, ownership.SettingsKey, value); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save ownership rules",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+	h.ownershipRules.Invalidate(projectID)
+
+	text := request.Rules
+	if rules == nil {
+		rules = []ownership.Rule{}
+		text = ""
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": text, "parsed": rules})
+}
@@ -9,6 +9,7 @@ import (
 	"server/internal/middleware"
 	"server/internal/models"
 	"server/internal/repository"
+	"server/internal/services"
 )
 
 // ProjectsHandler handles project-related endpoints
@@ -16,6 +17,9 @@ type ProjectsHandler struct {
 	projectsRepo *repository.ProjectsRepository
 	eventsRepo   *repository.EventsRepository
 	issuesRepo   *repository.IssuesRepository
+
+	groupingRules  *services.GroupingRules
+	ownershipRules *services.OwnershipRules
 }
 
 // NewProjectsHandler creates a new projects handler
@@ -23,11 +27,15 @@ func NewProjectsHandler(
 	projectsRepo *repository.ProjectsRepository,
 	eventsRepo *repository.EventsRepository,
 	issuesRepo *repository.IssuesRepository,
+	groupingRules *services.GroupingRules,
+	ownershipRules *services.OwnershipRules,
 ) *ProjectsHandler {
 	return &ProjectsHandler{
-		projectsRepo: projectsRepo,
-		eventsRepo:   eventsRepo,
-		issuesRepo:   issuesRepo,
+		projectsRepo:   projectsRepo,
+		eventsRepo:     eventsRepo,
+		issuesRepo:     issuesRepo,
+		groupingRules:  groupingRules,
+		ownershipRules: ownershipRules,
 	}
 }
 
@@ -112,11 +120,14 @@ func (h *ProjectsHandler) GetProjectStats(c *gin.Context) {
 		return
 	}
 
-	timeRange := c.DefaultQuery("time_range", "24h")
+	window, ok := parseTimeWindow(c, timeWindowParams(c))
+	if !ok {
+		return
+	}
 
 	// Get project statistics
 	ctx := c.Request.Context()
-	stats, err := h.eventsRepo.GetProjectStats(ctx, projectID, timeRange)
+	stats, err := h.eventsRepo.GetProjectStats(ctx, projectID, window)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get project statistics",
@@ -181,6 +192,16 @@ func (h *ProjectsHandler) GetProjectIssues(c *gin.Context) {
 	// Set project ID
 	query.ProjectID = &projectID
 
+	window, ok := parseOptionalTimeWindow(c, query.TimeWindowParams)
+	if !ok {
+		return
+	}
+	query.Window = window
+
+	if !parseAssignedTo(c, authCtx, &query) {
+		return
+	}
+
 	// Validate pagination
 	if query.Page < 1 {
 		query.Page = 1
@@ -247,7 +268,6 @@ func (h *ProjectsHandler) GetProjectEvents(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
 	environment := c.Query("environment")
 	userID := c.Query("user_id")
-	timeRange := c.Query("time_range")
 
 	if page < 1 {
 		page = 1
@@ -269,9 +289,12 @@ func (h *ProjectsHandler) GetProjectEvents(c *gin.Context) {
 	if userID != "" {
 		eventsQuery.UserID = &userID
 	}
-	if timeRange != "" {
-		eventsQuery.TimeRange = &timeRange
+
+	window, ok := parseOptionalTimeWindow(c, timeWindowParams(c))
+	if !ok {
+		return
 	}
+	eventsQuery.Window = window
 
 	// Get events
 	ctx := c.Request.Context()
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+
+	"server/internal/errors"
+	"server/internal/middleware"
+	"server/internal/models"
+	"server/internal/sentry"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxSentryBodySize limits the size of envelopes and store payloads
+const maxSentryBodySize = 20 << 20 // 20MB
+
+// SentryEnvelope handles POST /api/:project/envelope/
+func (h *IngestHandler) SentryEnvelope(c *gin.Context) {
+	authCtx, ok := h.sentryAuthContext(c)
+	if !ok {
+		return
+	}
+
+	body, err := readRequestBody(c, maxSentryBodySize)
+	if err != nil {
+		respondBodyError(c, err, "Failed to read envelope")
+		return
+	}
+
+	envelope, err := sentry.ParseEnvelope(bytes.NewReader(body))
+	if err != nil {
+		validationErr := errors.NewValidationError("request_body", "Invalid envelope format", err.Error())
+		c.JSON(http.StatusBadRequest, validationErr.ToJSON())
+		return
+	}
+
+	events, err := envelope.Events()
+	if err != nil {
+		validationErr := errors.NewValidationError("request_body", "Invalid event item", err.Error())
+		c.JSON(http.StatusBadRequest, validationErr.ToJSON())
+		return
+	}
+
+	eventID := envelope.Header.EventID
+	if eventID == "" && len(events) > 0 {
+		eventID = events[0].EventID
+	}
+
+	// Envelopes that only carry sessions, transactions or client reports are
+	// acknowledged without processing
+	if !h.processSentryEvents(c, authCtx, events) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": sentryEventID(eventID)})
+}
+
+// SentryStore handles the legacy POST /api/:project/store/ endpoint
+func (h *IngestHandler) SentryStore(c *gin.Context) {
+	authCtx, ok := h.sentryAuthContext(c)
+	if !ok {
+		return
+	}
+
+	body, err := readRequestBody(c, maxSentryBodySize)
+	if err != nil {
+		respondBodyError(c, err, "Failed to read event")
+		return
+	}
+
+	event, err := sentry.ParseEvent(body)
+	if err != nil {
+		validationErr := errors.NewValidationError("request_body", "Invalid JSON format", err.Error())
+		c.JSON(http.StatusBadRequest, validationErr.ToJSON())
+		return
+	}
+
+	if !h.processSentryEvents(c, authCtx, []*sentry.Event{event}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": sentryEventID(event.EventID)})
+}
+
+// sentryAuthContext returns the auth context and verifies that the project in
+// the DSN path matches the project of the public key
+func (h *IngestHandler) sentryAuthContext(c *gin.Context) (*models.AuthContext, bool) {
+	authCtx := middleware.GetAuthContext(c)
+	if authCtx == nil {
+		authErr := errors.NewAuthenticationError("ingest", "Authentication required")
+		c.JSON(http.StatusUnauthorized, authErr.ToJSON())
+		return nil, false
+	}
+
+	projectID, err := uuid.Parse(c.Param("project"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid project ID format",
+			"code":  "INVALID_PROJECT_ID",
+		})
+		return nil, false
+	}
+
+	if projectID != authCtx.Project.ID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Access denied to project",
+			"code":  "PROJECT_ACCESS_DENIED",
+		})
+		return nil, false
+	}
+
+	return authCtx, true
+}
+
+// processSentryEvents converts, validates and processes Sentry events.
+// Invalid events are dropped individually like on the native ingest
+// endpoint; the request only fails when every event was rejected.
+func (h *IngestHandler) processSentryEvents(c *gin.Context, authCtx *models.AuthContext, events []*sentry.Event) bool {
+	if len(events) == 0 {
+		return true
+	}
+
+	ingestEvents, results := h.sentryIngestEvents(events)
+	if len(ingestEvents) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "All events were rejected",
+			"code":           "VALIDATION_ERROR",
+			"rejected_count": len(results),
+			"results":        results,
+		})
+		return false
+	}
+	if len(results) > 0 {
+		log.Printf("Dropped %d of %d Sentry events for project %s", len(results), len(events), authCtx.Project.ID)
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.submitEvents(ctx, authCtx.Project.ID, ingestEvents); err != nil {
+		h.respondSubmitError(c, err, http.StatusInternalServerError, nil)
+		return false
+	}
+
+	return true
+}
+
+// sentryIngestEvents converts and validates Sentry events. It returns the
+// valid events and the results of the rejected ones.
+func (h *IngestHandler) sentryIngestEvents(events []*sentry.Event) ([]models.IngestEvent, []models.EventResult) {
+	ingestEvents := make([]models.IngestEvent, 0, len(events))
+	var rejected []models.EventResult
+	for i, event := range events {
+		ingestEvent := event.ToIngestEvent(maxTags)
+		if _, err := h.normalizeEvent(&ingestEvent); err != nil {
+			rejected = append(rejected, models.EventResult{
+				Index:  i,
+				Status: models.EventRejected,
+				Error:  err.Error(),
+			})
+			continue
+		}
+		ingestEvents = append(ingestEvents, ingestEvent)
+	}
+	return ingestEvents, rejected
+}
+
+// sentryEventID returns the event ID in the 32 character hex form SDKs expect
+func sentryEventID(eventID string) string {
+	if eventID != "" {
+		return eventID
+	}
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
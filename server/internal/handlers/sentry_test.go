@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"server/internal/models"
+	"server/internal/sentry"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSentryIngestEvents_DropsInvalidEventsIndividually(t *testing.T) {
+	h := &IngestHandler{}
+
+	tags := make(sentry.Tags)
+	for i := 0; i < maxTags; i++ {
+		tags[fmt.Sprintf("tag%d", i)] = "v"
+	}
+	old := time.Now().Add(-8 * 24 * time.Hour).Format(time.RFC3339)
+
+	events := []*sentry.Event{
+		parseSentryEvent(t, `{"message": "ok", "server_name": "web-1", "platform": "python"}`),
+		parseSentryEvent(t, `{"message": "too old", "timestamp": "`+old+`"}`),
+		parseSentryEvent(t, `{"message": "many tags", "server_name": "web-1", "logger": "app", "platform": "python"}`),
+	}
+	events[2].Tags = tags
+
+	accepted, rejected := h.sentryIngestEvents(events)
+
+	if len(accepted) != 2 {
+		t.Fatalf("Expected 2 accepted events, got %d", len(accepted))
+	}
+	if len(accepted[1].Tags) != maxTags {
+		t.Errorf("Expected derived tags to be skipped at the limit, got %d tags", len(accepted[1].Tags))
+	}
+	if accepted[0].Tags["server_name"] != "web-1" {
+		t.Errorf("Expected server_name tag below the limit, got %v", accepted[0].Tags)
+	}
+	if len(rejected) != 1 || rejected[0].Index != 1 || rejected[0].Status != models.EventRejected {
+		t.Errorf("Expected only the old event to be rejected, got %+v", rejected)
+	}
+}
+
+func TestSentryEnvelope_GzipBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	payload := `{"message":"boom","level":"error"}`
+	envelope := `{"event_id":"9ec79c33ec9942ab8353589fcb2e04dc"}
+{"type":"event","length":` + strconv.Itoa(len(payload)) + `}
+` + payload + "\n"
+
+	c := newBodyContext(compressBody(t, "gzip", []byte(envelope)), "gzip")
+	body, err := readRequestBody(c, maxSentryBodySize)
+	if err != nil {
+		t.Fatalf("Failed to read gzip body: %v", err)
+	}
+
+	parsed, err := sentry.ParseEnvelope(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to parse decompressed envelope: %v", err)
+	}
+	events, err := parsed.Events()
+	if err != nil || len(events) != 1 || events[0].Message.String() != "boom" {
+		t.Errorf("Expected the gzip envelope's event, got %+v (%v)", events, err)
+	}
+}
+
+func parseSentryEvent(t *testing.T, payload string) *sentry.Event {
+	t.Helper()
+	event, err := sentry.ParseEvent([]byte(payload))
+	if err != nil {
+		t.Fatalf("Failed to parse event: %v", err)
+	}
+	return event
+}
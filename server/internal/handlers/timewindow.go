@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseTimeWindow parses the time window query parameters of analytics
+// endpoints and responds with an error when they are invalid
+func parseTimeWindow(c *gin.Context, params models.TimeWindowParams) (*models.TimeWindow, bool) {
+	window, err := params.Parse(time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid time window",
+			"code":    "INVALID_TIME_WINDOW",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return window, true
+}
+
+// parseOptionalTimeWindow is parseTimeWindow for endpoints that aren't limited
+// to a time window unless one is given. It returns nil without parameters.
+func parseOptionalTimeWindow(c *gin.Context, params models.TimeWindowParams) (*models.TimeWindow, bool) {
+	if params.TimeRange == "" && params.Start == "" && params.End == "" {
+		return nil, true
+	}
+	return parseTimeWindow(c, params)
+}
+
+// timeWindowParams reads the time window query parameters of a request
+func timeWindowParams(c *gin.Context) models.TimeWindowParams {
+	var params models.TimeWindowParams
+	// Binding only fails for mismatched types, and every field is a string
+	_ = c.ShouldBindQuery(&params)
+	return params
+}
@@ -51,95 +51,137 @@ func (m *AuthMiddleware) RequireAPIKey(requiredScopes ...models.APIKeyScope) gin
 			return
 		}
 
-		// Validate API key format
-		if !isValidAPIKeyFormat(apiKey) {
-			authErr := errors.NewAuthenticationError("api_key_validation", "Invalid API key format")
-			c.JSON(http.StatusUnauthorized, authErr.ToJSON())
-			c.Abort()
+		if !m.authenticate(c, apiKey, requiredScopes) {
 			return
 		}
 
-		// Hash the API key for database lookup
-		keyHash := hashAPIKey(apiKey)
-
-		// Get API key from database
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		c.Next()
+	}
+}
 
-		dbAPIKey, err := m.apiKeysRepo.GetByHash(ctx, keyHash)
-		if err != nil {
-			dbErr := errors.NewDatabaseError("GetByHash", err)
-			c.JSON(http.StatusInternalServerError, dbErr.ToJSON())
-			c.Abort()
-			return
+// RequireSentryAuth middleware that authenticates Sentry SDK requests. The API
+// key is used as the DSN public key and is read from the X-Sentry-Auth header,
+// falling back to the sentry_key query parameter used by browser SDKs.
+func (m *AuthMiddleware) RequireSentryAuth(requiredScopes ...models.APIKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := parseSentryAuthHeader(c.GetHeader("X-Sentry-Auth"))["sentry_key"]
+		if apiKey == "" {
+			// Some SDKs send the auth header through Authorization
+			if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Sentry ") {
+				apiKey = parseSentryAuthHeader(auth)["sentry_key"]
+			}
+		}
+		if apiKey == "" {
+			apiKey = c.Query("sentry_key")
 		}
 
-		if dbAPIKey == nil {
-			authErr := errors.NewAuthenticationError("api_key_validation", "Invalid API key")
+		if apiKey == "" {
+			authErr := errors.NewAuthenticationError("sentry_auth", "Missing Sentry public key")
 			c.JSON(http.StatusUnauthorized, authErr.ToJSON())
 			c.Abort()
 			return
 		}
 
-		// Check if API key is expired
-		if dbAPIKey.IsExpired() {
-			authErr := errors.NewAuthenticationError("api_key_validation", "API key has expired")
-			c.JSON(http.StatusUnauthorized, authErr.ToJSON())
-			c.Abort()
+		if !m.authenticate(c, apiKey, requiredScopes) {
 			return
 		}
 
-		// Check required scopes
-		for _, requiredScope := range requiredScopes {
-			if !dbAPIKey.HasScope(requiredScope) {
-				authzErr := errors.NewAuthorizationError(string(requiredScope), "api_access")
-				c.JSON(http.StatusForbidden, authzErr.ToJSON())
-				c.Abort()
-				return
-			}
-		}
+		c.Next()
+	}
+}
 
-		// Get project information
-		project, err := m.projectsRepo.GetByID(ctx, dbAPIKey.ProjectID)
-		if err != nil {
-			dbErr := errors.NewDatabaseError("GetByID", err)
-			c.JSON(http.StatusInternalServerError, dbErr.ToJSON())
-			c.Abort()
-			return
-		}
+// authenticate validates the given API key, checks the required scopes and
+// stores the auth context on success. It aborts the request and returns false
+// on failure.
+func (m *AuthMiddleware) authenticate(c *gin.Context, apiKey string, requiredScopes []models.APIKeyScope) bool {
+	// Validate API key format
+	if !isValidAPIKeyFormat(apiKey) {
+		authErr := errors.NewAuthenticationError("api_key_validation", "Invalid API key format")
+		c.JSON(http.StatusUnauthorized, authErr.ToJSON())
+		c.Abort()
+		return false
+	}
 
-		if project == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Project not found",
-				"code":  "PROJECT_NOT_FOUND",
-			})
+	// Hash the API key for database lookup
+	keyHash := hashAPIKey(apiKey)
+
+	// Get API key from database
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dbAPIKey, err := m.apiKeysRepo.GetByHash(ctx, keyHash)
+	if err != nil {
+		dbErr := errors.NewDatabaseError("GetByHash", err)
+		c.JSON(http.StatusInternalServerError, dbErr.ToJSON())
+		c.Abort()
+		return false
+	}
+
+	if dbAPIKey == nil {
+		authErr := errors.NewAuthenticationError("api_key_validation", "Invalid API key")
+		c.JSON(http.StatusUnauthorized, authErr.ToJSON())
+		c.Abort()
+		return false
+	}
+
+	// Check if API key is expired
+	if dbAPIKey.IsExpired() {
+		authErr := errors.NewAuthenticationError("api_key_validation", "API key has expired")
+		c.JSON(http.StatusUnauthorized, authErr.ToJSON())
+		c.Abort()
+		return false
+	}
+
+	// Check required scopes
+	for _, requiredScope := range requiredScopes {
+		if !dbAPIKey.HasScope(requiredScope) {
+			authzErr := errors.NewAuthorizationError(string(requiredScope), "api_access")
+			c.JSON(http.StatusForbidden, authzErr.ToJSON())
 			c.Abort()
-			return
+			return false
 		}
+	}
 
-		// Update last used timestamp (async)
-		go func() {
-			updateCtx, updateCancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer updateCancel()
+	// Get project information
+	project, err := m.projectsRepo.GetByID(ctx, dbAPIKey.ProjectID)
+	if err != nil {
+		dbErr := errors.NewDatabaseError("GetByID", err)
+		c.JSON(http.StatusInternalServerError, dbErr.ToJSON())
+		c.Abort()
+		return false
+	}
 
-			if err := m.apiKeysRepo.UpdateLastUsed(updateCtx, dbAPIKey.ID); err != nil {
-				// Log error but don't fail the request
-				fmt.Printf("Failed to update API key last used timestamp: %v\n", err)
-			}
-		}()
+	if project == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Project not found",
+			"code":  "PROJECT_NOT_FOUND",
+		})
+		c.Abort()
+		return false
+	}
 
-		// Set auth context
-		authCtx := &models.AuthContext{
-			APIKey:  dbAPIKey,
-			Project: project,
-		}
+	// Update last used timestamp (async)
+	go func() {
+		updateCtx, updateCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer updateCancel()
 
-		c.Set("auth", authCtx)
-		c.Set("api_key", dbAPIKey)
-		c.Set("project", project)
+		if err := m.apiKeysRepo.UpdateLastUsed(updateCtx, dbAPIKey.ID); err != nil {
+			// Log error but don't fail the request
+			fmt.Printf("Failed to update API key last used timestamp: %v\n", err)
+		}
+	}()
 
-		c.Next()
+	// Set auth context
+	authCtx := &models.AuthContext{
+		APIKey:  dbAPIKey,
+		Project: project,
 	}
+
+	c.Set("auth", authCtx)
+	c.Set("api_key", dbAPIKey)
+	c.Set("project", project)
+
+	return true
 }
 
 // RequireScope middleware that checks for specific scopes (use after RequireAPIKey)
@@ -196,6 +238,27 @@ func GetAPIKey(c *gin.Context) *models.APIKey {
 	return nil
 }
 
+// parseSentryAuthHeader parses a header of the form
+// "Sentry sentry_version=7, sentry_key=<key>, sentry_client=<client>"
+func parseSentryAuthHeader(header string) map[string]string {
+	values := make(map[string]string)
+
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "Sentry ") {
+		return values
+	}
+
+	for _, part := range strings.Split(strings.TrimPrefix(header, "Sentry "), ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return values
+}
+
 // isValidAPIKeyFormat validates the API key format
 func isValidAPIKeyFormat(apiKey string) bool {
 	// Expected format: errly_<4_chars>_<64_hex_chars>
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ActivityType is the kind of an issue activity entry
+type ActivityType string
+
+const (
+	// ActivityFirstSeen records the first event of the issue
+	ActivityFirstSeen ActivityType = "first_seen"
+
+	// ActivityStatus records a status change made through the API
+	ActivityStatus ActivityType = "status_change"
+
+	// ActivityComment is a markdown comment left on the issue
+	ActivityComment ActivityType = "comment"
+
+	// ActivityRegression records an issue reopening because events arrived
+	// after it was resolved
+	ActivityRegression ActivityType = "regression"
+
+	// ActivityUnignored records an ignored issue reopening because a
+	// condition it was ignored until tripped
+	ActivityUnignored ActivityType = "unignored"
+
+	// ActivityMerge records issues merging into the issue
+	ActivityMerge ActivityType = "merge"
+
+	// ActivityUnmerge records fingerprints splitting out of the issue
+	ActivityUnmerge ActivityType = "unmerge"
+
+	// ActivityAssigned records the issue being assigned or unassigned
+	ActivityAssigned ActivityType = "assigned"
+)
+
+// MaxCommentLength is the longest comment accepted, in bytes
+const MaxCommentLength = 10000
+
+// MaxCommentMentions is the most distinct users and teams a comment can mention
+const MaxCommentMentions = 50
+
+// IssueActivity is an entry of the activity log of an issue. APIKeyID is the
+// key that made the change and is nil for changes made by Errly itself.
+// UserID is the user the key acted for at the time, if any.
+type IssueActivity struct {
+	ID        uuid.UUID              `json:"id" db:"id"`
+	ProjectID uuid.UUID              `json:"project_id" db:"project_id"`
+	IssueID   string                 `json:"issue_id" db:"issue_id"`
+	Type      ActivityType           `json:"type" db:"type"`
+	APIKeyID  *uuid.UUID             `json:"api_key_id" db:"api_key_id"`
+	UserID    *uuid.UUID             `json:"user_id" db:"user_id"`
+	Data      map[string]interface{} `json:"data" db:"data"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+}
+
+// mentionPattern matches "@<email>" mentions of users and "@<slug>" mentions
+// of teams that don't follow a word character, so plain emails in the text
+// aren't mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.+-]+@[\w-]+(?:\.[\w-]+)+|[\w-]+)`)
+
+// Mention is a user, by email, or a team, by slug, mentioned in a comment
+type Mention struct {
+	Type       AssigneeType
+	Identifier string
+}
+
+// ParseMentions returns the distinct mentions of a comment in order of
+// appearance
+func ParseMentions(text string) []Mention {
+	var mentions []Mention
+	seen := make(map[Mention]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		mention := Mention{Type: AssigneeTeam, Identifier: match[1]}
+		if strings.Contains(match[1], "@") {
+			mention = Mention{Type: AssigneeUser, Identifier: strings.ToLower(match[1])}
+		}
+		if !seen[mention] {
+			seen[mention] = true
+			mentions = append(mentions, mention)
+		}
+	}
+	return mentions
+}
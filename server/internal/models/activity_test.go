@@ -0,0 +1,23 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	text := "Thanks @Ana@Example.com, handing over to @payments.\n" +
+		"Mail ops@example.com, cc @payments and `@ana@example.com`"
+
+	expected := []Mention{
+		{Type: AssigneeUser, Identifier: "ana@example.com"},
+		{Type: AssigneeTeam, Identifier: "payments"},
+	}
+	if mentions := ParseMentions(text); !reflect.DeepEqual(mentions, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, mentions)
+	}
+
+	if mentions := ParseMentions("no mentions, just a@b.co"); mentions != nil {
+		t.Errorf("Expected no mentions, got %+v", mentions)
+	}
+}
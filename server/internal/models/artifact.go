@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReleaseArtifact is a file uploaded for a release, such as a source map or
+// the minified file it belongs to. Name is the URL the file is served from,
+// either absolute or with the host replaced by "~", e.g. ~/static/app.js.
+type ReleaseArtifact struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	ProjectID   uuid.UUID `json:"project_id" db:"project_id"`
+	Release     string    `json:"release" db:"release"`
+	Name        string    `json:"name" db:"name"`
+	StorageKey  string    `json:"-" db:"storage_key"`
+	Size        int64     `json:"size" db:"size"`
+	Checksum    string    `json:"checksum" db:"checksum"` // SHA-1 of the content
+	ContentType *string   `json:"content_type" db:"content_type"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// AssigneeType is the kind of owner an issue is assigned to
+type AssigneeType string
+
+const (
+	AssigneeUser AssigneeType = "user"
+	AssigneeTeam AssigneeType = "team"
+)
+
+// Special assignee values
+const (
+	// AssigneeMe is the user the API key of the request acts for
+	AssigneeMe = "me"
+
+	// AssigneeNone filters issues without an assignee
+	AssigneeNone = "unassigned"
+)
+
+// ErrNoUser is returned when "me" is used with an API key that doesn't act
+// for a user
+var ErrNoUser = errors.New(`the API key isn't linked to a user, so "me" can't be used`)
+
+// Assignee is the user or team an issue is assigned to. The zero value stands
+// for no assignee.
+type Assignee struct {
+	Type AssigneeType `json:"type"`
+	ID   uuid.UUID    `json:"id"`
+}
+
+// String returns the assignee as "user:<id>" or "team:<id>", the format
+// ParseAssignee accepts
+func (a Assignee) String() string {
+	if a.Type == "" {
+		return ""
+	}
+	return string(a.Type) + ":" + a.ID.String()
+}
+
+// ParseAssignee parses "user:<id>", "team:<id>" or "me", which stands for
+// the user me. me is nil for API keys that don't act for a user.
+func ParseAssignee(value string, me *uuid.UUID) (*Assignee, error) {
+	if value == AssigneeMe {
+		if me == nil {
+			return nil, ErrNoUser
+		}
+		return &Assignee{Type: AssigneeUser, ID: *me}, nil
+	}
+
+	kind, id, found := strings.Cut(value, ":")
+	if !found || (AssigneeType(kind) != AssigneeUser && AssigneeType(kind) != AssigneeTeam) {
+		return nil, fmt.Errorf(`invalid assignee %q, expected "user:<id>", "team:<id>" or "me"`, value)
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid assignee ID %q", id)
+	}
+
+	return &Assignee{Type: AssigneeType(kind), ID: parsed}, nil
+}
+
+// ParseAssigneeFilter parses the assigned_to filter of issue queries. It
+// accepts the values of ParseAssignee and "unassigned", which returns the
+// zero Assignee.
+func ParseAssigneeFilter(value string, me *uuid.UUID) (*Assignee, error) {
+	if value == AssigneeNone {
+		return &Assignee{}, nil
+	}
+	return ParseAssignee(value, me)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseAssignee(t *testing.T) {
+	me := uuid.New()
+	team := uuid.New()
+
+	assignee, err := ParseAssignee("team:"+team.String(), &me)
+	if err != nil || assignee.Type != AssigneeTeam || assignee.ID != team {
+		t.Errorf("Expected team %s, got %+v (%v)", team, assignee, err)
+	}
+	if assignee.String() != "team:"+team.String() {
+		t.Errorf("Expected the parsed format back, got %s", assignee.String())
+	}
+
+	assignee, err = ParseAssignee(AssigneeMe, &me)
+	if err != nil || assignee.Type != AssigneeUser || assignee.ID != me {
+		t.Errorf("Expected user %s, got %+v (%v)", me, assignee, err)
+	}
+
+	if _, err := ParseAssignee(AssigneeMe, nil); !errors.Is(err, ErrNoUser) {
+		t.Errorf("Expected ErrNoUser without a user, got %v", err)
+	}
+	for _, value := range []string{"", "user", "group:" + team.String(), "user:nobody", AssigneeNone} {
+		if _, err := ParseAssignee(value, &me); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestParseAssigneeFilter_Unassigned(t *testing.T) {
+	assignee, err := ParseAssigneeFilter(AssigneeNone, nil)
+	if err != nil || *assignee != (Assignee{}) {
+		t.Errorf("Expected the zero assignee, got %+v (%v)", assignee, err)
+	}
+}
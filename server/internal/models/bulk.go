@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bulk operation limits
+const (
+	// MaxBulkIssues is the most issues a bulk operation can affect
+	MaxBulkIssues = 10000
+
+	// BulkChunkSize is the number of issues changed at once, after which the
+	// progress of the job is saved
+	BulkChunkSize = 100
+)
+
+// BulkOperation is the change a bulk job applies to issues
+type BulkOperation string
+
+const (
+	BulkStatus BulkOperation = "status"
+	BulkAssign BulkOperation = "assign"
+	BulkMerge  BulkOperation = "merge"
+	BulkDelete BulkOperation = "delete"
+)
+
+// BulkJobStatus is the state of a bulk job
+type BulkJobStatus string
+
+const (
+	BulkJobRunning   BulkJobStatus = "running"
+	BulkJobCompleted BulkJobStatus = "completed"
+	BulkJobFailed    BulkJobStatus = "failed"
+)
+
+// BulkJob is a bulk issue operation running in the background. Processed
+// counts the issues changed so far out of Total.
+type BulkJob struct {
+	ID         uuid.UUID     `json:"id" db:"id"`
+	ProjectID  uuid.UUID     `json:"project_id" db:"project_id"`
+	APIKeyID   *uuid.UUID    `json:"api_key_id" db:"api_key_id"`
+	Operation  BulkOperation `json:"operation" db:"operation"`
+	Status     BulkJobStatus `json:"status" db:"status"`
+	Total      int           `json:"total" db:"total"`
+	Processed  int           `json:"processed" db:"processed"`
+	Error      *string       `json:"error,omitempty" db:"error"`
+	CreatedAt  time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at" db:"updated_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty" db:"finished_at"`
+}
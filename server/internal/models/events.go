@@ -25,6 +25,41 @@ const (
 	StatusIgnored    IssueStatus = "ignored"
 )
 
+// IssueSubstatus qualifies the status of an issue
+type IssueSubstatus string
+
+const (
+	// SubstatusRegressed marks unresolved issues that reopened because
+	// events arrived after they were resolved
+	SubstatusRegressed IssueSubstatus = "regressed"
+
+	// SubstatusEscalating marks unresolved issues that reopened because a
+	// condition they were ignored until tripped
+	SubstatusEscalating IssueSubstatus = "escalating"
+)
+
+// IssueResolution qualifies how a resolved issue was resolved
+type IssueResolution string
+
+const (
+	// ResolutionInRelease resolves an issue in a release. Events from older
+	// releases keep the issue resolved.
+	ResolutionInRelease IssueResolution = "in_release"
+
+	// ResolutionInNextRelease resolves an issue in the release after the
+	// latest one at the time. The latest release is stored as
+	// ResolvedInRelease and only events from newer releases reopen the issue.
+	ResolutionInNextRelease IssueResolution = "in_next_release"
+)
+
+// StatusUpdate is a change of the status of an issue
+type StatusUpdate struct {
+	Status            IssueStatus
+	Resolution        IssueResolution // Resolved issues only, empty for a plain resolve
+	ResolvedInRelease string
+	Ignore            *IgnoreConditions // Ignored issues only, nil to ignore for good
+}
+
 // ErrorEvent represents a single error event from the client
 type ErrorEvent struct {
 	ID             string                 `json:"id"`
@@ -42,6 +77,7 @@ type ErrorEvent struct {
 	URL            *string                `json:"url,omitempty"`
 	Tags           map[string]string      `json:"tags"`
 	Extra          map[string]interface{} `json:"extra"`
+	Exceptions     []Exception            `json:"exceptions,omitempty"`
 	Fingerprint    string                 `json:"fingerprint"`
 	Level          ErrorLevel             `json:"level"`
 	CreatedAt      time.Time              `json:"created_at"`
@@ -49,19 +85,39 @@ type ErrorEvent struct {
 
 // Issue represents an aggregated issue
 type Issue struct {
-	ID           string            `json:"id"`
-	ProjectID    uuid.UUID         `json:"project_id"`
-	Fingerprint  string            `json:"fingerprint"`
-	Message      string            `json:"message"`
-	Level        ErrorLevel        `json:"level"`
-	Status       IssueStatus       `json:"status"`
-	FirstSeen    time.Time         `json:"first_seen"`
-	LastSeen     time.Time         `json:"last_seen"`
-	EventCount   uint64            `json:"event_count"`
-	UserCount    uint64            `json:"user_count"`
-	Environments []string          `json:"environments"`
-	Tags         map[string]string `json:"tags"`
-	UpdatedAt    time.Time         `json:"updated_at"`
+	ID              string            `json:"id"`
+	ProjectID       uuid.UUID         `json:"project_id"`
+	Fingerprint     string            `json:"fingerprint"`
+	Message         string            `json:"message"`
+	Level           ErrorLevel        `json:"level"`
+	Status          IssueStatus       `json:"status"`
+	Substatus       IssueSubstatus    `json:"substatus,omitempty"`
+	Resolution      IssueResolution   `json:"resolution,omitempty"`
+	FirstSeen       time.Time         `json:"first_seen"`
+	LastSeen        time.Time         `json:"last_seen"`
+	EventCount      uint64            `json:"event_count"`
+	UserCount       uint64            `json:"user_count"`
+	Environments    []string          `json:"environments"`
+	Tags            map[string]string `json:"tags"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	GroupingVersion uint8             `json:"grouping_version"` // Version of the algorithm that grouped the issue
+
+	ResolvedInRelease  string     `json:"resolved_in_release,omitempty"`
+	ResolvedAt         *time.Time `json:"resolved_at,omitempty"`
+	RegressedAt        *time.Time `json:"regressed_at,omitempty"`
+	RegressedInRelease string     `json:"regressed_in_release,omitempty"`
+
+	Ignore *IgnoreConditions `json:"ignore,omitempty"` // Conditions an ignored issue reopens on
+
+	MergedFingerprints []string `json:"merged_fingerprints,omitempty"` // Fingerprints of issues merged into this one
+
+	Assignee *Assignee `json:"assignee"` // User or team that owns the issue, null when unassigned
+}
+
+// Fingerprints returns the fingerprint of the issue and the fingerprints
+// merged into it, whose events all belong to the issue
+func (i *Issue) Fingerprints() []string {
+	return append([]string{i.Fingerprint}, i.MergedFingerprints...)
 }
 
 // IngestRequest represents the request payload for event ingestion
@@ -69,8 +125,26 @@ type IngestRequest struct {
 	Events []IngestEvent `json:"events" binding:"required,min=1,max=100"`
 }
 
+// EventStatus is the outcome of ingesting a single event
+type EventStatus string
+
+const (
+	EventAccepted  EventStatus = "accepted"
+	EventTruncated EventStatus = "truncated"
+	EventRejected  EventStatus = "rejected"
+)
+
+// EventResult describes the outcome for one event of an ingest request
+type EventResult struct {
+	Index     int         `json:"index"`
+	Status    EventStatus `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	Truncated []string    `json:"truncated,omitempty"` // Fields shortened to fit the limits
+}
+
 // IngestEvent represents a single event in the ingestion request
 type IngestEvent struct {
+	EventID        *string                `json:"event_id,omitempty"` // Client supplied ID used to deduplicate retries
 	Message        string                 `json:"message" binding:"required"`
 	StackTrace     *string                `json:"stack_trace,omitempty"`
 	Environment    string                 `json:"environment" binding:"required"`
@@ -83,6 +157,8 @@ type IngestEvent struct {
 	URL            *string                `json:"url,omitempty"`
 	Tags           map[string]string      `json:"tags"`
 	Extra          map[string]interface{} `json:"extra"`
+	Exceptions     []Exception            `json:"exceptions,omitempty"`  // Raised exception first, followed by its causes
+	Fingerprint    []string               `json:"fingerprint,omitempty"` // Overrides the default grouping; "{{ default }}" expands to it
 	Level          ErrorLevel             `json:"level"`
 	Timestamp      *FlexibleTime          `json:"timestamp,omitempty"`
 }
@@ -98,29 +174,34 @@ type ProjectStats struct {
 	LastEvent        *time.Time `json:"last_event"`
 }
 
-// IssuesQuery represents query parameters for fetching issues
+// IssuesQuery represents query parameters for fetching issues. Bulk
+// operations take its filters as JSON.
 type IssuesQuery struct {
-	ProjectID   *uuid.UUID   `form:"project_id"`
-	Status      *IssueStatus `form:"status"`
-	Environment *string      `form:"environment"`
-	Level       *ErrorLevel  `form:"level"`
-	Search      *string      `form:"search"`
-	TimeRange   *string      `form:"time_range"`
-	Page        int          `form:"page,default=1"`
-	Limit       int          `form:"limit,default=50"`
-	SortBy      string       `form:"sort_by,default=last_seen"`
-	SortOrder   string       `form:"sort_order,default=desc"`
+	ProjectID   *uuid.UUID      `form:"project_id" json:"project_id"`
+	Status      *IssueStatus    `form:"status" json:"status"`
+	Substatus   *IssueSubstatus `form:"substatus" json:"substatus"`
+	Environment *string         `form:"environment" json:"environment"`
+	Level       *ErrorLevel     `form:"level" json:"level"`
+	Search      *string         `form:"search" json:"search"`
+	AssignedTo  *string         `form:"assigned_to" json:"assigned_to"` // "user:<id>", "team:<id>", "me" or "unassigned"
+	TimeWindowParams
+	Window    *TimeWindow `form:"-" json:"-"` // Filters last_seen, parsed from TimeWindowParams
+	Assignee  *Assignee   `form:"-" json:"-"` // Parsed from AssignedTo; the zero value selects unassigned issues
+	Page      int         `form:"page,default=1" json:"-"`
+	Limit     int         `form:"limit,default=50" json:"-"`
+	SortBy    string      `form:"sort_by,default=last_seen" json:"-"`
+	SortOrder string      `form:"sort_order,default=desc" json:"-"`
 }
 
 // EventsQuery represents query parameters for fetching events
 type EventsQuery struct {
-	IssueID     *string    `form:"issue_id"`
-	ProjectID   *uuid.UUID `form:"project_id"`
-	Environment *string    `form:"environment"`
-	UserID      *string    `form:"user_id"`
-	TimeRange   *string    `form:"time_range"`
-	Page        int        `form:"page,default=1"`
-	Limit       int        `form:"limit,default=100"`
+	IssueID     *string     `form:"issue_id"`
+	ProjectID   *uuid.UUID  `form:"project_id"`
+	Environment *string     `form:"environment"`
+	UserID      *string     `form:"user_id"`
+	Window      *TimeWindow `form:"-"`
+	Page        int         `form:"page,default=1"`
+	Limit       int         `form:"limit,default=100"`
 }
 
 // PaginatedResponse represents a paginated response
@@ -0,0 +1,58 @@
+package models
+
+// Exception is a structured exception. Chained exceptions are stored with the
+// exception that was raised first, followed by its causes.
+type Exception struct {
+	Type       string      `json:"type,omitempty"`
+	Value      string      `json:"value,omitempty"`
+	Module     string      `json:"module,omitempty"`
+	Mechanism  *Mechanism  `json:"mechanism,omitempty"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Mechanism describes how an exception was captured
+type Mechanism struct {
+	Type        string `json:"type"`
+	Handled     *bool  `json:"handled,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Stacktrace holds frames ordered from the oldest call to the frame that
+// raised the exception
+type Stacktrace struct {
+	Frames []StackFrame `json:"frames"`
+}
+
+// StackFrame is a single stack frame
+type StackFrame struct {
+	Filename    string   `json:"filename,omitempty"`
+	AbsPath     string   `json:"abs_path,omitempty"`
+	Function    string   `json:"function,omitempty"`
+	Module      string   `json:"module,omitempty"`
+	Lineno      int      `json:"lineno,omitempty"`
+	Colno       int      `json:"colno,omitempty"`
+	InApp       *bool    `json:"in_app,omitempty"`
+	ContextLine string   `json:"context_line,omitempty"`
+	PreContext  []string `json:"pre_context,omitempty"`
+	PostContext []string `json:"post_context,omitempty"`
+}
+
+// Title returns the "Type: value" summary of the exception
+func (e Exception) Title() string {
+	switch {
+	case e.Type != "" && e.Value != "":
+		return e.Type + ": " + e.Value
+	case e.Type != "":
+		return e.Type
+	default:
+		return e.Value
+	}
+}
+
+// Frames returns the frames of the exception, if any
+func (e Exception) Frames() []StackFrame {
+	if e.Stacktrace == nil {
+		return nil
+	}
+	return e.Stacktrace.Frames
+}
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Ignore condition limits
+const (
+	// MaxIgnoreDuration is the longest an issue can be ignored for
+	MaxIgnoreDuration = 365 * 24 * time.Hour
+
+	// DefaultIgnoreRateWindow is the window event rates are measured over
+	// when none is given
+	DefaultIgnoreRateWindow = time.Hour
+
+	// MaxIgnoreRateWindow is the longest window event rates can be measured
+	// over
+	MaxIgnoreRateWindow = 7 * 24 * time.Hour
+)
+
+// IgnoreCondition names the condition that reopened an ignored issue
+type IgnoreCondition string
+
+const (
+	IgnoreConditionUntil      IgnoreCondition = "until"
+	IgnoreConditionEventCount IgnoreCondition = "event_count"
+	IgnoreConditionUserCount  IgnoreCondition = "user_count"
+	IgnoreConditionEventRate  IgnoreCondition = "event_rate"
+)
+
+// IgnoreConditions reopen an ignored issue when any of them trips. The counts
+// are totals of the issue, computed from its counts when it was ignored, so
+// "100 more events" on an issue with 400 events is stored as 500. Zero values
+// are unset conditions.
+type IgnoreConditions struct {
+	Until           *time.Time `json:"until,omitempty"`
+	UntilEventCount uint64     `json:"until_event_count,omitempty"`
+	UntilUserCount  uint64     `json:"until_user_count,omitempty"`
+	EventRate       uint64     `json:"event_rate,omitempty"`  // Events per RateWindow
+	RateWindow      uint32     `json:"rate_window,omitempty"` // Seconds
+}
+
+// Empty reports whether no condition is set, which ignores the issue for good
+func (c *IgnoreConditions) Empty() bool {
+	return c == nil || (c.Until == nil && c.UntilEventCount == 0 && c.UntilUserCount == 0 && c.EventRate == 0)
+}
+
+// Tripped returns the condition, other than the event rate, that an issue
+// has met, or "" when there is none. The event rate needs the recent events
+// of the issue and is checked with RateExceeded.
+func (c *IgnoreConditions) Tripped(issue *Issue, now time.Time) IgnoreCondition {
+	switch {
+	case c.Empty():
+		return ""
+	case c.Until != nil && !now.Before(*c.Until):
+		return IgnoreConditionUntil
+	case c.UntilEventCount > 0 && issue.EventCount >= c.UntilEventCount:
+		return IgnoreConditionEventCount
+	case c.UntilUserCount > 0 && issue.UserCount >= c.UntilUserCount:
+		return IgnoreConditionUserCount
+	default:
+		return ""
+	}
+}
+
+// RateExceeded reports whether the events of an issue over the last
+// RateWindow exceed the event rate
+func (c *IgnoreConditions) RateExceeded(recentEvents uint64) bool {
+	return c != nil && c.EventRate > 0 && recentEvents > c.EventRate
+}
+
+// RateWindowDuration returns the window the event rate is measured over
+func (c *IgnoreConditions) RateWindowDuration() time.Duration {
+	if c.RateWindow == 0 {
+		return DefaultIgnoreRateWindow
+	}
+	return time.Duration(c.RateWindow) * time.Second
+}
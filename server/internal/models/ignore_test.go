@@ -0,0 +1,49 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIgnoreConditions_Tripped(t *testing.T) {
+	past, future := testNow.Add(-time.Minute), testNow.Add(time.Minute)
+	issue := &Issue{EventCount: 500, UserCount: 20}
+
+	tests := []struct {
+		name       string
+		conditions *IgnoreConditions
+		expected   IgnoreCondition
+	}{
+		{"none", nil, ""},
+		{"until passed", &IgnoreConditions{Until: &past}, IgnoreConditionUntil},
+		{"until ahead", &IgnoreConditions{Until: &future}, ""},
+		{"event count reached", &IgnoreConditions{UntilEventCount: 500}, IgnoreConditionEventCount},
+		{"event count ahead", &IgnoreConditions{UntilEventCount: 501}, ""},
+		{"user count reached", &IgnoreConditions{Until: &future, UntilUserCount: 20}, IgnoreConditionUserCount},
+		{"rate only", &IgnoreConditions{EventRate: 10}, ""},
+	}
+
+	for _, tt := range tests {
+		if result := tt.conditions.Tripped(issue, testNow); result != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, result)
+		}
+	}
+}
+
+func TestIgnoreConditions_RateExceeded(t *testing.T) {
+	conditions := &IgnoreConditions{EventRate: 100}
+	if conditions.RateExceeded(100) {
+		t.Error("Expected a rate equal to the threshold not to trip")
+	}
+	if !conditions.RateExceeded(101) {
+		t.Error("Expected a rate above the threshold to trip")
+	}
+	if conditions.RateWindowDuration() != DefaultIgnoreRateWindow {
+		t.Errorf("Expected the default window, got %v", conditions.RateWindowDuration())
+	}
+
+	var none *IgnoreConditions
+	if none.RateExceeded(1000) || !none.Empty() {
+		t.Error("Expected nil conditions to be empty and never trip")
+	}
+}
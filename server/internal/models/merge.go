@@ -0,0 +1,30 @@
+package models
+
+// MaxMergeIssues is the most issues that can be merged at once
+const MaxMergeIssues = 100
+
+// MergedStatus returns the status of issues merged into one: their status
+// when they all share it, and unresolved otherwise so that nothing one of the
+// issues still needed attention for is hidden
+func MergedStatus(issues []*Issue) IssueStatus {
+	if len(issues) == 0 {
+		return StatusUnresolved
+	}
+	status := issues[0].Status
+	for _, issue := range issues[1:] {
+		if issue.Status != status {
+			return StatusUnresolved
+		}
+	}
+	return status
+}
+
+// HasMergedFingerprint reports whether a fingerprint was merged into the issue
+func (i *Issue) HasMergedFingerprint(fingerprint string) bool {
+	for _, merged := range i.MergedFingerprints {
+		if merged == fingerprint {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestMergedStatus(t *testing.T) {
+	resolved := &Issue{Status: StatusResolved}
+	ignored := &Issue{Status: StatusIgnored}
+	unresolved := &Issue{Status: StatusUnresolved}
+
+	tests := []struct {
+		name     string
+		issues   []*Issue
+		expected IssueStatus
+	}{
+		{"none", nil, StatusUnresolved},
+		{"all resolved", []*Issue{resolved, resolved}, StatusResolved},
+		{"all ignored", []*Issue{ignored, ignored}, StatusIgnored},
+		{"mixed", []*Issue{resolved, ignored}, StatusUnresolved},
+		{"one unresolved", []*Issue{resolved, unresolved}, StatusUnresolved},
+	}
+
+	for _, tt := range tests {
+		if result := MergedStatus(tt.issues); result != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, result)
+		}
+	}
+}
+
+func TestIssue_Fingerprints(t *testing.T) {
+	issue := &Issue{Fingerprint: "a", MergedFingerprints: []string{"b", "c"}}
+
+	fingerprints := issue.Fingerprints()
+	if len(fingerprints) != 3 || fingerprints[0] != "a" {
+		t.Errorf("Expected the own fingerprint first, got %v", fingerprints)
+	}
+	if !issue.HasMergedFingerprint("b") || issue.HasMergedFingerprint("a") {
+		t.Error("Expected only merged fingerprints to count as merged")
+	}
+}
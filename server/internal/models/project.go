@@ -27,6 +27,7 @@ type APIKey struct {
 	KeyHash    string     `json:"-" db:"key_hash"` // Never expose hash in JSON
 	KeyPrefix  string     `json:"key_prefix" db:"key_prefix"`
 	ProjectID  uuid.UUID  `json:"project_id" db:"project_id"`
+	UserID     *uuid.UUID `json:"user_id" db:"user_id"` // User the key acts for, if any
 	Scopes     []string   `json:"scopes" db:"scopes"`
 	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
 	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
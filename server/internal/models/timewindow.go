@@ -0,0 +1,217 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Time window limits
+const (
+	DefaultTimeRange = "24h"
+
+	// MaxTimeWindow is the longest window that can be queried, the retention
+	// of the daily rollups
+	MaxTimeWindow = 400 * 24 * time.Hour
+
+	// MaxTimeSeriesPoints is the most buckets a time series may have
+	MaxTimeSeriesPoints = 2000
+
+	// defaultTimeSeriesPoints is the most buckets of the interval chosen when
+	// none is given
+	defaultTimeSeriesPoints = 200
+)
+
+// TimeRanges are the accepted time_range presets, ending now
+var TimeRanges = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// Intervals are the accepted time series bucket sizes. They all divide a day
+// so buckets start at the same times every day.
+var Intervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"10m": 10 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"3h":  3 * time.Hour,
+	"6h":  6 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// timeZonePattern matches IANA time zone names, which are also inlined into
+// queries
+var timeZonePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_+\-]*(/[A-Za-z0-9_+\-]+)*$`)
+
+// TimeWindowParams are the query parameters selecting the time window of
+// analytics endpoints
+type TimeWindowParams struct {
+	TimeRange string `form:"time_range" json:"time_range"` // Preset ending now, ignored when start is set
+	Start     string `form:"start" json:"start"`           // RFC3339
+	End       string `form:"end" json:"end"`               // RFC3339, defaults to now
+	Interval  string `form:"interval" json:"interval"`     // Time series bucket size, one of Intervals
+	TZ        string `form:"tz" json:"tz"`                 // IANA time zone of the buckets, defaults to UTC
+}
+
+// TimeWindow is a validated time range with the bucketing of its time series
+type TimeWindow struct {
+	Start    time.Time
+	End      time.Time
+	Interval time.Duration
+	Location *time.Location
+	Preset   string // The time_range preset the window was built from, if any
+}
+
+// Parse validates the parameters and returns the time window they select
+func (p TimeWindowParams) Parse(now time.Time) (*TimeWindow, error) {
+	window := &TimeWindow{End: now, Location: time.UTC}
+
+	if p.TZ != "" && p.TZ != "UTC" {
+		if !timeZonePattern.MatchString(p.TZ) || p.TZ == "Local" {
+			return nil, fmt.Errorf("invalid time zone %q", p.TZ)
+		}
+		location, err := time.LoadLocation(p.TZ)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone %q", p.TZ)
+		}
+		window.Location = location
+	}
+
+	switch {
+	case p.Start != "":
+		start, err := time.Parse(time.RFC3339, p.Start)
+		if err != nil {
+			return nil, fmt.Errorf("start must be an RFC3339 time: %w", err)
+		}
+		window.Start = start
+		if p.End != "" {
+			end, err := time.Parse(time.RFC3339, p.End)
+			if err != nil {
+				return nil, fmt.Errorf("end must be an RFC3339 time: %w", err)
+			}
+			window.End = end
+		}
+	case p.End != "":
+		return nil, fmt.Errorf("end requires start")
+	default:
+		timeRange := p.TimeRange
+		if timeRange == "" {
+			timeRange = DefaultTimeRange
+		}
+		duration, ok := TimeRanges[timeRange]
+		if !ok {
+			return nil, fmt.Errorf("invalid time_range %q, expected one of %v", timeRange, sortedKeys(TimeRanges))
+		}
+		window.Start = now.Add(-duration)
+		window.Preset = timeRange
+	}
+
+	if !window.End.After(window.Start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+	if window.End.Sub(window.Start) > MaxTimeWindow {
+		return nil, fmt.Errorf("time window must not exceed %d days", int(MaxTimeWindow/(24*time.Hour)))
+	}
+
+	if p.Interval != "" {
+		interval, ok := Intervals[p.Interval]
+		if !ok {
+			return nil, fmt.Errorf("invalid interval %q, expected one of %v", p.Interval, sortedKeys(Intervals))
+		}
+		window.Interval = interval
+		if points := window.points(); points > MaxTimeSeriesPoints {
+			return nil, fmt.Errorf("interval %s gives %d points, at most %d are allowed", p.Interval, points, MaxTimeSeriesPoints)
+		}
+	} else {
+		window.Interval = window.defaultInterval()
+	}
+
+	return window, nil
+}
+
+// Truncate rounds a time down to the start of its bucket in the window's time
+// zone. Minute buckets are aligned to UTC and hour and day buckets to local
+// midnight, like ClickHouse toStartOfInterval.
+func (w *TimeWindow) Truncate(t time.Time) time.Time {
+	t = t.In(w.Location)
+	if w.Interval < time.Hour {
+		return t.Truncate(w.Interval)
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.Location)
+	if w.Interval >= 24*time.Hour {
+		return midnight
+	}
+	return midnight.Add(t.Sub(midnight).Truncate(w.Interval))
+}
+
+// Buckets returns the start of every bucket of the window's time series
+func (w *TimeWindow) Buckets() []time.Time {
+	var buckets []time.Time
+	for bucket := w.Truncate(w.Start); bucket.Before(w.End); {
+		buckets = append(buckets, bucket)
+
+		var next time.Time
+		if w.Interval >= 24*time.Hour {
+			next = time.Date(bucket.Year(), bucket.Month(), bucket.Day()+1, 0, 0, 0, 0, w.Location)
+		} else {
+			next = w.Truncate(bucket.Add(w.Interval))
+		}
+		if !next.After(bucket) {
+			// Daylight saving time changes can round back to the same bucket
+			next = bucket.Add(w.Interval)
+		}
+		bucket = next
+	}
+	return buckets
+}
+
+// IntervalName returns the name of the window's interval in Intervals
+func (w *TimeWindow) IntervalName() string {
+	for name, interval := range Intervals {
+		if interval == w.Interval {
+			return name
+		}
+	}
+	return w.Interval.String()
+}
+
+// points returns the approximate number of buckets of the window
+func (w *TimeWindow) points() int {
+	return int((w.End.Sub(w.Start) + w.Interval - 1) / w.Interval)
+}
+
+// defaultInterval returns the smallest interval that keeps the time series
+// within defaultTimeSeriesPoints
+func (w *TimeWindow) defaultInterval() time.Duration {
+	intervals := make([]time.Duration, 0, len(Intervals))
+	for _, interval := range Intervals {
+		intervals = append(intervals, interval)
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+
+	for _, interval := range intervals {
+		if w.End.Sub(w.Start) <= interval*defaultTimeSeriesPoints {
+			return interval
+		}
+	}
+	return intervals[len(intervals)-1]
+}
+
+// sortedKeys returns the keys of a map of durations ordered by duration
+func sortedKeys(values map[string]time.Duration) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return values[keys[i]] < values[keys[j]] })
+	return keys
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 16, 12, 34, 56, 0, time.UTC)
+
+func TestTimeWindowParams_Parse(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   TimeWindowParams
+		start    time.Time
+		end      time.Time
+		interval time.Duration
+	}{
+		{"default", TimeWindowParams{}, testNow.Add(-24 * time.Hour), testNow, 10 * time.Minute},
+		{"preset", TimeWindowParams{TimeRange: "7d"}, testNow.Add(-7 * 24 * time.Hour), testNow, time.Hour},
+		{
+			"explicit",
+			TimeWindowParams{Start: "2026-10-01T00:00:00Z", End: "2026-10-03T00:00:00+02:00", Interval: "1d"},
+			time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 2, 22, 0, 0, 0, time.UTC),
+			24 * time.Hour,
+		},
+		{"start without end", TimeWindowParams{Start: "2026-10-16T12:00:00Z", TimeRange: "30d"}, time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), testNow, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := tt.params.Parse(testNow)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !window.Start.Equal(tt.start) || !window.End.Equal(tt.end) {
+				t.Errorf("Expected %v to %v, got %v to %v", tt.start, tt.end, window.Start, window.End)
+			}
+			if window.Interval != tt.interval {
+				t.Errorf("Expected interval %v, got %v", tt.interval, window.Interval)
+			}
+		})
+	}
+}
+
+func TestTimeWindowParams_ParseRejectsInvalidWindows(t *testing.T) {
+	tests := map[string]TimeWindowParams{
+		"unknown preset":   {TimeRange: "2w"},
+		"bad start":        {Start: "yesterday"},
+		"end before start": {Start: "2026-10-16T00:00:00Z", End: "2026-10-15T00:00:00Z"},
+		"end only":         {End: "2026-10-16T00:00:00Z"},
+		"too long":         {Start: "2024-01-01T00:00:00Z"},
+		"unknown interval": {Interval: "7m"},
+		"too many points":  {TimeRange: "30d", Interval: "1m"},
+		"unknown zone":     {TZ: "Mars/Olympus_Mons"},
+		"local zone":       {TZ: "Local"},
+		"quoted zone":      {TZ: "UTC'"},
+	}
+
+	for name, params := range tests {
+		if _, err := params.Parse(testNow); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestTimeWindow_Buckets(t *testing.T) {
+	window, err := TimeWindowParams{
+		Start:    "2026-10-15T22:30:00Z",
+		End:      "2026-10-16T03:00:00Z",
+		Interval: "2h",
+		TZ:       "Europe/Berlin",
+	}.Parse(testNow)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Berlin is UTC+2, so the buckets start at even local hours
+	expected := []string{
+		"2026-10-15T22:00:00Z",
+		"2026-10-16T00:00:00Z",
+		"2026-10-16T02:00:00Z",
+	}
+	buckets := window.Buckets()
+	if len(buckets) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %v", len(expected), buckets)
+	}
+	for i, bucket := range buckets {
+		if got := bucket.UTC().Format(time.RFC3339); got != expected[i] {
+			t.Errorf("Bucket %d: expected %s, got %s", i, expected[i], got)
+		}
+	}
+}
+
+func TestTimeWindow_BucketsAcrossDaylightSavingTime(t *testing.T) {
+	window, err := TimeWindowParams{
+		Start:    "2026-10-24T00:00:00+02:00",
+		End:      "2026-10-27T00:00:00+01:00",
+		Interval: "1d",
+		TZ:       "Europe/Berlin",
+	}.Parse(testNow)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buckets := window.Buckets()
+	if len(buckets) != 3 {
+		t.Fatalf("Expected 3 daily buckets, got %v", buckets)
+	}
+	for _, bucket := range buckets {
+		if bucket.Hour() != 0 {
+			t.Errorf("Expected buckets at local midnight, got %v", bucket)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// Package otlp converts OpenTelemetry traces and logs received over OTLP/HTTP
+// into Errly events. Only exception span events and error logs are kept.
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/models"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types accepted by the OTLP/HTTP receiver
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJSON     = "application/json"
+)
+
+// Semantic convention attribute keys
+const (
+	attrExceptionType       = "exception.type"
+	attrExceptionMessage    = "exception.message"
+	attrExceptionStacktrace = "exception.stacktrace"
+	attrServiceName         = "service.name"
+	attrServiceVersion      = "service.version"
+	attrDeploymentEnv       = "deployment.environment"
+	attrDeploymentEnvName   = "deployment.environment.name"
+	attrEndUserID           = "enduser.id"
+	attrURLFull             = "url.full"
+	attrHTTPURL             = "http.url"
+	attrClientAddress       = "client.address"
+	attrUserAgent           = "user_agent.original"
+)
+
+// exceptionEventName is the span event name used to record exceptions
+const exceptionEventName = "exception"
+
+// defaultEnvironment is used when the resource has no deployment environment
+const defaultEnvironment = "production"
+
+// Decoder decodes OTLP request bodies. The OTLP JSON encoding represents
+// trace and span IDs as hex strings rather than the base64 protojson expects,
+// so the decoder also knows how to render IDs back to their hex form.
+type Decoder struct {
+	json bool
+}
+
+// NewDecoder returns a decoder for the given Content-Type header
+func NewDecoder(contentType string) (*Decoder, error) {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	switch mediaType {
+	case ContentTypeProtobuf:
+		return &Decoder{}, nil
+	case ContentTypeJSON:
+		return &Decoder{json: true}, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type: %s", contentType)
+	}
+}
+
+// IsJSON reports whether the decoder handles the JSON encoding
+func (d *Decoder) IsJSON() bool {
+	return d.json
+}
+
+// DecodeTraces decodes an ExportTraceServiceRequest. TracesData shares its
+// wire format with the export request.
+func (d *Decoder) DecodeTraces(body []byte) (*tracepb.TracesData, error) {
+	data := &tracepb.TracesData{}
+	if err := d.unmarshal(body, data); err != nil {
+		return nil, fmt.Errorf("invalid traces payload: %w", err)
+	}
+	return data, nil
+}
+
+// DecodeLogs decodes an ExportLogsServiceRequest. LogsData shares its wire
+// format with the export request.
+func (d *Decoder) DecodeLogs(body []byte) (*logspb.LogsData, error) {
+	data := &logspb.LogsData{}
+	if err := d.unmarshal(body, data); err != nil {
+		return nil, fmt.Errorf("invalid logs payload: %w", err)
+	}
+	return data, nil
+}
+
+// TraceEvents extracts one event per exception span event
+func (d *Decoder) TraceEvents(data *tracepb.TracesData) []models.IngestEvent {
+	var events []models.IngestEvent
+
+	for _, resourceSpans := range data.GetResourceSpans() {
+		resource := attributeMap(resourceSpans.GetResource().GetAttributes())
+
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			for _, span := range scopeSpans.GetSpans() {
+				for _, spanEvent := range span.GetEvents() {
+					if spanEvent.GetName() != exceptionEventName {
+						continue
+					}
+
+					attributes := attributeMap(span.GetAttributes())
+					for key, value := range attributeMap(spanEvent.GetAttributes()) {
+						attributes[key] = value
+					}
+
+					event := newEvent(resource, attributes, spanEvent.GetTimeUnixNano())
+					event.Tags["span.name"] = span.GetName()
+					if id := d.formatID(span.GetTraceId()); id != "" {
+						event.Tags["trace_id"] = id
+					}
+					if id := d.formatID(span.GetSpanId()); id != "" {
+						event.Tags["span_id"] = id
+					}
+
+					events = append(events, event)
+				}
+			}
+		}
+	}
+
+	return events
+}
+
+// LogEvents extracts one event per log record at ERROR severity or above
+func (d *Decoder) LogEvents(data *logspb.LogsData) []models.IngestEvent {
+	var events []models.IngestEvent
+
+	for _, resourceLogs := range data.GetResourceLogs() {
+		resource := attributeMap(resourceLogs.GetResource().GetAttributes())
+
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			for _, record := range scopeLogs.GetLogRecords() {
+				if !isErrorSeverity(record) {
+					continue
+				}
+
+				attributes := attributeMap(record.GetAttributes())
+				if _, ok := attributes[attrExceptionMessage]; !ok {
+					if body := valueString(record.GetBody()); body != "" {
+						attributes[attrExceptionMessage] = body
+					}
+				}
+
+				timestamp := record.GetTimeUnixNano()
+				if timestamp == 0 {
+					timestamp = record.GetObservedTimeUnixNano()
+				}
+
+				event := newEvent(resource, attributes, timestamp)
+				if scope := scopeLogs.GetScope().GetName(); scope != "" {
+					event.Tags["logger"] = scope
+				}
+				if severity := record.GetSeverityText(); severity != "" {
+					event.Tags["severity"] = severity
+				}
+				if id := d.formatID(record.GetTraceId()); id != "" {
+					event.Tags["trace_id"] = id
+				}
+				if id := d.formatID(record.GetSpanId()); id != "" {
+					event.Tags["span_id"] = id
+				}
+
+				events = append(events, event)
+			}
+		}
+	}
+
+	return events
+}
+
+// unmarshal decodes the body using the configured encoding
+func (d *Decoder) unmarshal(body []byte, message proto.Message) error {
+	if d.json {
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, message)
+	}
+	return proto.Unmarshal(body, message)
+}
+
+// formatID renders a trace or span ID as hex
+func (d *Decoder) formatID(id []byte) string {
+	if len(id) == 0 {
+		return ""
+	}
+	if d.json {
+		// protojson decoded the hex string as base64, so encoding it again
+		// restores the original representation
+		return base64.StdEncoding.EncodeToString(id)
+	}
+	return hex.EncodeToString(id)
+}
+
+// newEvent builds an ingest event from resource and record attributes
+func newEvent(resource, attributes map[string]string, timeUnixNano uint64) models.IngestEvent {
+	event := models.IngestEvent{
+		Message:     exceptionTitle(attributes),
+		Environment: firstNonEmpty(resource[attrDeploymentEnvName], resource[attrDeploymentEnv], defaultEnvironment),
+		Level:       models.LevelError,
+		Tags:        make(map[string]string),
+		Extra:       make(map[string]interface{}),
+	}
+
+	if timeUnixNano > 0 {
+		event.Timestamp = &models.FlexibleTime{Time: time.Unix(0, int64(timeUnixNano)).UTC()}
+	}
+
+	if stack := attributes[attrExceptionStacktrace]; stack != "" {
+		event.StackTrace = &stack
+	}
+	if version := resource[attrServiceVersion]; version != "" {
+		event.ReleaseVersion = &version
+	}
+	if service := resource[attrServiceName]; service != "" {
+		event.Tags["service"] = service
+	}
+	if userID := attributes[attrEndUserID]; userID != "" {
+		event.UserID = &userID
+	}
+	if url := firstNonEmpty(attributes[attrURLFull], attributes[attrHTTPURL]); url != "" {
+		event.URL = &url
+	}
+	if ip := attributes[attrClientAddress]; ip != "" {
+		event.UserIP = &ip
+	}
+	if userAgent := attributes[attrUserAgent]; userAgent != "" {
+		event.Browser = &userAgent
+	}
+
+	// Remaining attributes are kept as extra context
+	for key, value := range attributes {
+		switch key {
+		case attrExceptionType, attrExceptionMessage, attrExceptionStacktrace:
+			continue
+		}
+		event.Extra[key] = value
+	}
+
+	return event
+}
+
+// exceptionTitle renders "type: message" from the exception attributes
+func exceptionTitle(attributes map[string]string) string {
+	exceptionType := attributes[attrExceptionType]
+	message := attributes[attrExceptionMessage]
+
+	switch {
+	case exceptionType != "" && message != "":
+		return exceptionType + ": " + message
+	case exceptionType != "":
+		return exceptionType
+	case message != "":
+		return message
+	default:
+		return "<unlabeled exception>"
+	}
+}
+
+// isErrorSeverity reports whether a log record is at ERROR severity or above.
+// Records without a severity number fall back to the severity text.
+func isErrorSeverity(record *logspb.LogRecord) bool {
+	if record.GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+		return record.GetSeverityNumber() >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	}
+
+	switch strings.ToUpper(record.GetSeverityText()) {
+	case "ERROR", "FATAL", "CRITICAL", "ALERT", "EMERGENCY":
+		return true
+	default:
+		return false
+	}
+}
+
+// attributeMap flattens OTLP attributes into strings
+func attributeMap(attributes []*commonpb.KeyValue) map[string]string {
+	result := make(map[string]string, len(attributes))
+	for _, attribute := range attributes {
+		result[attribute.GetKey()] = valueString(attribute.GetValue())
+	}
+	return result
+}
+
+// valueString renders an AnyValue as a string
+func valueString(value *commonpb.AnyValue) string {
+	if value == nil {
+		return ""
+	}
+
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'f', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		parts := make([]string, 0, len(v.ArrayValue.GetValues()))
+		for _, item := range v.ArrayValue.GetValues() {
+			parts = append(parts, valueString(item))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case *commonpb.AnyValue_KvlistValue:
+		parts := make([]string, 0, len(v.KvlistValue.GetValues()))
+		for _, item := range v.KvlistValue.GetValues() {
+			parts = append(parts, item.GetKey()+"="+valueString(item.GetValue()))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	default:
+		return ""
+	}
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
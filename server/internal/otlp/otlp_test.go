@@ -0,0 +1,148 @@
+package otlp
+
+import (
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+
+	"server/internal/models"
+)
+
+func TestDecoder_TraceEventsJSON(t *testing.T) {
+	body := `{"resourceSpans":[{
+		"resource":{"attributes":[
+			{"key":"service.name","value":{"stringValue":"checkout"}},
+			{"key":"service.version","value":{"stringValue":"2.4.0"}},
+			{"key":"deployment.environment","value":{"stringValue":"staging"}}
+		]},
+		"scopeSpans":[{"spans":[{
+			"traceId":"5b8efff798038103d269b633813fc60c",
+			"spanId":"eee19b7ec3c1b174",
+			"name":"POST /orders",
+			"events":[
+				{"name":"log","timeUnixNano":"1700000000000000000"},
+				{"name":"exception","timeUnixNano":"1700000000000000000","attributes":[
+					{"key":"exception.type","value":{"stringValue":"ValueError"}},
+					{"key":"exception.message","value":{"stringValue":"invalid quantity"}},
+					{"key":"exception.stacktrace","value":{"stringValue":"Traceback (most recent call last):"}}
+				]}
+			]
+		}]}]
+	}]}`
+
+	decoder, err := NewDecoder("application/json; charset=utf-8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := decoder.DecodeTraces([]byte(body))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	events := decoder.TraceEvents(data)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 exception event, got %d", len(events))
+	}
+
+	event := events[0]
+	if event.Message != "ValueError: invalid quantity" {
+		t.Errorf("Expected exception title, got '%s'", event.Message)
+	}
+	if event.Environment != "staging" {
+		t.Errorf("Expected environment from resource, got '%s'", event.Environment)
+	}
+	if event.ReleaseVersion == nil || *event.ReleaseVersion != "2.4.0" {
+		t.Errorf("Expected release from service.version, got %v", event.ReleaseVersion)
+	}
+	if event.StackTrace == nil || *event.StackTrace != "Traceback (most recent call last):" {
+		t.Errorf("Expected stack trace from exception.stacktrace, got %v", event.StackTrace)
+	}
+	if event.Tags["trace_id"] != "5b8efff798038103d269b633813fc60c" {
+		t.Errorf("Expected hex trace ID to round trip, got '%s'", event.Tags["trace_id"])
+	}
+	if event.Tags["span_id"] != "eee19b7ec3c1b174" {
+		t.Errorf("Expected hex span ID to round trip, got '%s'", event.Tags["span_id"])
+	}
+	if event.Timestamp == nil || event.Timestamp.Unix() != 1700000000 {
+		t.Errorf("Expected event timestamp, got %v", event.Timestamp)
+	}
+}
+
+func TestDecoder_LogEventsProtobuf(t *testing.T) {
+	data := &logspb.LogsData{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				stringAttribute("service.name", "billing"),
+			}},
+			ScopeLogs: []*logspb.ScopeLogs{{
+				LogRecords: []*logspb.LogRecord{
+					{
+						SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+						Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "started"}},
+					},
+					{
+						SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+						Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "payment declined"}},
+						TraceId:        []byte{0x01, 0x02},
+					},
+					{
+						SeverityText: "FATAL",
+						Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "out of memory"}},
+					},
+				},
+			}},
+		}},
+	}
+
+	body, err := proto.Marshal(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoder, err := NewDecoder(ContentTypeProtobuf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded, err := decoder.DecodeLogs(body)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	events := decoder.LogEvents(decoded)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 error log events, got %d", len(events))
+	}
+	if events[0].Message != "payment declined" {
+		t.Errorf("Expected message from log body, got '%s'", events[0].Message)
+	}
+	if events[0].Level != models.LevelError {
+		t.Errorf("Expected error level, got '%s'", events[0].Level)
+	}
+	if events[0].Environment != defaultEnvironment {
+		t.Errorf("Expected default environment, got '%s'", events[0].Environment)
+	}
+	if events[0].Tags["trace_id"] != "0102" {
+		t.Errorf("Expected hex trace ID, got '%s'", events[0].Tags["trace_id"])
+	}
+	if events[1].Message != "out of memory" {
+		t.Errorf("Expected severity text fallback, got '%s'", events[1].Message)
+	}
+}
+
+func TestNewDecoder_UnsupportedContentType(t *testing.T) {
+	if _, err := NewDecoder("text/plain"); err == nil {
+		t.Error("Expected error for unsupported content type")
+	}
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
@@ -0,0 +1,49 @@
+package otlp
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// EncodeResponse encodes an Export{Trace,Logs}ServiceResponse. Both messages
+// share the same layout: field 1 holds the partial success, which in turn has
+// the rejected count in field 1 and the error message in field 2. The
+// response is empty when nothing was rejected.
+func (d *Decoder) EncodeResponse(rejected int64, errorMessage string, rejectedField string) ([]byte, string) {
+	if d.json {
+		response := map[string]interface{}{}
+		if rejected > 0 || errorMessage != "" {
+			response["partialSuccess"] = map[string]interface{}{
+				rejectedField:  strconv.FormatInt(rejected, 10),
+				"errorMessage": errorMessage,
+			}
+		}
+		body, _ := json.Marshal(response)
+		return body, ContentTypeJSON
+	}
+
+	if rejected == 0 && errorMessage == "" {
+		return []byte{}, ContentTypeProtobuf
+	}
+
+	var partialSuccess []byte
+	partialSuccess = protowire.AppendTag(partialSuccess, 1, protowire.VarintType)
+	partialSuccess = protowire.AppendVarint(partialSuccess, uint64(rejected))
+	if errorMessage != "" {
+		partialSuccess = protowire.AppendTag(partialSuccess, 2, protowire.BytesType)
+		partialSuccess = protowire.AppendString(partialSuccess, errorMessage)
+	}
+
+	var body []byte
+	body = protowire.AppendTag(body, 1, protowire.BytesType)
+	body = protowire.AppendBytes(body, partialSuccess)
+	return body, ContentTypeProtobuf
+}
+
+// JSON field names of the rejected counters
+const (
+	RejectedSpans      = "rejectedSpans"
+	RejectedLogRecords = "rejectedLogRecords"
+)
@@ -0,0 +1,255 @@
+// Package ownership assigns new issues to the users and teams that own the
+// code, URLs or tags their events come from.
+package ownership
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"server/internal/models"
+)
+
+// SettingsKey is the project settings key holding the ownership rules
+const SettingsKey = "ownership_rules"
+
+// Rule limits
+const (
+	MaxRules         = 200
+	MaxOwnersPerRule = 10
+	maxPatternLength = 500
+)
+
+// Matcher types
+const (
+	MatchPath   = "path"   // Glob on the filename or absolute path of any frame
+	MatchModule = "module" // Glob on the module of any frame
+	MatchURL    = "url"    // Glob on the event URL
+	MatchTag    = "tags"   // Glob on the value of the tag Key, written tags.<key>
+)
+
+// Owner is a user, written as their email, or a team, written as #<slug>
+type Owner struct {
+	Type       models.AssigneeType `json:"type"`
+	Identifier string              `json:"identifier"` // Email of users, slug of teams
+}
+
+// String returns the owner as written in the rules
+func (o Owner) String() string {
+	if o.Type == models.AssigneeTeam {
+		return "#" + o.Identifier
+	}
+	return o.Identifier
+}
+
+// Rule assigns issues whose events match its pattern to its first owner.
+// Rules are written one per line as "<type>:<pattern> <owner>...", for
+// example "url:*/checkout/* #payments" or "path:src/billing/* ana@example.com".
+// Patterns are globs where * matches any text and ? a single character.
+type Rule struct {
+	Line    int     `json:"line"`
+	Type    string  `json:"type"`
+	Key     string  `json:"key,omitempty"` // Tag name for tag rules
+	Pattern string  `json:"pattern"`
+	Owners  []Owner `json:"owners"`
+
+	regexp *regexp.Regexp
+}
+
+// String returns the rule as written in the rules
+func (r *Rule) String() string {
+	matcher := r.Type
+	if r.Type == MatchTag {
+		matcher += "." + r.Key
+	}
+
+	parts := []string{matcher + ":" + r.Pattern}
+	for _, owner := range r.Owners {
+		parts = append(parts, owner.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseSettings reads the ownership rules stored in project settings and
+// returns their text along with the parsed rules. Projects without rules
+// return "" and nil.
+func ParseSettings(settings map[string]interface{}) (string, []Rule, error) {
+	raw, ok := settings[SettingsKey]
+	if !ok || raw == nil {
+		return "", nil, nil
+	}
+
+	text, ok := raw.(string)
+	if !ok {
+		return "", nil, fmt.Errorf("invalid ownership rules: expected text, got %T", raw)
+	}
+	rules, err := Parse(text)
+	if err != nil {
+		return "", nil, err
+	}
+	return text, rules, nil
+}
+
+// Parse reads ownership rules. Blank lines and lines starting with # are
+// skipped.
+func Parse(text string) ([]Rule, error) {
+	var rules []Rule
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	line := 0
+	for scanner.Scan() {
+		line++
+		content := strings.TrimSpace(scanner.Text())
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+
+		rule, err := parseRule(content)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rule.Line = line
+		rules = append(rules, *rule)
+
+		if len(rules) > MaxRules {
+			return nil, fmt.Errorf("at most %d ownership rules are allowed", MaxRules)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid ownership rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// Match returns the rule matching the event. Like CODEOWNERS, the last
+// matching rule wins, so specific rules go below general ones. It returns
+// nil when no rule matches.
+func Match(rules []Rule, event *models.ErrorEvent) *Rule {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].matches(event) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// parseRule parses a single rule line
+func parseRule(content string) (*Rule, error) {
+	fields := strings.Fields(content)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("expected \"<type>:<pattern> <owner>...\"")
+	}
+	if len(fields)-1 > MaxOwnersPerRule {
+		return nil, fmt.Errorf("at most %d owners are allowed per rule", MaxOwnersPerRule)
+	}
+
+	matcher, pattern, found := strings.Cut(fields[0], ":")
+	if !found || pattern == "" {
+		return nil, fmt.Errorf("expected \"<type>:<pattern>\", got %q", fields[0])
+	}
+	if len(pattern) > maxPatternLength {
+		return nil, fmt.Errorf("pattern longer than %d characters", maxPatternLength)
+	}
+
+	rule := &Rule{Type: matcher, Pattern: pattern}
+	if key, isTag := strings.CutPrefix(matcher, MatchTag+"."); isTag {
+		if key == "" {
+			return nil, fmt.Errorf("tag rules require a key")
+		}
+		rule.Type = MatchTag
+		rule.Key = key
+	}
+	switch rule.Type {
+	case MatchPath, MatchModule, MatchURL, MatchTag:
+	default:
+		return nil, fmt.Errorf("unknown rule type %q", matcher)
+	}
+
+	for _, field := range fields[1:] {
+		owner, err := parseOwner(field)
+		if err != nil {
+			return nil, err
+		}
+		rule.Owners = append(rule.Owners, owner)
+	}
+
+	rule.regexp = globRegexp(pattern)
+	return rule, nil
+}
+
+// parseOwner parses "#<slug>" as a team and "<email>" as a user
+func parseOwner(field string) (Owner, error) {
+	if slug, isTeam := strings.CutPrefix(field, "#"); isTeam {
+		if slug == "" {
+			return Owner{}, fmt.Errorf("empty team slug")
+		}
+		return Owner{Type: models.AssigneeTeam, Identifier: slug}, nil
+	}
+	if !strings.Contains(field, "@") {
+		return Owner{}, fmt.Errorf("owner %q must be an email or a #team", field)
+	}
+	return Owner{Type: models.AssigneeUser, Identifier: field}, nil
+}
+
+// globRegexp compiles a glob where * matches any text and ? a single
+// character into an anchored regular expression
+func globRegexp(pattern string) *regexp.Regexp {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for _, char := range pattern {
+		switch char {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.MustCompile(builder.String())
+}
+
+// matches reports whether the event satisfies the rule
+func (r *Rule) matches(event *models.ErrorEvent) bool {
+	switch r.Type {
+	case MatchURL:
+		return event.URL != nil && r.regexp.MatchString(*event.URL)
+	case MatchTag:
+		value, ok := event.Tags[r.Key]
+		return ok && r.regexp.MatchString(value)
+	case MatchPath, MatchModule:
+		for _, exception := range event.Exceptions {
+			for _, frame := range exception.Frames() {
+				if r.Type == MatchModule {
+					if frame.Module != "" && r.regexp.MatchString(frame.Module) {
+						return true
+					}
+					continue
+				}
+				if r.matchesPath(frame.Filename) || r.matchesPath(frame.AbsPath) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchesPath reports whether the pattern matches the path or a part of it
+// following a slash, so "src/checkout/*" matches "/app/src/checkout/cart.js"
+func (r *Rule) matchesPath(path string) bool {
+	for path != "" {
+		if r.regexp.MatchString(path) {
+			return true
+		}
+		slash := strings.Index(path, "/")
+		if slash < 0 {
+			return false
+		}
+		path = path[slash+1:]
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package ownership
+
+import (
+	"strings"
+	"testing"
+
+	"server/internal/models"
+)
+
+func testEvent() *models.ErrorEvent {
+	url := "https://shop.example.com/checkout/confirm"
+	return &models.ErrorEvent{
+		Message: "Payment declined",
+		URL:     &url,
+		Tags:    map[string]string{"service": "payments-api"},
+		Exceptions: []models.Exception{{
+			Type: "PaymentError",
+			Stacktrace: &models.Stacktrace{Frames: []models.StackFrame{
+				{Filename: "webpack:///./src/checkout/cart.js", Module: "checkout.cart"},
+				{AbsPath: "/app/src/billing/charge.js", Module: "billing.charge"},
+			}},
+		}},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    string
+		expected int // Line of the matching rule, 0 for none
+	}{
+		{"path suffix", "path:src/checkout/* #payments", 1},
+		{"absolute path", "path:/app/src/billing/*.js ana@example.com", 1},
+		{"module", "module:billing.* #payments", 1},
+		{"url glob", "url:https://*/checkout/* #payments", 1},
+		{"tag", "tags.service:payments-??? #payments", 1},
+		{"last match wins", "url:* #frontend\n\n# Payments\npath:src/billing/* #payments", 4},
+		{"partial path", "path:checkout/cart #payments", 0},
+		{"missing tag", "tags.route:* #payments", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := Parse(tt.rules)
+			if err != nil {
+				t.Fatalf("Failed to parse rules: %v", err)
+			}
+
+			rule := Match(rules, testEvent())
+			switch {
+			case tt.expected == 0 && rule != nil:
+				t.Errorf("Expected no match, got line %d", rule.Line)
+			case tt.expected != 0 && (rule == nil || rule.Line != tt.expected):
+				t.Errorf("Expected line %d to match, got %+v", tt.expected, rule)
+			}
+		})
+	}
+}
+
+func TestParse_Owners(t *testing.T) {
+	rules, err := Parse("tags.service:payments #payments ana@example.com")
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+
+	expected := []Owner{
+		{Type: models.AssigneeTeam, Identifier: "payments"},
+		{Type: models.AssigneeUser, Identifier: "ana@example.com"},
+	}
+	if len(rules) != 1 || len(rules[0].Owners) != 2 || rules[0].Owners[0] != expected[0] || rules[0].Owners[1] != expected[1] {
+		t.Fatalf("Expected owners %+v, got %+v", expected, rules)
+	}
+	if rules[0].String() != "tags.service:payments #payments ana@example.com" {
+		t.Errorf("Expected the rule as written, got %s", rules[0].String())
+	}
+}
+
+func TestParse_RejectsInvalidRules(t *testing.T) {
+	tests := map[string]string{
+		"no owner":      "path:src/*",
+		"no pattern":    "path: #payments",
+		"unknown type":  "function:charge #payments",
+		"tag key":       "tags.:x #payments",
+		"invalid owner": "path:src/* payments",
+		"empty team":    "path:src/* #",
+		"too many":      strings.Repeat("url:* #a\n", MaxRules+1),
+	}
+
+	for name, text := range tests {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("%s: expected %q to be rejected", name, text)
+		}
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	text, rules, err := ParseSettings(map[string]interface{}{})
+	if err != nil || text != "" || rules != nil {
+		t.Errorf("Expected no rules, got %q %v (%v)", text, rules, err)
+	}
+
+	text, rules, err = ParseSettings(map[string]interface{}{SettingsKey: "url:* #web"})
+	if err != nil || text != "url:* #web" || len(rules) != 1 {
+		t.Errorf("Expected one rule, got %q %v (%v)", text, rules, err)
+	}
+
+	if _, _, err := ParseSettings(map[string]interface{}{SettingsKey: []string{"url:* #web"}}); err == nil {
+		t.Error("Expected rules that aren't text to be rejected")
+	}
+}
@@ -0,0 +1,191 @@
+// Package release orders release names. Semantic versions are compared by
+// precedence; other names by when the release was first seen, which stands
+// in for its build date.
+package release
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is a parsed release name
+type Version struct {
+	Name       string
+	Semver     bool
+	Numbers    []uint64 // Major, minor and patch; minor and patch may be missing
+	Prerelease []string
+	Build      string
+}
+
+// Parse parses a release name. A package prefix such as "frontend@" and a
+// leading "v" are ignored. Names that aren't semantic versions parse with
+// Semver false.
+func Parse(name string) Version {
+	version := Version{Name: name}
+
+	value := name
+	if at := strings.LastIndex(value, "@"); at >= 0 {
+		value = value[at+1:]
+	}
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "v"), "V")
+
+	if plus := strings.Index(value, "+"); plus >= 0 {
+		version.Build = value[plus+1:]
+		value = value[:plus]
+	}
+	if dash := strings.Index(value, "-"); dash >= 0 {
+		version.Prerelease = strings.Split(value[dash+1:], ".")
+		value = value[:dash]
+	}
+
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 {
+		return Version{Name: name}
+	}
+	for _, part := range parts {
+		number, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return Version{Name: name}
+		}
+		version.Numbers = append(version.Numbers, number)
+	}
+
+	version.Semver = true
+	return version
+}
+
+// Compare returns -1, 0 or 1 when release a is older than, the same as or
+// newer than release b. Two semantic versions are compared by precedence and
+// then by build metadata, so "1.2.0+20261016" is newer than "1.2.0+20261001".
+// Otherwise the release first seen later is newer; releases missing from
+// firstSeen are older than the ones present. Remaining ties are broken by
+// name.
+func Compare(a, b string, firstSeen map[string]time.Time) int {
+	if a == b {
+		return 0
+	}
+
+	versionA, versionB := Parse(a), Parse(b)
+	if versionA.Semver && versionB.Semver {
+		if result := comparePrecedence(versionA, versionB); result != 0 {
+			return result
+		}
+		if result := compareIdentifiers(versionA.Build, versionB.Build); result != 0 {
+			return result
+		}
+	}
+
+	seenA, okA := firstSeen[a]
+	seenB, okB := firstSeen[b]
+	switch {
+	case okA && !okB:
+		return 1
+	case !okA && okB:
+		return -1
+	case okA && okB && !seenA.Equal(seenB):
+		if seenA.After(seenB) {
+			return 1
+		}
+		return -1
+	}
+
+	return strings.Compare(a, b)
+}
+
+// Latest returns the newest of the releases, or "" when there are none
+func Latest(releases []string, firstSeen map[string]time.Time) string {
+	latest := ""
+	for _, name := range releases {
+		if latest == "" || Compare(name, latest, firstSeen) > 0 {
+			latest = name
+		}
+	}
+	return latest
+}
+
+// comparePrecedence compares semantic versions following semver precedence.
+// Missing minor and patch numbers count as zero.
+func comparePrecedence(a, b Version) int {
+	for i := 0; i < 3; i++ {
+		numberA, numberB := component(a.Numbers, i), component(b.Numbers, i)
+		if numberA != numberB {
+			if numberA > numberB {
+				return 1
+			}
+			return -1
+		}
+	}
+
+	// A prerelease is older than the release itself
+	switch {
+	case len(a.Prerelease) == 0 && len(b.Prerelease) == 0:
+		return 0
+	case len(a.Prerelease) == 0:
+		return 1
+	case len(b.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a.Prerelease) && i < len(b.Prerelease); i++ {
+		if result := compareIdentifier(a.Prerelease[i], b.Prerelease[i]); result != 0 {
+			return result
+		}
+	}
+	return compareInts(len(a.Prerelease), len(b.Prerelease))
+}
+
+// compareIdentifiers compares dot separated identifiers such as build metadata
+func compareIdentifiers(a, b string) int {
+	if a == b {
+		return 0
+	}
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		if result := compareIdentifier(partsA[i], partsB[i]); result != 0 {
+			return result
+		}
+	}
+	return compareInts(len(partsA), len(partsB))
+}
+
+// compareIdentifier compares numeric identifiers numerically and others
+// lexically; numeric identifiers are older than alphanumeric ones
+func compareIdentifier(a, b string) int {
+	numberA, errA := strconv.ParseUint(a, 10, 64)
+	numberB, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if numberA == numberB {
+			return 0
+		}
+		if numberA > numberB {
+			return 1
+		}
+		return -1
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func component(numbers []uint64, index int) uint64 {
+	if index < len(numbers) {
+		return numbers[index]
+	}
+	return 0
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	default:
+		return 0
+	}
+}
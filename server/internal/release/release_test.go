@@ -0,0 +1,77 @@
+package release
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompare_Semver(t *testing.T) {
+	tests := []struct {
+		older, newer string
+	}{
+		{"1.2.3", "1.2.4"},
+		{"1.9.0", "1.10.0"},
+		{"v1.2", "1.2.1"},
+		{"frontend@1.0.0", "frontend@2.0.0"},
+		{"1.0.0-alpha", "1.0.0-alpha.1"},
+		{"1.0.0-alpha.1", "1.0.0-beta"},
+		{"1.0.0-beta.2", "1.0.0-beta.11"},
+		{"1.0.0-rc.1", "1.0.0"},
+		{"1.0.0+20261001", "1.0.0+20261016"},
+	}
+
+	for _, tt := range tests {
+		if result := Compare(tt.older, tt.newer, nil); result != -1 {
+			t.Errorf("Expected %s to be older than %s, got %d", tt.older, tt.newer, result)
+		}
+		if result := Compare(tt.newer, tt.older, nil); result != 1 {
+			t.Errorf("Expected %s to be newer than %s, got %d", tt.newer, tt.older, result)
+		}
+	}
+}
+
+func TestCompare_FirstSeen(t *testing.T) {
+	now := time.Now()
+	firstSeen := map[string]time.Time{
+		"abc123": now.Add(-time.Hour),
+		"def456": now,
+	}
+
+	if Compare("abc123", "def456", firstSeen) != -1 {
+		t.Error("Expected the release seen first to be older")
+	}
+	if Compare("unknown", "abc123", firstSeen) != -1 {
+		t.Error("Expected an unseen release to be older")
+	}
+
+	firstSeen["1.0.0"] = now.Add(time.Hour)
+	firstSeen["2.0.0"] = now
+	if Compare("2.0.0", "1.0.0", firstSeen) != 1 {
+		t.Error("Expected semantic versions to be ordered by precedence, not first seen dates")
+	}
+}
+
+func TestLatest(t *testing.T) {
+	if latest := Latest([]string{"1.2.0", "1.10.0", "1.9.3"}, nil); latest != "1.10.0" {
+		t.Errorf("Expected 1.10.0, got %s", latest)
+	}
+	if latest := Latest(nil, nil); latest != "" {
+		t.Errorf("Expected no release, got %s", latest)
+	}
+}
+
+func TestParse(t *testing.T) {
+	version := Parse("api@v2.1.0-rc.1+exp.sha.5114f85")
+	if !version.Semver || len(version.Numbers) != 3 || version.Numbers[0] != 2 {
+		t.Fatalf("Expected a semantic version, got %+v", version)
+	}
+	if len(version.Prerelease) != 2 || version.Build != "exp.sha.5114f85" {
+		t.Errorf("Unexpected prerelease or build: %+v", version)
+	}
+
+	for _, name := range []string{"5114f85", "2026.10.16.1", "release-candidate", ""} {
+		if Parse(name).Semver {
+			t.Errorf("Expected %q not to parse as a semantic version", name)
+		}
+	}
+}
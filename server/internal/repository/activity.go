@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"server/internal/database"
+	"server/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ActivityRepository handles the activity log of issues
+type ActivityRepository struct {
+	db *database.PostgresDB
+}
+
+// NewActivityRepository creates a new activity repository
+func NewActivityRepository(db *database.PostgresDB) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// Create adds an entry to the activity log of an issue. The user the API key
+// acts for is stored along with the key. Entries an issue has only once, such
+// as first_seen, are skipped when the issue already has one.
+func (r *ActivityRepository) Create(ctx context.Context, activity *models.IssueActivity) error {
+	data := activity.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode activity data: %w", err)
+	}
+
+	query := `
+		INSERT INTO issue_activity (id, project_id, issue_id, type, api_key_id, user_id, data)
+		VALUES ($1, $2, $3, $4, $5, (SELECT user_id FROM api_keys WHERE id = $5), $6)
+		ON CONFLICT DO NOTHING
+		RETURNING id, user_id, created_at
+	`
+
+	err = r.db.QueryRowContext(ctx, query,
+		uuid.New(),
+		activity.ProjectID,
+		activity.IssueID,
+		string(activity.Type),
+		activity.APIKeyID,
+		dataJSON,
+	).Scan(&activity.ID, &activity.UserID, &activity.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create issue activity: %w", err)
+	}
+
+	return nil
+}
+
+// ListByIssue returns a page of the activity log of an issue of a project,
+// oldest first, and the number of entries in the log
+func (r *ActivityRepository) ListByIssue(ctx context.Context, projectID uuid.UUID, issueID string, page, limit int) ([]models.IssueActivity, int, error) {
+	var total int
+	countQuery := `SELECT count(*) FROM issue_activity WHERE project_id = $1 AND issue_id = $2`
+	if err := r.db.QueryRowContext(ctx, countQuery, projectID, issueID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count issue activity: %w", err)
+	}
+
+	query := `
+		SELECT id, project_id, issue_id, type, api_key_id, user_id, data, created_at
+		FROM issue_activity
+		WHERE project_id = $1 AND issue_id = $2
+		ORDER BY created_at, id
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, projectID, issueID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get issue activity: %w", err)
+	}
+	defer rows.Close()
+
+	activities := []models.IssueActivity{}
+	for rows.Next() {
+		var activity models.IssueActivity
+		var dataJSON []byte
+		err := rows.Scan(
+			&activity.ID,
+			&activity.ProjectID,
+			&activity.IssueID,
+			&activity.Type,
+			&activity.APIKeyID,
+			&activity.UserID,
+			&dataJSON,
+			&activity.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan issue activity: %w", err)
+		}
+		if err := json.Unmarshal(dataJSON, &activity.Data); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode activity data: %w", err)
+		}
+		activities = append(activities, activity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating issue activity: %w", err)
+	}
+
+	return activities, total, nil
+}
+
+// DeleteByIssues deletes the activity logs of issues of a project
+func (r *ActivityRepository) DeleteByIssues(ctx context.Context, projectID uuid.UUID, issueIDs []string) error {
+	query := `DELETE FROM issue_activity WHERE project_id = $1 AND issue_id = ANY($2::uuid[])`
+
+	if _, err := r.db.ExecContext(ctx, query, projectID, pq.Array(issueIDs)); err != nil {
+		return fmt.Errorf("failed to delete issue activity: %w", err)
+	}
+
+	return nil
+}
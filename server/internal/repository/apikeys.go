@@ -25,7 +25,7 @@ func NewAPIKeysRepository(db *database.PostgresDB) *APIKeysRepository {
 // GetByHash retrieves an API key by its hash
 func (r *APIKeysRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
 	query := `
-		SELECT id, name, key_hash, key_prefix, project_id, scopes, 
+		SELECT id, name, key_hash, key_prefix, project_id, user_id, scopes, 
 		       last_used_at, created_at, expires_at
 		FROM api_keys 
 		WHERE key_hash = $1
@@ -40,6 +40,7 @@ func (r *APIKeysRepository) GetByHash(ctx context.Context, keyHash string) (*mod
 		&apiKey.KeyHash,
 		&apiKey.KeyPrefix,
 		&apiKey.ProjectID,
+		&apiKey.UserID,
 		&scopes,
 		&apiKey.LastUsedAt,
 		&apiKey.CreatedAt,
@@ -60,7 +61,7 @@ func (r *APIKeysRepository) GetByHash(ctx context.Context, keyHash string) (*mod
 // GetByProject retrieves all API keys for a project
 func (r *APIKeysRepository) GetByProject(ctx context.Context, projectID uuid.UUID) ([]*models.APIKey, error) {
 	query := `
-		SELECT id, name, key_hash, key_prefix, project_id, scopes, 
+		SELECT id, name, key_hash, key_prefix, project_id, user_id, scopes, 
 		       last_used_at, created_at, expires_at
 		FROM api_keys 
 		WHERE project_id = $1
@@ -84,6 +85,7 @@ func (r *APIKeysRepository) GetByProject(ctx context.Context, projectID uuid.UUI
 			&apiKey.KeyHash,
 			&apiKey.KeyPrefix,
 			&apiKey.ProjectID,
+			&apiKey.UserID,
 			&scopes,
 			&apiKey.LastUsedAt,
 			&apiKey.CreatedAt,
@@ -107,8 +109,8 @@ func (r *APIKeysRepository) GetByProject(ctx context.Context, projectID uuid.UUI
 // Create creates a new API key
 func (r *APIKeysRepository) Create(ctx context.Context, apiKey *models.APIKey) error {
 	query := `
-		INSERT INTO api_keys (id, name, key_hash, key_prefix, project_id, scopes, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO api_keys (id, name, key_hash, key_prefix, project_id, user_id, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 
 	apiKey.ID = uuid.New()
@@ -120,6 +122,7 @@ func (r *APIKeysRepository) Create(ctx context.Context, apiKey *models.APIKey) e
 		apiKey.KeyHash,
 		apiKey.KeyPrefix,
 		apiKey.ProjectID,
+		apiKey.UserID,
 		pq.Array(apiKey.Scopes),
 		apiKey.ExpiresAt,
 	)
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"server/internal/database"
+	"server/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ArtifactsRepository handles release artifact metadata. The artifact
+// contents live in a storage.Store under StorageKey.
+type ArtifactsRepository struct {
+	db *database.PostgresDB
+}
+
+// NewArtifactsRepository creates a new artifacts repository
+func NewArtifactsRepository(db *database.PostgresDB) *ArtifactsRepository {
+	return &ArtifactsRepository{db: db}
+}
+
+// Upsert creates an artifact or replaces the artifact with the same project,
+// release and name
+func (r *ArtifactsRepository) Upsert(ctx context.Context, artifact *models.ReleaseArtifact) error {
+	query := `
+		INSERT INTO release_artifacts (id, project_id, release, name, storage_key, size, checksum, content_type)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (project_id, release, name) DO UPDATE
+		SET storage_key = EXCLUDED.storage_key,
+		    size = EXCLUDED.size,
+		    checksum = EXCLUDED.checksum,
+		    content_type = EXCLUDED.content_type,
+		    created_at = NOW()
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		uuid.New(),
+		artifact.ProjectID,
+		artifact.Release,
+		artifact.Name,
+		artifact.StorageKey,
+		artifact.Size,
+		artifact.Checksum,
+		artifact.ContentType,
+	).Scan(&artifact.ID, &artifact.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save release artifact: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an artifact of a project by its ID
+func (r *ArtifactsRepository) GetByID(ctx context.Context, projectID, artifactID uuid.UUID) (*models.ReleaseArtifact, error) {
+	query := `
+		SELECT id, project_id, release, name, storage_key, size, checksum, content_type, created_at
+		FROM release_artifacts
+		WHERE project_id = $1 AND id = $2
+	`
+
+	artifact, err := scanArtifact(r.db.QueryRowContext(ctx, query, projectID, artifactID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get release artifact: %w", err)
+	}
+
+	return artifact, nil
+}
+
+// GetByName retrieves the artifact uploaded for a release under name
+func (r *ArtifactsRepository) GetByName(ctx context.Context, projectID uuid.UUID, release, name string) (*models.ReleaseArtifact, error) {
+	query := `
+		SELECT id, project_id, release, name, storage_key, size, checksum, content_type, created_at
+		FROM release_artifacts
+		WHERE project_id = $1 AND release = $2 AND name = $3
+	`
+
+	artifact, err := scanArtifact(r.db.QueryRowContext(ctx, query, projectID, release, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get release artifact by name: %w", err)
+	}
+
+	return artifact, nil
+}
+
+// List retrieves all artifacts of a release
+func (r *ArtifactsRepository) List(ctx context.Context, projectID uuid.UUID, release string) ([]*models.ReleaseArtifact, error) {
+	query := `
+		SELECT id, project_id, release, name, storage_key, size, checksum, content_type, created_at
+		FROM release_artifacts
+		WHERE project_id = $1 AND release = $2
+		ORDER BY name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, projectID, release)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list release artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	artifacts := make([]*models.ReleaseArtifact, 0)
+	for rows.Next() {
+		artifact, err := scanArtifact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan release artifact: %w", err)
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating release artifacts: %w", err)
+	}
+
+	return artifacts, nil
+}
+
+// Delete deletes an artifact of a project
+func (r *ArtifactsRepository) Delete(ctx context.Context, projectID, artifactID uuid.UUID) error {
+	query := `DELETE FROM release_artifacts WHERE project_id = $1 AND id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, projectID, artifactID)
+	if err != nil {
+		return fmt.Errorf("failed to delete release artifact: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("release artifact not found")
+	}
+
+	return nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanArtifact(row rowScanner) (*models.ReleaseArtifact, error) {
+	var artifact models.ReleaseArtifact
+	err := row.Scan(
+		&artifact.ID,
+		&artifact.ProjectID,
+		&artifact.Release,
+		&artifact.Name,
+		&artifact.StorageKey,
+		&artifact.Size,
+		&artifact.Checksum,
+		&artifact.ContentType,
+		&artifact.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"server/internal/database"
+	"server/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// AssigneesRepository looks up the users and teams issues are assigned to
+type AssigneesRepository struct {
+	db *database.PostgresDB
+}
+
+// NewAssigneesRepository creates a new assignees repository
+func NewAssigneesRepository(db *database.PostgresDB) *AssigneesRepository {
+	return &AssigneesRepository{db: db}
+}
+
+// Exists reports whether the user or team of an assignee belongs to a space
+func (r *AssigneesRepository) Exists(ctx context.Context, spaceID uuid.UUID, assignee *models.Assignee) (bool, error) {
+	var query string
+	switch assignee.Type {
+	case models.AssigneeUser:
+		query = `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND space_id = $2)`
+	case models.AssigneeTeam:
+		query = `SELECT EXISTS (SELECT 1 FROM teams WHERE id = $1 AND space_id = $2)`
+	default:
+		return false, fmt.Errorf("unknown assignee type %q", assignee.Type)
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, assignee.ID, spaceID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up %s: %w", assignee.Type, err)
+	}
+
+	return exists, nil
+}
+
+// Resolve looks up a user of a space by email or a team by slug. It returns
+// nil when the space has no such user or team.
+func (r *AssigneesRepository) Resolve(ctx context.Context, spaceID uuid.UUID, assigneeType models.AssigneeType, identifier string) (*models.Assignee, error) {
+	var query string
+	switch assigneeType {
+	case models.AssigneeUser:
+		query = `SELECT id FROM users WHERE lower(email) = lower($1) AND space_id = $2`
+	case models.AssigneeTeam:
+		query = `SELECT id FROM teams WHERE slug = $1 AND space_id = $2`
+	default:
+		return nil, fmt.Errorf("unknown assignee type %q", assigneeType)
+	}
+
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, query, identifier, spaceID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up %s %q: %w", assigneeType, identifier, err)
+	}
+
+	return &models.Assignee{Type: assigneeType, ID: id}, nil
+}
+
+// ResolveMentions looks up the users and teams of a space mentioned in a
+// comment, with one query per assignee type. It returns the assignees in the
+// order of the mentions and skips mentions the space has no user or team for.
+func (r *AssigneesRepository) ResolveMentions(ctx context.Context, spaceID uuid.UUID, mentions []models.Mention) ([]models.Assignee, error) {
+	identifiers := make(map[models.AssigneeType][]string)
+	for _, mention := range mentions {
+		identifiers[mention.Type] = append(identifiers[mention.Type], mention.Identifier)
+	}
+
+	ids := make(map[models.Mention]uuid.UUID)
+	for assigneeType, values := range identifiers {
+		if err := r.resolveIdentifiers(ctx, spaceID, assigneeType, values, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	var assignees []models.Assignee
+	for _, mention := range mentions {
+		if id, ok := ids[mention]; ok {
+			assignees = append(assignees, models.Assignee{Type: mention.Type, ID: id})
+		}
+	}
+
+	return assignees, nil
+}
+
+// resolveIdentifiers looks up the users of a space by email or its teams by
+// slug and adds their IDs to ids
+func (r *AssigneesRepository) resolveIdentifiers(ctx context.Context, spaceID uuid.UUID, assigneeType models.AssigneeType, identifiers []string, ids map[models.Mention]uuid.UUID) error {
+	var query string
+	switch assigneeType {
+	case models.AssigneeUser:
+		query = `SELECT id, lower(email) FROM users WHERE lower(email) = ANY($1) AND space_id = $2`
+	case models.AssigneeTeam:
+		query = `SELECT id, slug FROM teams WHERE slug = ANY($1) AND space_id = $2`
+	default:
+		return fmt.Errorf("unknown assignee type %q", assigneeType)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(identifiers), spaceID)
+	if err != nil {
+		return fmt.Errorf("failed to look up %ss: %w", assigneeType, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var identifier string
+		if err := rows.Scan(&id, &identifier); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", assigneeType, err)
+		}
+		ids[models.Mention{Type: assigneeType, Identifier: identifier}] = id
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to look up %ss: %w", assigneeType, err)
+	}
+
+	return nil
+}
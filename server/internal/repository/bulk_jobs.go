@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"server/internal/database"
+	"server/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// BulkJobsRepository handles the progress of bulk issue operations
+type BulkJobsRepository struct {
+	db *database.PostgresDB
+}
+
+// NewBulkJobsRepository creates a new bulk jobs repository
+func NewBulkJobsRepository(db *database.PostgresDB) *BulkJobsRepository {
+	return &BulkJobsRepository{db: db}
+}
+
+// Create saves a new running job
+func (r *BulkJobsRepository) Create(ctx context.Context, job *models.BulkJob) error {
+	query := `
+		INSERT INTO issue_bulk_jobs (id, project_id, api_key_id, operation, status, total)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	job.Status = models.BulkJobRunning
+	err := r.db.QueryRowContext(ctx, query,
+		uuid.New(),
+		job.ProjectID,
+		job.APIKeyID,
+		string(job.Operation),
+		string(job.Status),
+		job.Total,
+	).Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create bulk job: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateProgress saves the number of issues a job has processed
+func (r *BulkJobsRepository) UpdateProgress(ctx context.Context, jobID uuid.UUID, processed int) error {
+	query := `
+		UPDATE issue_bulk_jobs
+		SET processed = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, jobID, processed); err != nil {
+		return fmt.Errorf("failed to update bulk job progress: %w", err)
+	}
+
+	return nil
+}
+
+// Finish saves the outcome of a job. errorMessage is empty for jobs that
+// completed.
+func (r *BulkJobsRepository) Finish(ctx context.Context, jobID uuid.UUID, status models.BulkJobStatus, processed int, errorMessage string) error {
+	query := `
+		UPDATE issue_bulk_jobs
+		SET status = $2, processed = $3, error = NULLIF($4, ''), updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, jobID, string(status), processed, errorMessage); err != nil {
+		return fmt.Errorf("failed to finish bulk job: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a job of a project by its ID
+func (r *BulkJobsRepository) GetByID(ctx context.Context, projectID, jobID uuid.UUID) (*models.BulkJob, error) {
+	query := `
+		SELECT id, project_id, api_key_id, operation, status, total, processed, error,
+		       created_at, updated_at, finished_at
+		FROM issue_bulk_jobs
+		WHERE project_id = $1 AND id = $2
+	`
+
+	var job models.BulkJob
+	var operation, status string
+	err := r.db.QueryRowContext(ctx, query, projectID, jobID).Scan(
+		&job.ID,
+		&job.ProjectID,
+		&job.APIKeyID,
+		&operation,
+		&status,
+		&job.Total,
+		&job.Processed,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get bulk job: %w", err)
+	}
+
+	job.Operation = models.BulkOperation(operation)
+	job.Status = models.BulkJobStatus(status)
+
+	return &job, nil
+}
@@ -2,7 +2,7 @@ package repository
 
 import (
 	"context"
-	"crypto/md5"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -34,8 +34,9 @@ func (r *EventsRepository) InsertEvents(ctx context.Context, events []*models.Er
 		INSERT INTO error_events (
 			id, project_id, timestamp, message, stack_trace, environment,
 			release_version, user_id, user_email, user_ip, browser, os, url,
-			tags, extra, fingerprint, level, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			tags, extra, fingerprint, level, created_at,
+			exception_type, exception_value, exceptions
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare batch: %w", err)
@@ -43,7 +44,12 @@ func (r *EventsRepository) InsertEvents(ctx context.Context, events []*models.Er
 
 	// Add events to batch
 	for _, event := range events {
-		err := batch.Append(
+		exceptionType, exceptionValue, exceptions, err := encodeExceptions(event.Exceptions)
+		if err != nil {
+			return err
+		}
+
+		err = batch.Append(
 			event.ID,
 			event.ProjectID,
 			event.Timestamp,
@@ -62,6 +68,9 @@ func (r *EventsRepository) InsertEvents(ctx context.Context, events []*models.Er
 			event.Fingerprint,
 			string(event.Level),
 			event.CreatedAt,
+			exceptionType,
+			exceptionValue,
+			exceptions,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to append event to batch: %w", err)
@@ -101,27 +110,25 @@ func (r *EventsRepository) GetEvents(ctx context.Context, query *models.EventsQu
 		argIndex++
 	}
 
-	// Add time range condition
-	if query.TimeRange != nil {
-		timeCondition := getTimeRangeCondition(*query.TimeRange)
-		if timeCondition != "" {
-			conditions = append(conditions, timeCondition)
-		}
+	if query.Window != nil {
+		conditions = append(conditions, rawEvents.timeCondition(argIndex))
+		args = append(args, query.Window.Start, query.Window.End)
+		argIndex += 2
 	}
 
-	// If issue_id is provided, get fingerprint and filter by it
+	// If issue_id is provided, get its fingerprints and filter by them
 	if query.IssueID != nil && *query.IssueID != "" {
-		// Get issue fingerprint
-		fingerprintQuery := `SELECT fingerprint FROM issues WHERE id = $1 LIMIT 1`
+		// Get the issue fingerprint and the fingerprints merged into it
+		fingerprintQuery := `SELECT arrayPushFront(merged_fingerprints, fingerprint) FROM issues FINAL WHERE id = $1 LIMIT 1`
 		row := r.db.QueryRow(ctx, fingerprintQuery, *query.IssueID)
 
-		var fingerprint string
-		if err := row.Scan(&fingerprint); err != nil {
-			return nil, fmt.Errorf("failed to get issue fingerprint: %w", err)
+		var fingerprints []string
+		if err := row.Scan(&fingerprints); err != nil {
+			return nil, fmt.Errorf("failed to get issue fingerprints: %w", err)
 		}
 
-		conditions = append(conditions, fmt.Sprintf("fingerprint = $%d", argIndex))
-		args = append(args, fingerprint)
+		conditions = append(conditions, fmt.Sprintf("has($%d, fingerprint)", argIndex))
+		args = append(args, fingerprints)
 		argIndex++
 	}
 
@@ -152,7 +159,7 @@ func (r *EventsRepository) GetEvents(ctx context.Context, query *models.EventsQu
 		SELECT 
 			id, project_id, timestamp, message, stack_trace, environment,
 			release_version, user_id, user_email, user_ip, browser, os, url,
-			tags, extra, fingerprint, level
+			tags, extra, fingerprint, level, exceptions
 		FROM error_events
 		%s
 		ORDER BY timestamp DESC
@@ -168,7 +175,7 @@ func (r *EventsRepository) GetEvents(ctx context.Context, query *models.EventsQu
 	var events []models.ErrorEvent
 	for rows.Next() {
 		var event models.ErrorEvent
-		var level string
+		var level, exceptions string
 
 		err := rows.Scan(
 			&event.ID,
@@ -188,12 +195,18 @@ func (r *EventsRepository) GetEvents(ctx context.Context, query *models.EventsQu
 			&event.Extra,
 			&event.Fingerprint,
 			&level,
+			&exceptions,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan event: %w", err)
 		}
 
 		event.Level = models.ErrorLevel(level)
+		if exceptions != "" {
+			if err := json.Unmarshal([]byte(exceptions), &event.Exceptions); err != nil {
+				return nil, fmt.Errorf("failed to decode event exceptions: %w", err)
+			}
+		}
 		events = append(events, event)
 	}
 
@@ -211,23 +224,11 @@ func (r *EventsRepository) GetEvents(ctx context.Context, query *models.EventsQu
 	}, nil
 }
 
-// GetProjectStats retrieves aggregated statistics for a project
-func (r *EventsRepository) GetProjectStats(ctx context.Context, projectID uuid.UUID, timeRange string) (*models.ProjectStats, error) {
-	timeCondition := getTimeRangeCondition(timeRange)
-
-	query := fmt.Sprintf(`
-		SELECT 
-			'%s' as project_id,
-			count() as total_events,
-			uniq(fingerprint) as total_issues,
-			countIf(level = 'error') as error_events,
-			uniq(user_id) as affected_users,
-			max(timestamp) as last_event
-		FROM error_events
-		WHERE project_id = $1 AND %s
-	`, projectID.String(), timeCondition)
-
-	row := r.db.QueryRow(ctx, query, projectID)
+// GetProjectStats retrieves aggregated statistics for a project, from the
+// event rollups where the time window allows
+func (r *EventsRepository) GetProjectStats(ctx context.Context, projectID uuid.UUID, window *models.TimeWindow) (*models.ProjectStats, error) {
+	query, args := statsQuery(projectID, statsParts(window, time.Now()))
+	row := r.db.QueryRow(ctx, query, args...)
 
 	var stats models.ProjectStats
 	var lastEvent *time.Time
@@ -254,39 +255,77 @@ func (r *EventsRepository) GetProjectStats(ctx context.Context, projectID uuid.U
 	return &stats, nil
 }
 
-// GenerateFingerprint generates a fingerprint for an event
-func (r *EventsRepository) GenerateFingerprint(event *models.IngestEvent) string {
-	// Create fingerprint based on message and stack trace
-	fingerprintData := event.Message
-	if event.StackTrace != nil {
-		// Use first few lines of stack trace for fingerprint
-		lines := strings.Split(*event.StackTrace, "\n")
-		if len(lines) > 3 {
-			lines = lines[:3]
+// GetReleaseFirstSeen returns when the releases of a project were first seen,
+// or when all of its releases were if none are given. The times come from the
+// daily rollup and are rounded down to the day.
+func (r *EventsRepository) GetReleaseFirstSeen(ctx context.Context, projectID uuid.UUID, releases []string) (map[string]time.Time, error) {
+	query := `
+		SELECT release_version, min(bucket)
+		FROM event_rollups_daily
+		WHERE project_id = $1 AND release_version != ''
+		GROUP BY release_version
+	`
+	args := []interface{}{projectID}
+	if len(releases) > 0 {
+		query = `
+			SELECT release_version, min(bucket)
+			FROM event_rollups_daily
+			WHERE project_id = $1 AND has($2, release_version)
+			GROUP BY release_version
+		`
+		args = append(args, releases)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query releases: %w", err)
+	}
+	defer rows.Close()
+
+	firstSeen := make(map[string]time.Time)
+	for rows.Next() {
+		var release string
+		var seen time.Time
+		if err := rows.Scan(&release, &seen); err != nil {
+			return nil, fmt.Errorf("failed to scan release: %w", err)
 		}
-		fingerprintData += strings.Join(lines, "\n")
+		firstSeen[release] = seen
 	}
 
-	// Add environment to make fingerprints environment-specific
-	fingerprintData += event.Environment
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating releases: %w", err)
+	}
 
-	// Generate MD5 hash
-	hash := md5.Sum([]byte(fingerprintData))
-	return fmt.Sprintf("%x", hash)
+	return firstSeen, nil
 }
 
-// getTimeRangeCondition returns a ClickHouse condition for time range filtering
-func getTimeRangeCondition(timeRange string) string {
-	switch timeRange {
-	case "1h":
-		return "timestamp >= now() - INTERVAL 1 HOUR"
-	case "24h":
-		return "timestamp >= now() - INTERVAL 24 HOUR"
-	case "7d":
-		return "timestamp >= now() - INTERVAL 7 DAY"
-	case "30d":
-		return "timestamp >= now() - INTERVAL 30 DAY"
-	default:
-		return "timestamp >= now() - INTERVAL 24 HOUR"
+// encodeExceptions returns the type and value of the raised exception and the
+// JSON encoded exception chain for storage
+func encodeExceptions(exceptions []models.Exception) (string, string, string, error) {
+	if len(exceptions) == 0 {
+		return "", "", "", nil
+	}
+
+	encoded, err := json.Marshal(exceptions)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to encode exceptions: %w", err)
 	}
+	return exceptions[0].Type, exceptions[0].Value, string(encoded), nil
+}
+
+// CountIssueEvents returns the number of events with the given fingerprints
+// since a time
+func (r *EventsRepository) CountIssueEvents(ctx context.Context, projectID uuid.UUID, fingerprints []string, since time.Time) (uint64, error) {
+	query := `
+		SELECT count()
+		FROM error_events
+		WHERE project_id = $1 AND has($2, fingerprint) AND timestamp >= $3
+	`
+
+	var count uint64
+	if err := r.db.QueryRow(ctx, query, projectID, fingerprints, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count issue events: %w", err)
+	}
+
+	return count, nil
 }
@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"server/internal/database"
 	"server/internal/models"
 
+	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/google/uuid"
 )
 
@@ -16,69 +18,104 @@ type IssuesRepository struct {
 	db *database.ClickHouseDB
 }
 
+// issuesSource returns the issues table with its counters and timestamps
+// taken from issue_stats. The issue rows only hold the values of the batch
+// that created them; issue_stats is maintained by a materialized view over
+// error_events and counts every event and affected user exactly once. The
+// stats of an issue cover its own fingerprint and the fingerprints merged
+// into it. Issues merged into another one are left out.
+//
+// The filter selects issues by their stored columns, such as project_id and
+// id, and is applied inside the subqueries so only the issues and stats it
+// selects are read. Conditions on the counters go in the outer query.
+func issuesSource(filter string) string {
+	if filter == "" {
+		filter = "1"
+	}
+
+	// The filter is applied before the fingerprints are expanded, since the
+	// expanded column shadows the fingerprint column
+	issues := fmt.Sprintf(`
+			SELECT *
+			FROM issues FINAL
+			WHERE merged_into = '' AND (%s)`, filter)
+	fingerprints := fmt.Sprintf(`
+			SELECT id AS issue_id, project_id, arrayJoin(arrayPushFront(merged_fingerprints, fingerprint)) AS fingerprint
+			FROM (%s
+			)`, issues)
+
+	return fmt.Sprintf(`(
+	SELECT
+		i.id AS id, i.project_id AS project_id, i.fingerprint AS fingerprint,
+		i.message AS message, i.level AS level, i.status AS status,
+		i.substatus AS substatus, i.resolution AS resolution,
+		i.resolved_in_release AS resolved_in_release, i.resolved_at AS resolved_at,
+		i.ignore_until AS ignore_until, i.ignore_until_event_count AS ignore_until_event_count,
+		i.ignore_until_user_count AS ignore_until_user_count,
+		i.ignore_event_rate AS ignore_event_rate, i.ignore_rate_window AS ignore_rate_window,
+		if(s.event_count = 0, i.first_seen, s.first_seen) AS first_seen,
+		if(s.event_count = 0, i.last_seen, s.last_seen) AS last_seen,
+		if(s.event_count = 0, i.event_count, s.event_count) AS event_count,
+		if(s.event_count = 0, i.user_count, s.user_count) AS user_count,
+		if(s.event_count = 0, i.environments, s.environments) AS environments,
+		i.tags AS tags, i.grouping_version AS grouping_version,
+		i.regressed_at AS regressed_at, i.regressed_in_release AS regressed_in_release,
+		i.merged_fingerprints AS merged_fingerprints,
+		i.assignee_type AS assignee_type, i.assignee_id AS assignee_id
+	FROM (%[1]s
+	) AS i
+	LEFT JOIN (
+		SELECT
+			f.issue_id AS issue_id,
+			min(st.first_seen) AS first_seen,
+			max(st.last_seen) AS last_seen,
+			sum(st.event_count) AS event_count,
+			groupUniqArrayArray(st.environments) AS environments,
+			uniqMerge(st.users) AS user_count
+		FROM issue_stats AS st
+		INNER JOIN (%[2]s
+		) AS f ON f.project_id = st.project_id AND f.fingerprint = st.fingerprint
+		WHERE (st.project_id, st.fingerprint) IN (
+			SELECT project_id, fingerprint FROM (%[2]s
+			)
+		)
+		GROUP BY f.issue_id
+	) AS s ON s.issue_id = i.id
+) AS issues`, issues, fingerprints)
+}
+
+// issueColumns are the columns read by scanIssue
+const issueColumns = `
+	id, project_id, fingerprint, message, level, status, substatus,
+	resolution, resolved_in_release, resolved_at, ignore_until, ignore_until_event_count,
+	ignore_until_user_count, ignore_event_rate, ignore_rate_window, first_seen, last_seen, event_count, user_count, environments, tags,
+	grouping_version, regressed_at, regressed_in_release, merged_fingerprints,
+	assignee_type, assignee_id`
+
 // NewIssuesRepository creates a new issues repository
 func NewIssuesRepository(db *database.ClickHouseDB) *IssuesRepository {
 	return &IssuesRepository{db: db}
 }
 
+// IssueID returns the ID of a new issue. It is derived from the project and
+// fingerprint, so replicas that create the same issue concurrently write the
+// same row and the duplicates collapse when the table merges.
+func IssueID(projectID uuid.UUID, fingerprint string) string {
+	return uuid.NewSHA1(projectID, []byte(fingerprint)).String()
+}
+
 // GetIssues retrieves issues with pagination and filtering
 func (r *IssuesRepository) GetIssues(ctx context.Context, query *models.IssuesQuery) (*models.IssuesResponse, error) {
-	// Build WHERE conditions
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	if query.ProjectID != nil {
-		conditions = append(conditions, fmt.Sprintf("project_id = $%d", argIndex))
-		args = append(args, *query.ProjectID)
-		argIndex++
-	}
-
-	if query.Status != nil && *query.Status != "" {
-		conditions = append(conditions, fmt.Sprintf("status = $%d", argIndex))
-		args = append(args, string(*query.Status))
-		argIndex++
-	}
-
-	if query.Level != nil && *query.Level != "" {
-		conditions = append(conditions, fmt.Sprintf("level = $%d", argIndex))
-		args = append(args, string(*query.Level))
-		argIndex++
-	}
-
-	if query.Environment != nil && *query.Environment != "" {
-		conditions = append(conditions, fmt.Sprintf("has(environments, $%d)", argIndex))
-		args = append(args, *query.Environment)
-		argIndex++
-	}
-
-	if query.Search != nil && *query.Search != "" {
-		conditions = append(conditions, fmt.Sprintf("positionCaseInsensitive(message, $%d) > 0", argIndex))
-		args = append(args, *query.Search)
-		argIndex++
-	}
-
-	// Add time range condition
-	if query.TimeRange != nil {
-		timeCondition := getTimeRangeCondition(*query.TimeRange)
-		if timeCondition != "" {
-			// For issues, we filter by last_seen
-			timeCondition = strings.Replace(timeCondition, "timestamp", "last_seen", 1)
-			conditions = append(conditions, timeCondition)
-		}
-	}
-
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = "WHERE " + strings.Join(conditions, " AND ")
-	}
+	filter, whereClause, args := issuesFilter(query)
 
 	// Count total issues - using safe query building
 	var countQuery string
 	if whereClause != "" {
-		countQuery = "SELECT count() FROM issues " + whereClause
+		countQuery = "SELECT count() FROM " + issuesSource(filter) + " " + whereClause
+	} else if filter != "" {
+		countQuery = "SELECT count() FROM issues FINAL WHERE merged_into = '' AND " + filter
 	} else {
-		countQuery = "SELECT count() FROM issues"
+		countQuery = "SELECT count() FROM issues FINAL WHERE merged_into = ''"
 	}
 	row := r.db.QueryRow(ctx, countQuery, args...)
 
@@ -108,14 +145,12 @@ func (r *IssuesRepository) GetIssues(ctx context.Context, query *models.IssuesQu
 
 	// Get issues
 	dataQuery := fmt.Sprintf(`
-		SELECT
-			id, project_id, fingerprint, message, level, status,
-			first_seen, last_seen, event_count, user_count, environments, tags
-		FROM issues
+		SELECT %s
+		FROM %s
 		%s
 		ORDER BY %s
 		LIMIT %d OFFSET %d
-	`, whereClause, orderBy, query.Limit, offset)
+	`, issueColumns, issuesSource(filter), whereClause, orderBy, query.Limit, offset)
 
 	rows, err := r.db.Query(ctx, dataQuery, args...)
 	if err != nil {
@@ -125,30 +160,11 @@ func (r *IssuesRepository) GetIssues(ctx context.Context, query *models.IssuesQu
 
 	var issues []models.Issue
 	for rows.Next() {
-		var issue models.Issue
-		var level, status string
-
-		err := rows.Scan(
-			&issue.ID,
-			&issue.ProjectID,
-			&issue.Fingerprint,
-			&issue.Message,
-			&level,
-			&status,
-			&issue.FirstSeen,
-			&issue.LastSeen,
-			&issue.EventCount,
-			&issue.UserCount,
-			&issue.Environments,
-			&issue.Tags,
-		)
+		issue, err := scanIssue(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan issue: %w", err)
 		}
-
-		issue.Level = models.ErrorLevel(level)
-		issue.Status = models.IssueStatus(status)
-		issues = append(issues, issue)
+		issues = append(issues, *issue)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -168,108 +184,640 @@ func (r *IssuesRepository) GetIssues(ctx context.Context, query *models.IssuesQu
 	return response, nil
 }
 
+// issuesFilter returns the issuesSource filter and the WHERE clause selecting
+// the issues matching the filters of a query, and their arguments. Filters on
+// stored columns go in the source filter, filters on the counters and
+// timestamps taken from issue_stats in the WHERE clause.
+func issuesFilter(query *models.IssuesQuery) (string, string, []interface{}) {
+	// Build WHERE conditions
+	var filters, conditions []string
+	var args []interface{}
+	argIndex := 1
+
+	if query.ProjectID != nil {
+		filters = append(filters, fmt.Sprintf("project_id = $%d", argIndex))
+		args = append(args, *query.ProjectID)
+		argIndex++
+	}
+
+	if query.Status != nil && *query.Status != "" {
+		filters = append(filters, fmt.Sprintf("status = $%d", argIndex))
+		args = append(args, string(*query.Status))
+		argIndex++
+	}
+
+	if query.Substatus != nil && *query.Substatus != "" {
+		filters = append(filters, fmt.Sprintf("substatus = $%d", argIndex))
+		args = append(args, string(*query.Substatus))
+		argIndex++
+	}
+
+	if query.Level != nil && *query.Level != "" {
+		filters = append(filters, fmt.Sprintf("level = $%d", argIndex))
+		args = append(args, string(*query.Level))
+		argIndex++
+	}
+
+	if query.Environment != nil && *query.Environment != "" {
+		conditions = append(conditions, fmt.Sprintf("has(environments, $%d)", argIndex))
+		args = append(args, *query.Environment)
+		argIndex++
+	}
+
+	if query.Search != nil && *query.Search != "" {
+		filters = append(filters, fmt.Sprintf("positionCaseInsensitive(message, $%d) > 0", argIndex))
+		args = append(args, *query.Search)
+		argIndex++
+	}
+
+	if query.Assignee != nil {
+		filters = append(filters, fmt.Sprintf("assignee_type = $%d AND assignee_id = $%d", argIndex, argIndex+1))
+		args = append(args, string(query.Assignee.Type), assigneeID(query.Assignee))
+		argIndex += 2
+	}
+
+	// For issues, we filter by last_seen
+	if query.Window != nil {
+		conditions = append(conditions, fmt.Sprintf("last_seen >= $%d AND last_seen < $%d", argIndex, argIndex+1))
+		args = append(args, query.Window.Start, query.Window.End)
+		argIndex += 2
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return strings.Join(filters, " AND "), whereClause, args
+}
+
+// FindIssues returns the issues matching the filters of a query, up to a limit
+func (r *IssuesRepository) FindIssues(ctx context.Context, query *models.IssuesQuery, limit int) ([]*models.Issue, error) {
+	filter, whereClause, args := issuesFilter(query)
+
+	dataQuery := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		%s
+		ORDER BY last_seen DESC
+		LIMIT %d
+	`, issueColumns, issuesSource(filter), whereClause, limit)
+
+	rows, err := r.db.Query(ctx, dataQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query issues: %w", err)
+	}
+	defer rows.Close()
+
+	var issues []*models.Issue
+	for rows.Next() {
+		issue, err := scanIssue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan issue: %w", err)
+		}
+		issues = append(issues, issue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating issues: %w", err)
+	}
+
+	return issues, nil
+}
+
 // GetIssueByID retrieves a single issue by ID
 func (r *IssuesRepository) GetIssueByID(ctx context.Context, issueID string) (*models.Issue, error) {
-	query := `
-		SELECT
-			id, project_id, fingerprint, message, level, status,
-			first_seen, last_seen, event_count, user_count, environments, tags
-		FROM issues
-		WHERE id = $1
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
 		LIMIT 1
-	`
+	`, issueColumns, issuesSource("id = $1"))
 
-	row := r.db.QueryRow(ctx, query, issueID)
+	issue, err := scanIssue(r.db.QueryRow(ctx, query, issueID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue by ID: %w", err)
+	}
 
-	var issue models.Issue
-	var level, status string
+	return issue, nil
+}
 
-	err := row.Scan(
-		&issue.ID,
-		&issue.ProjectID,
-		&issue.Fingerprint,
-		&issue.Message,
-		&level,
-		&status,
-		&issue.FirstSeen,
-		&issue.LastSeen,
-		&issue.EventCount,
-		&issue.UserCount,
-		&issue.Environments,
-		&issue.Tags,
-	)
+// GetIssuesByFingerprints returns the issues of a project with the given
+// fingerprints, keyed by fingerprint. A fingerprint merged into an issue
+// returns that issue. Fingerprints without an issue are left out. When older
+// data holds several issues for a fingerprint the first one seen is returned.
+func (r *IssuesRepository) GetIssuesByFingerprints(ctx context.Context, projectID uuid.UUID, fingerprints []string) (map[string]*models.Issue, error) {
+	if len(fingerprints) == 0 {
+		return make(map[string]*models.Issue), nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		ORDER BY first_seen
+	`, issueColumns, issuesSource("project_id = $1 AND hasAny(arrayPushFront(merged_fingerprints, fingerprint), $2)"))
+
+	rows, err := r.db.Query(ctx, query, projectID, fingerprints)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get issue by ID: %w", err)
+		return nil, fmt.Errorf("failed to query issues by fingerprint: %w", err)
 	}
+	defer rows.Close()
 
-	issue.Level = models.ErrorLevel(level)
-	issue.Status = models.IssueStatus(status)
+	var matches []*models.Issue
+	for rows.Next() {
+		issue, err := scanIssue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan issue: %w", err)
+		}
+		matches = append(matches, issue)
+	}
 
-	return &issue, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating issues: %w", err)
+	}
+
+	return issuesByFingerprint(matches, fingerprints), nil
+}
+
+// issuesByFingerprint keys issues by the requested fingerprints they hold,
+// their own or merged ones. Issues are passed in the order they were first
+// seen, so the first issue of a fingerprint wins.
+func issuesByFingerprint(matches []*models.Issue, fingerprints []string) map[string]*models.Issue {
+	requested := make(map[string]bool, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		requested[fingerprint] = true
+	}
+
+	issues := make(map[string]*models.Issue)
+	for _, issue := range matches {
+		for _, fingerprint := range issue.Fingerprints() {
+			if _, exists := issues[fingerprint]; requested[fingerprint] && !exists {
+				issues[fingerprint] = issue
+			}
+		}
+	}
+	return issues
 }
 
-// UpdateIssueStatus updates the status of an issue
-func (r *IssuesRepository) UpdateIssueStatus(ctx context.Context, issueID string, status models.IssueStatus) error {
+// GetIssuesByIDs returns the issues with the given IDs, keyed by ID. Unknown
+// issues and issues merged into another one are left out.
+func (r *IssuesRepository) GetIssuesByIDs(ctx context.Context, issueIDs []string) (map[string]*models.Issue, error) {
+	issues := make(map[string]*models.Issue)
+	if len(issueIDs) == 0 {
+		return issues, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+	`, issueColumns, issuesSource("has($1, id)"))
+
+	rows, err := r.db.Query(ctx, query, issueIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query issues by ID: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		issue, err := scanIssue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan issue: %w", err)
+		}
+		issues[issue.ID] = issue
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating issues: %w", err)
+	}
+
+	return issues, nil
+}
+
+// GetMergedIssueIDs returns the IDs of the issues of a project that were
+// merged into the given issues
+func (r *IssuesRepository) GetMergedIssueIDs(ctx context.Context, projectID uuid.UUID, issueIDs []string) ([]string, error) {
+	if len(issueIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.Query(ctx, "SELECT id FROM issues FINAL WHERE project_id = $1 AND has($2, merged_into)", projectID, issueIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merged issues: %w", err)
+	}
+	defer rows.Close()
+
+	var mergedIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan merged issue: %w", err)
+		}
+		mergedIDs = append(mergedIDs, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating merged issues: %w", err)
+	}
+
+	return mergedIDs, nil
+}
+
+// MergeIssues merges issues of a project into a primary issue. The primary
+// takes over the fingerprints of the merged issues, including the ones merged
+// into them earlier, and those issues then point to the primary. The
+// mutations are waited for so the following batches send the events of the
+// merged fingerprints to the primary.
+func (r *IssuesRepository) MergeIssues(ctx context.Context, projectID uuid.UUID, primaryID string, issueIDs []string, fingerprints []string) error {
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{"mutations_sync": 1}))
+
+	// The primary takes over the fingerprints first, so events arriving in
+	// between always find an issue
 	query := `
 		ALTER TABLE issues
-		UPDATE status = $2, updated_at = now64()
-		WHERE id = $1
+		UPDATE
+			merged_fingerprints = arrayDistinct(arrayConcat(merged_fingerprints, $3)),
+			updated_at = now64()
+		WHERE project_id = $1 AND id = $2
+	`
+	if err := r.db.Exec(ctx, query, projectID, primaryID, fingerprints); err != nil {
+		return fmt.Errorf("failed to add merged fingerprints: %w", err)
+	}
+
+	query = `
+		ALTER TABLE issues
+		UPDATE
+			merged_into = $2,
+			merged_fingerprints = [],
+			updated_at = now64()
+		WHERE project_id = $1 AND (has($3, id) OR has($3, merged_into))
 	`
+	if err := r.db.Exec(ctx, query, projectID, primaryID, issueIDs); err != nil {
+		return fmt.Errorf("failed to merge issues: %w", err)
+	}
 
-	err := r.db.Exec(ctx, query, issueID, string(status))
+	return nil
+}
+
+// UnmergeFingerprints splits fingerprints out of the issue they were merged
+// into. The issues with those fingerprints are restored as unresolved issues
+// and receive their events again.
+func (r *IssuesRepository) UnmergeFingerprints(ctx context.Context, projectID uuid.UUID, primaryID string, fingerprints []string) error {
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{"mutations_sync": 1}))
+
+	query := `
+		ALTER TABLE issues
+		UPDATE
+			merged_into = '',
+			status = 'unresolved',
+			substatus = '',
+			resolution = '',
+			resolved_in_release = '',
+			resolved_at = NULL,
+			ignore_until = NULL,
+			ignore_until_event_count = 0,
+			ignore_until_user_count = 0,
+			ignore_event_rate = 0,
+			ignore_rate_window = 0,
+			updated_at = now64()
+		WHERE project_id = $1 AND merged_into = $2 AND has($3, fingerprint)
+	`
+	if err := r.db.Exec(ctx, query, projectID, primaryID, fingerprints); err != nil {
+		return fmt.Errorf("failed to restore unmerged issues: %w", err)
+	}
+
+	query = `
+		ALTER TABLE issues
+		UPDATE
+			merged_fingerprints = arrayFilter(f -> NOT has($3, f), merged_fingerprints),
+			updated_at = now64()
+		WHERE project_id = $1 AND id = $2
+	`
+	if err := r.db.Exec(ctx, query, projectID, primaryID, fingerprints); err != nil {
+		return fmt.Errorf("failed to remove merged fingerprints: %w", err)
+	}
+
+	return nil
+}
+
+// GetGroupingVersions returns the grouping algorithm version of the issues of
+// a project with the given fingerprints. Fingerprints without an issue are
+// left out.
+func (r *IssuesRepository) GetGroupingVersions(ctx context.Context, projectID uuid.UUID, fingerprints []string) (map[string]uint8, error) {
+	versions := make(map[string]uint8)
+	if len(fingerprints) == 0 {
+		return versions, nil
+	}
+
+	query := `
+		SELECT fingerprint, any(grouping_version)
+		FROM issues
+		WHERE project_id = $1 AND has($2, fingerprint)
+		GROUP BY fingerprint
+	`
+
+	rows, err := r.db.Query(ctx, query, projectID, fingerprints)
 	if err != nil {
+		return nil, fmt.Errorf("failed to query issue grouping versions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fingerprint string
+		var version uint8
+		if err := rows.Scan(&fingerprint, &version); err != nil {
+			return nil, fmt.Errorf("failed to scan issue grouping version: %w", err)
+		}
+		versions[fingerprint] = version
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating issue grouping versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// UpdateIssueStatus updates the status of an issue and clears its substatus.
+// Ignore conditions not part of the update are cleared.
+func (r *IssuesRepository) UpdateIssueStatus(ctx context.Context, issueID string, update *models.StatusUpdate) error {
+	if err := r.updateStatus(ctx, "id = $10", update, issueID); err != nil {
 		return fmt.Errorf("failed to update issue status: %w", err)
 	}
+	return nil
+}
+
+// UpdateIssuesStatus applies the same status update to issues of a project
+// with a single mutation
+func (r *IssuesRepository) UpdateIssuesStatus(ctx context.Context, projectID uuid.UUID, issueIDs []string, update *models.StatusUpdate) error {
+	if err := r.updateStatus(ctx, "project_id = $10 AND has($11, id)", update, projectID, issueIDs); err != nil {
+		return fmt.Errorf("failed to update issues status: %w", err)
+	}
+	return nil
+}
+
+// updateStatus applies a status update to the issues matching a condition,
+// whose arguments are numbered from $10. Resolved issues record the time they
+// were resolved.
+func (r *IssuesRepository) updateStatus(ctx context.Context, condition string, update *models.StatusUpdate, conditionArgs ...interface{}) error {
+	query := `
+		ALTER TABLE issues
+		UPDATE
+			status = $1,
+			substatus = '',
+			resolution = $2,
+			resolved_in_release = $3,
+			ignore_until = $4,
+			ignore_until_event_count = $5,
+			ignore_until_user_count = $6,
+			ignore_event_rate = $7,
+			ignore_rate_window = $8,
+			resolved_at = $9,
+			updated_at = now64()
+		WHERE ` + condition
+
+	ignore := update.Ignore
+	if ignore == nil {
+		ignore = &models.IgnoreConditions{}
+	}
+	// A nil *time.Time isn't bound as NULL, only an untyped nil is
+	var until interface{}
+	if ignore.Until != nil {
+		until = *ignore.Until
+	}
+	var resolvedAt interface{}
+	if update.Status == models.StatusResolved {
+		resolvedAt = time.Now()
+	}
+
+	args := []interface{}{
+		string(update.Status),
+		string(update.Resolution),
+		update.ResolvedInRelease,
+		until,
+		ignore.UntilEventCount,
+		ignore.UntilUserCount,
+		ignore.EventRate,
+		ignore.RateWindow,
+		resolvedAt,
+	}
+	return r.db.Exec(ctx, query, append(args, conditionArgs...)...)
+}
+
+// AssignIssues assigns issues of a project to a user or team, or unassigns
+// them when assignee is nil
+func (r *IssuesRepository) AssignIssues(ctx context.Context, projectID uuid.UUID, issueIDs []string, assignee *models.Assignee) error {
+	query := `
+		ALTER TABLE issues
+		UPDATE
+			assignee_type = $1,
+			assignee_id = $2,
+			updated_at = now64()
+		WHERE project_id = $3 AND has($4, id)
+	`
+
+	var assigneeType models.AssigneeType
+	if assignee != nil {
+		assigneeType = assignee.Type
+	}
+	if err := r.db.Exec(ctx, query, string(assigneeType), assigneeID(assignee), projectID, issueIDs); err != nil {
+		return fmt.Errorf("failed to assign issues: %w", err)
+	}
+	return nil
+}
+
+// assigneeID returns the stored ID of an assignee, which is empty for
+// unassigned issues
+func assigneeID(assignee *models.Assignee) string {
+	if assignee == nil || assignee.Type == "" {
+		return ""
+	}
+	return assignee.ID.String()
+}
+
+// DeleteIssues deletes issues of a project together with their events and
+// statistics, including the issues merged into them. New events with their
+// fingerprints create new issues.
+func (r *IssuesRepository) DeleteIssues(ctx context.Context, projectID uuid.UUID, issues []*models.Issue) error {
+	var issueIDs, fingerprints []string
+	for _, issue := range issues {
+		issueIDs = append(issueIDs, issue.ID)
+		fingerprints = append(fingerprints, issue.Fingerprints()...)
+	}
+	if len(issueIDs) == 0 {
+		return nil
+	}
+
+	// Events go first so a failure leaves the issues around to retry with
+	for _, table := range []string{"error_events", "issue_stats", "event_rollups_hourly", "event_rollups_daily"} {
+		query := "ALTER TABLE " + table + " DELETE WHERE project_id = $1 AND has($2, fingerprint)"
+		if err := r.db.Exec(ctx, query, projectID, fingerprints); err != nil {
+			return fmt.Errorf("failed to delete issue data from %s: %w", table, err)
+		}
+	}
+
+	// Waited for so the following batches don't see the deleted issues
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{"mutations_sync": 1}))
+	query := `
+		ALTER TABLE issues
+		DELETE WHERE project_id = $1 AND (has($2, id) OR has($2, merged_into))
+	`
+	if err := r.db.Exec(ctx, query, projectID, issueIDs); err != nil {
+		return fmt.Errorf("failed to delete issues: %w", err)
+	}
+
+	return nil
+}
+
+// MarkRegressed reopens a resolved issue that received new events. The
+// mutation is waited for so the following batches see the issue unresolved
+// and don't report the regression again.
+func (r *IssuesRepository) MarkRegressed(ctx context.Context, issueID string, regressedAt time.Time, release string) error {
+	query := `
+		ALTER TABLE issues
+		UPDATE
+			status = 'unresolved',
+			substatus = $2,
+			resolution = '',
+			resolved_in_release = '',
+			resolved_at = NULL,
+			regressed_at = $3,
+			regressed_in_release = $4,
+			updated_at = now64()
+		WHERE id = $1 AND status = 'resolved'
+	`
+
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{"mutations_sync": 1}))
+	if err := r.db.Exec(ctx, query, issueID, string(models.SubstatusRegressed), regressedAt, release); err != nil {
+		return fmt.Errorf("failed to mark issue regressed: %w", err)
+	}
 
 	return nil
 }
 
-// GetIssueTimeSeries retrieves time series data for an issue
-func (r *IssuesRepository) GetIssueTimeSeries(ctx context.Context, issueID string, timeRange string) ([]map[string]interface{}, error) {
-	// First get the issue to get its fingerprint
+// Unignore reopens an ignored issue whose ignore condition tripped. The
+// mutation is waited for so the following batches see the issue unresolved.
+// It reports whether the issue was still ignored, which is false when
+// another replica or the sweeper reopened it first. The check and the
+// mutation are separate steps, so replicas checking the same issue at once
+// may both reopen it and both report true.
+func (r *IssuesRepository) Unignore(ctx context.Context, issueID string) (bool, error) {
+	var ignored uint64
+	err := r.db.QueryRow(ctx, "SELECT count() FROM issues FINAL WHERE id = $1 AND status = 'ignored'", issueID).Scan(&ignored)
+	if err != nil {
+		return false, fmt.Errorf("failed to check issue status: %w", err)
+	}
+	if ignored == 0 {
+		return false, nil
+	}
+
+	query := `
+		ALTER TABLE issues
+		UPDATE
+			status = 'unresolved',
+			substatus = $2,
+			ignore_until = NULL,
+			ignore_until_event_count = 0,
+			ignore_until_user_count = 0,
+			ignore_event_rate = 0,
+			ignore_rate_window = 0,
+			updated_at = now64()
+		WHERE id = $1 AND status = 'ignored'
+	`
+
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{"mutations_sync": 1}))
+	if err := r.db.Exec(ctx, query, issueID, string(models.SubstatusEscalating)); err != nil {
+		return false, fmt.Errorf("failed to unignore issue: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetConditionallyIgnoredIssues returns the ignored issues of every project
+// that have ignore conditions
+func (r *IssuesRepository) GetConditionallyIgnoredIssues(ctx context.Context) ([]*models.Issue, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+	`, issueColumns, issuesSource(`status = 'ignored' AND (
+			ignore_until IS NOT NULL OR ignore_until_event_count > 0 OR
+			ignore_until_user_count > 0 OR ignore_event_rate > 0
+		)`))
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ignored issues: %w", err)
+	}
+	defer rows.Close()
+
+	var issues []*models.Issue
+	for rows.Next() {
+		issue, err := scanIssue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan issue: %w", err)
+		}
+		issues = append(issues, issue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ignored issues: %w", err)
+	}
+
+	return issues, nil
+}
+
+// GetIssueTimeSeries retrieves time series data for an issue. Every bucket of
+// the window is returned, buckets without events with a count of zero.
+func (r *IssuesRepository) GetIssueTimeSeries(ctx context.Context, issueID string, window *models.TimeWindow) ([]map[string]interface{}, error) {
+	// First get the issue to get its fingerprints
 	issue, err := r.GetIssueByID(ctx, issueID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get issue: %w", err)
 	}
 
-	timeCondition := getTimeRangeCondition(timeRange)
+	buckets := window.Buckets()
+	if len(buckets) == 0 {
+		return []map[string]interface{}{}, nil
+	}
+	source := seriesSource(window, buckets, time.Now())
 
 	query := fmt.Sprintf(`
 		SELECT
-			toStartOfHour(timestamp) as timestamp,
-			count() as count
-		FROM error_events
-		WHERE fingerprint = $1 AND %s
-		GROUP BY timestamp
-		ORDER BY timestamp
-	`, timeCondition)
-
-	rows, err := r.db.Query(ctx, query, issue.Fingerprint)
+			%s as time_bucket,
+			%s as count
+		FROM %s
+		WHERE project_id = $1 AND has($2, fingerprint) AND %s
+		GROUP BY time_bucket
+	`, source.bucketExpression(window), source.eventCount, source.table, source.timeCondition(3))
+
+	rows, err := r.db.Query(ctx, query, issue.ProjectID, issue.Fingerprints(), buckets[0], window.End)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query time series: %w", err)
 	}
 	defer rows.Close()
 
-	var timeSeries []map[string]interface{}
+	counts := make(map[int64]uint64)
 	for rows.Next() {
-		var timestamp string
+		var timestamp time.Time
 		var count uint64
 
 		err := rows.Scan(&timestamp, &count)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan time series: %w", err)
 		}
-
-		timeSeries = append(timeSeries, map[string]interface{}{
-			"timestamp": timestamp,
-			"count":     count,
-		})
+		counts[timestamp.Unix()] = count
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating time series: %w", err)
 	}
 
+	timeSeries := make([]map[string]interface{}, 0, len(buckets))
+	for _, bucket := range buckets {
+		timeSeries = append(timeSeries, map[string]interface{}{
+			"timestamp": bucket,
+			"count":     counts[bucket.Unix()],
+		})
+	}
+
 	return timeSeries, nil
 }
 
@@ -290,8 +838,8 @@ func (r *IssuesRepository) getIssuesStats(ctx context.Context, projectID *uuid.U
 				countIf(status = 'unresolved') as unresolved_issues,
 				countIf(status = 'resolved') as resolved_issues,
 				countIf(status = 'ignored') as ignored_issues
-			FROM issues
-			WHERE project_id = $1
+			FROM issues FINAL
+			WHERE project_id = $1 AND merged_into = ''
 		`
 		args = append(args, *projectID)
 	} else {
@@ -301,7 +849,8 @@ func (r *IssuesRepository) getIssuesStats(ctx context.Context, projectID *uuid.U
 				countIf(status = 'unresolved') as unresolved_issues,
 				countIf(status = 'resolved') as resolved_issues,
 				countIf(status = 'ignored') as ignored_issues
-			FROM issues
+			FROM issues FINAL
+			WHERE merged_into = ''
 		`
 	}
 
@@ -332,10 +881,16 @@ func (r *IssuesRepository) InsertIssue(ctx context.Context, issue *models.Issue)
 	query := `
 		INSERT INTO issues (
 			id, project_id, fingerprint, message, level, status,
-			first_seen, last_seen, event_count, user_count, environments, tags, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			first_seen, last_seen, event_count, user_count, environments, tags, updated_at,
+			grouping_version, assignee_type, assignee_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
+	var assigneeType models.AssigneeType
+	if issue.Assignee != nil {
+		assigneeType = issue.Assignee.Type
+	}
+
 	return r.db.Exec(ctx, query,
 		issue.ID,
 		issue.ProjectID,
@@ -350,28 +905,66 @@ func (r *IssuesRepository) InsertIssue(ctx context.Context, issue *models.Issue)
 		issue.Environments,
 		issue.Tags,
 		issue.UpdatedAt,
+		issue.GroupingVersion,
+		string(assigneeType),
+		assigneeID(issue.Assignee),
 	)
 }
 
-// UpdateIssue updates an existing issue in ClickHouse
-func (r *IssuesRepository) UpdateIssue(ctx context.Context, issue *models.Issue) error {
-	query := `
-		ALTER TABLE issues
-		UPDATE
-			last_seen = $2,
-			event_count = $3,
-			user_count = $4,
-			environments = $5,
-			updated_at = $6
-		WHERE id = $1
-	`
+// scanIssue scans a row of issueColumns
+func scanIssue(row rowScanner) (*models.Issue, error) {
+	var issue models.Issue
+	var level, status, substatus, resolution string
+	var ignore models.IgnoreConditions
+	var assigneeType, assigneeID string
 
-	return r.db.Exec(ctx, query,
-		issue.ID,
-		issue.LastSeen,
-		issue.EventCount,
-		issue.UserCount,
-		issue.Environments,
-		issue.UpdatedAt,
+	err := row.Scan(
+		&issue.ID,
+		&issue.ProjectID,
+		&issue.Fingerprint,
+		&issue.Message,
+		&level,
+		&status,
+		&substatus,
+		&resolution,
+		&issue.ResolvedInRelease,
+		&issue.ResolvedAt,
+		&ignore.Until,
+		&ignore.UntilEventCount,
+		&ignore.UntilUserCount,
+		&ignore.EventRate,
+		&ignore.RateWindow,
+		&issue.FirstSeen,
+		&issue.LastSeen,
+		&issue.EventCount,
+		&issue.UserCount,
+		&issue.Environments,
+		&issue.Tags,
+		&issue.GroupingVersion,
+		&issue.RegressedAt,
+		&issue.RegressedInRelease,
+		&issue.MergedFingerprints,
+		&assigneeType,
+		&assigneeID,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	issue.Level = models.ErrorLevel(level)
+	issue.Status = models.IssueStatus(status)
+	issue.Substatus = models.IssueSubstatus(substatus)
+	issue.Resolution = models.IssueResolution(resolution)
+	if !ignore.Empty() {
+		issue.Ignore = &ignore
+	}
+	if assigneeType != "" {
+		id, err := uuid.Parse(assigneeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid assignee ID %q: %w", assigneeID, err)
+		}
+		issue.Assignee = &models.Assignee{Type: models.AssigneeType(assigneeType), ID: id}
+	}
+
+	return &issue, nil
 }
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestIssuesByFingerprint(t *testing.T) {
+	primary := &models.Issue{ID: "primary", Fingerprint: "a", MergedFingerprints: []string{"m"}}
+	other := &models.Issue{ID: "other", Fingerprint: "b"}
+	duplicate := &models.Issue{ID: "duplicate", Fingerprint: "a"}
+
+	issues := issuesByFingerprint([]*models.Issue{primary, other, duplicate}, []string{"a", "m", "b", "x"})
+
+	expected := map[string]string{"a": "primary", "m": "primary", "b": "other"}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d fingerprints, got %v", len(expected), issues)
+	}
+	for fingerprint, id := range expected {
+		if issue := issues[fingerprint]; issue == nil || issue.ID != id {
+			t.Errorf("Expected fingerprint %s to return issue %s, got %+v", fingerprint, id, issue)
+		}
+	}
+
+	if issues := issuesByFingerprint([]*models.Issue{primary}, []string{"b"}); len(issues) != 0 {
+		t.Errorf("Expected fingerprints that weren't requested to be left out, got %v", issues)
+	}
+}
+
+func TestIssuesSource_FiltersSubqueries(t *testing.T) {
+	source := issuesSource("project_id = $1")
+
+	// The issues, the fingerprints joined with issue_stats and the stats
+	// themselves are all limited to the filtered issues
+	if count := strings.Count(source, "WHERE merged_into = '' AND (project_id = $1)"); count != 3 {
+		t.Errorf("Expected the filter in 3 subqueries, got %d:\n%s", count, source)
+	}
+	if !strings.Contains(source, "WHERE (st.project_id, st.fingerprint) IN (") {
+		t.Errorf("Expected issue_stats to be limited to the fingerprints of the filtered issues:\n%s", source)
+	}
+
+	if count := strings.Count(issuesSource(""), "WHERE merged_into = '' AND (1)"); count != 3 {
+		t.Errorf("Expected every issue without a filter, got %d subqueries", count)
+	}
+}
+
+func TestIssuesSource_CountersFromStats(t *testing.T) {
+	source := issuesSource("id = $1")
+
+	// Counters and timestamps come from issue_stats and fall back to the
+	// issue row until its events are aggregated
+	for _, column := range []string{"first_seen", "last_seen", "event_count", "user_count", "environments"} {
+		expected := "if(s.event_count = 0, i." + column + ", s." + column + ") AS " + column
+		if !strings.Contains(source, expected) {
+			t.Errorf("Expected %s from issue_stats, missing %q", column, expected)
+		}
+	}
+	for _, aggregate := range []string{
+		"min(st.first_seen) AS first_seen",
+		"max(st.last_seen) AS last_seen",
+		"sum(st.event_count) AS event_count",
+		"uniqMerge(st.users) AS user_count",
+	} {
+		if !strings.Contains(source, aggregate) {
+			t.Errorf("Expected the stats to aggregate %q", aggregate)
+		}
+	}
+	if !strings.Contains(source, "arrayJoin(arrayPushFront(merged_fingerprints, fingerprint))") {
+		t.Error("Expected the stats of merged fingerprints to count for the issue")
+	}
+}
+
+func TestIssuesFilter_SplitsStoredAndAggregatedColumns(t *testing.T) {
+	projectID := uuid.New()
+	status := models.StatusUnresolved
+	environment := "production"
+	now := time.Now()
+
+	filter, where, args := issuesFilter(&models.IssuesQuery{
+		ProjectID:   &projectID,
+		Status:      &status,
+		Environment: &environment,
+		Window:      &models.TimeWindow{Start: now.Add(-time.Hour), End: now},
+	})
+
+	if filter != "project_id = $1 AND status = $2" {
+		t.Errorf("Expected the stored columns in the source filter, got %q", filter)
+	}
+	if where != "WHERE has(environments, $3) AND last_seen >= $4 AND last_seen < $5" {
+		t.Errorf("Expected the aggregated columns in the WHERE clause, got %q", where)
+	}
+	if len(args) != 5 || args[0] != projectID {
+		t.Errorf("Expected 5 arguments starting with the project, got %v", args)
+	}
+
+	if filter, where, args := issuesFilter(&models.IssuesQuery{}); filter != "" || where != "" || len(args) != 0 {
+		t.Errorf("Expected no filters, got %q %q %v", filter, where, args)
+	}
+}
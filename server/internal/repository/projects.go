@@ -231,6 +231,47 @@ func (r *ProjectsRepository) Update(ctx context.Context, project *models.Project
 	return nil
 }
 
+// UpdateSetting sets a single key of a project's settings, or removes it when
+// value is nil. Other settings and columns are left untouched, so concurrent
+// updates of different keys don't overwrite each other.
+func (r *ProjectsRepository) UpdateSetting(ctx context.Context, projectID uuid.UUID, key string, value interface{}) error {
+	query := `
+		UPDATE projects
+		SET settings = COALESCE(settings, '{}'::jsonb) - $2::text, updated_at = NOW()
+		WHERE id = $1
+	`
+	args := []interface{}{projectID, key}
+
+	if value != nil {
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal project setting: %w", err)
+		}
+		query = `
+			UPDATE projects
+			SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), ARRAY[$2::text], $3::jsonb), updated_at = NOW()
+			WHERE id = $1
+		`
+		args = append(args, valueJSON)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update project setting: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("project not found")
+	}
+
+	return nil
+}
+
 // Delete deletes a project
 func (r *ProjectsRepository) Delete(ctx context.Context, projectID uuid.UUID) error {
 	query := `DELETE FROM projects WHERE id = $1`
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"server/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// eventSource is a table error event analytics are read from: the raw events
+// or a rollup, a pre-aggregated copy maintained by a materialized view
+type eventSource struct {
+	table       string
+	granularity time.Duration // Bucket size of rollups, zero for raw events
+	timeColumn  string
+	eventCount  string // Aggregate expression counting events
+	errorCount  string // Aggregate expression counting error level events
+	userCount   string // Aggregate expression counting unique users
+	userState   string // Aggregate expression returning the uniq state of the users
+	lastSeen    string // Aggregate expression returning the latest event time
+}
+
+var (
+	rawEvents = eventSource{
+		table:      "error_events",
+		timeColumn: "timestamp",
+		eventCount: "count()",
+		errorCount: "countIf(level = 'error')",
+		userCount:  "uniq(" + userKeyExpression + ")",
+		userState:  "uniqState(" + userKeyExpression + ")",
+		lastSeen:   "max(timestamp)",
+	}
+	hourlyRollup = eventSource{
+		table:       "event_rollups_hourly",
+		granularity: time.Hour,
+		timeColumn:  "bucket",
+		eventCount:  "sum(event_count)",
+		errorCount:  "sumIf(event_count, level = 'error')",
+		userCount:   "uniqMerge(users)",
+		userState:   "uniqMergeState(users)",
+		lastSeen:    "max(last_seen)",
+	}
+	dailyRollup = eventSource{
+		table:       "event_rollups_daily",
+		granularity: 24 * time.Hour,
+		timeColumn:  "bucket",
+		eventCount:  "sum(event_count)",
+		errorCount:  "sumIf(event_count, level = 'error')",
+		userCount:   "uniqMerge(users)",
+		userState:   "uniqMergeState(users)",
+		lastSeen:    "max(last_seen)",
+	}
+)
+
+// rollups are tried from the coarsest to the finest
+var rollups = []eventSource{dailyRollup, hourlyRollup}
+
+// maxHourlyRange is the longest preset time range read from the hourly
+// rollup. Longer ranges use daily buckets to keep the number of rows low.
+const maxHourlyRange = 7 * 24 * time.Hour
+
+// userKeyExpression identifies the user of an event by ID, then email, then IP
+// address. It is NULL for anonymous events, which uniq skips. The materialized
+// views use the same expression.
+const userKeyExpression = `coalesce(
+	concat('id:', nullIf(user_id, '')),
+	concat('email:', nullIf(user_email, '')),
+	concat('ip:', nullIf(user_ip, ''))
+)`
+
+// statsPart is a time range of a time window read from a single source
+type statsPart struct {
+	source eventSource
+	start  time.Time
+	end    time.Time
+}
+
+// statsParts returns the sources for totals over a time window. Presets such
+// as "last 24 hours" read the whole buckets of the window from a rollup and
+// the partial bucket it starts in from raw events, so the totals are exact.
+// Explicit windows read a rollup only when both ends fall on its bucket
+// boundaries, and raw events otherwise.
+func statsParts(window *models.TimeWindow, now time.Time) []statsPart {
+	if window.Preset != "" {
+		source := hourlyRollup
+		if window.End.Sub(window.Start) > maxHourlyRange {
+			source = dailyRollup
+		}
+
+		head := window.Start
+		if !aligned(head, source.granularity) {
+			head = head.Truncate(source.granularity).Add(source.granularity)
+		}
+		if !head.Before(window.End) {
+			return []statsPart{{rawEvents, window.Start, window.End}}
+		}
+
+		var parts []statsPart
+		if head.After(window.Start) {
+			parts = append(parts, statsPart{rawEvents, window.Start, head})
+		}
+		return append(parts, statsPart{source, head, window.End})
+	}
+
+	for _, source := range rollups {
+		if source.covers(window, now) && aligned(window.Start, source.granularity) {
+			return []statsPart{{source, window.Start, window.End}}
+		}
+	}
+	return []statsPart{{rawEvents, window.Start, window.End}}
+}
+
+// statsQuery returns the query of a project's totals over the parts of a
+// time window and its arguments. Each part is aggregated by issue, and the
+// user states are merged so users seen in several parts count once. The
+// aliases differ from the rollup columns they aggregate.
+func statsQuery(projectID uuid.UUID, parts []statsPart) (string, []interface{}) {
+	args := []interface{}{projectID}
+	selects := make([]string, len(parts))
+	for i, part := range parts {
+		selects[i] = fmt.Sprintf(`
+			SELECT
+				fingerprint,
+				%s AS events,
+				%s AS errors,
+				%s AS user_state,
+				%s AS latest
+			FROM %s
+			WHERE project_id = $1 AND %s
+			GROUP BY fingerprint`,
+			part.source.eventCount, part.source.errorCount, part.source.userState, part.source.lastSeen,
+			part.source.table, part.source.timeCondition(len(args)+1))
+		args = append(args, part.start, part.end)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			'%s' as project_id,
+			sum(events) as total_events,
+			uniq(fingerprint) as total_issues,
+			sum(errors) as error_events,
+			uniqMerge(user_state) as affected_users,
+			max(latest) as last_event
+		FROM (%s
+		)
+	`, projectID.String(), strings.Join(selects, "\n\t\t\tUNION ALL"))
+	return query, args
+}
+
+// seriesSource returns the source for a time series over a time window. A
+// rollup is used when every bucket of the series starts on one of its bucket
+// boundaries, which depends on the interval and the time zone.
+func seriesSource(window *models.TimeWindow, buckets []time.Time, now time.Time) eventSource {
+	for _, source := range rollups {
+		if window.Interval%source.granularity != 0 || !source.covers(window, now) {
+			continue
+		}
+
+		usable := true
+		for _, bucket := range buckets {
+			if !aligned(bucket, source.granularity) {
+				usable = false
+				break
+			}
+		}
+		if usable {
+			return source
+		}
+	}
+	return rawEvents
+}
+
+// covers reports whether the rollup has no events after the end of the window
+// in the window's last bucket
+func (s eventSource) covers(window *models.TimeWindow, now time.Time) bool {
+	return aligned(window.End, s.granularity) || !window.End.Before(now)
+}
+
+// timeCondition returns a condition selecting rows from start to end, using
+// the argument placeholders $argIndex and $argIndex+1
+func (s eventSource) timeCondition(argIndex int) string {
+	return fmt.Sprintf("%s >= $%d AND %s < $%d", s.timeColumn, argIndex, s.timeColumn, argIndex+1)
+}
+
+// bucketExpression returns an expression rounding the time column down to the
+// buckets of a time window
+func (s eventSource) bucketExpression(window *models.TimeWindow) string {
+	amount, unit := int64(window.Interval/time.Minute), "MINUTE"
+	switch {
+	case window.Interval%(24*time.Hour) == 0:
+		amount, unit = int64(window.Interval/(24*time.Hour)), "DAY"
+	case window.Interval%time.Hour == 0:
+		amount, unit = int64(window.Interval/time.Hour), "HOUR"
+	}
+	// The location name was validated when the window was parsed
+	return fmt.Sprintf("toStartOfInterval(%s, INTERVAL %d %s, '%s')", s.timeColumn, amount, unit, window.Location.String())
+}
+
+// aligned reports whether a time is a multiple of the granularity since the
+// Unix epoch. Zero granularity, for raw events, aligns with every time.
+func aligned(t time.Time, granularity time.Duration) bool {
+	return granularity == 0 || t.UnixNano()%int64(granularity) == 0
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// clickhouseMigration returns the up section of a ClickHouse migration with
+// all whitespace removed, so statements compare regardless of formatting
+func clickhouseMigration(t *testing.T, name string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join("..", "..", "..", "migrations", "clickhouse", name))
+	if err != nil {
+		t.Fatalf("Failed to read migration: %v", err)
+	}
+	up, _, _ := strings.Cut(string(content), "-- +goose Down")
+	return compactSQL(up)
+}
+
+func compactSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), "")
+}
+
+// uniqStateArguments returns the arguments of every uniqState call
+func uniqStateArguments(sql string) []string {
+	var arguments []string
+	for {
+		start := strings.Index(sql, "uniqState(")
+		if start < 0 {
+			return arguments
+		}
+		sql = sql[start+len("uniqState("):]
+
+		depth := 1
+		end := 0
+		for ; end < len(sql) && depth > 0; end++ {
+			switch sql[end] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+		}
+		arguments = append(arguments, sql[:end-1])
+		sql = sql[end:]
+	}
+}
+
+func TestUserKeyExpression_MatchesMaterializedViews(t *testing.T) {
+	expected := compactSQL(userKeyExpression)
+
+	migrations := map[string]int{
+		"20261016000004_add_issue_users.sql":   2, // issue_stats view and backfill
+		"20261016000005_add_event_rollups.sql": 4, // Hourly and daily views and backfills
+	}
+	for name, count := range migrations {
+		arguments := uniqStateArguments(clickhouseMigration(t, name))
+		if len(arguments) != count {
+			t.Errorf("%s: expected %d user states, got %d", name, count, len(arguments))
+		}
+		for _, argument := range arguments {
+			if argument != expected {
+				t.Errorf("%s: expected users to be keyed by userKeyExpression, got %s", name, argument)
+			}
+		}
+	}
+}
+
+func TestIssueUsersBackfill_AddsUsersOnly(t *testing.T) {
+	migration := clickhouseMigration(t, "20261016000004_add_issue_users.sql")
+
+	_, backfill, found := strings.Cut(migration, "INSERTINTOissue_stats")
+	if !found {
+		t.Fatal("Expected the migration to backfill issue_stats")
+	}
+
+	// The backfill rows add the users of stored events without adding to the
+	// event counts; first and last seen and environments repeat the values
+	// that are already aggregated
+	expected := "SELECTproject_id,fingerprint,min(timestamp),max(timestamp),toUInt64(0),groupUniqArray(environment),uniqState("
+	if !strings.HasPrefix(backfill, expected) {
+		t.Errorf("Expected the backfill to insert users with an event count of zero, got %s", backfill)
+	}
+	if !strings.HasSuffix(backfill, "FROMerror_eventsGROUPBYproject_id,fingerprint;") {
+		t.Errorf("Expected the backfill to aggregate every issue of the stored events, got %s", backfill)
+	}
+}
+
+func TestEventSources_CountUsersAlike(t *testing.T) {
+	if rawEvents.userCount != "uniq("+userKeyExpression+")" || rawEvents.userState != "uniqState("+userKeyExpression+")" {
+		t.Errorf("Expected raw events to count users by userKeyExpression, got %s and %s", rawEvents.userCount, rawEvents.userState)
+	}
+	for _, source := range rollups {
+		if source.userCount != "uniqMerge(users)" || source.userState != "uniqMergeState(users)" {
+			t.Errorf("Expected %s to merge the user states, got %s and %s", source.table, source.userCount, source.userState)
+		}
+	}
+}
+
+func TestStatsParts(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 37, 12, 0, time.UTC)
+	hour := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+
+	preset := func(name string, end time.Time) *models.TimeWindow {
+		return &models.TimeWindow{Start: end.Add(-models.TimeRanges[name]), End: end, Preset: name}
+	}
+	sevenDaysAgo := now.Add(-7 * 24 * time.Hour)
+	thirtyDaysAgo := now.Add(-30 * 24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		window   *models.TimeWindow
+		expected []statsPart
+	}{
+		// Presets read the partial bucket they start in from raw events
+		{"1h preset", preset("1h", now), []statsPart{
+			{rawEvents, now.Add(-time.Hour), hour.Add(time.Hour)},
+			{hourlyRollup, hour.Add(time.Hour), now},
+		}},
+		{"24h preset", preset("24h", now), []statsPart{
+			{rawEvents, now.Add(-24 * time.Hour), day.Add(11 * time.Hour)},
+			{hourlyRollup, day.Add(11 * time.Hour), now},
+		}},
+		{"7d preset", preset("7d", now), []statsPart{
+			{rawEvents, sevenDaysAgo, sevenDaysAgo.Truncate(time.Hour).Add(time.Hour)},
+			{hourlyRollup, sevenDaysAgo.Truncate(time.Hour).Add(time.Hour), now},
+		}},
+		{"30d preset", preset("30d", now), []statsPart{
+			{rawEvents, thirtyDaysAgo, day.Add(-28 * 24 * time.Hour)},
+			{dailyRollup, day.Add(-28 * 24 * time.Hour), now},
+		}},
+		{"preset on a boundary", preset("1h", hour.Add(time.Hour)), []statsPart{
+			{hourlyRollup, hour, hour.Add(time.Hour)},
+		}},
+
+		// Explicit windows use a rollup only when its buckets fit the window
+		{"days until now", &models.TimeWindow{Start: day, End: now}, []statsPart{{dailyRollup, day, now}}},
+		{"past days", &models.TimeWindow{Start: day.Add(-24 * time.Hour), End: day}, []statsPart{{dailyRollup, day.Add(-24 * time.Hour), day}}},
+		{"hours until now", &models.TimeWindow{Start: hour, End: now}, []statsPart{{hourlyRollup, hour, now}}},
+		{"past hours", &models.TimeWindow{Start: day.Add(time.Hour), End: hour}, []statsPart{{hourlyRollup, day.Add(time.Hour), hour}}},
+		{"days ending mid-day", &models.TimeWindow{Start: day, End: hour}, []statsPart{{hourlyRollup, day, hour}}},
+		{"start after a boundary", &models.TimeWindow{Start: hour.Add(time.Second), End: now}, []statsPart{{rawEvents, hour.Add(time.Second), now}}},
+		{"end after a boundary", &models.TimeWindow{Start: day, End: hour.Add(time.Second)}, []statsPart{{rawEvents, day, hour.Add(time.Second)}}},
+		{"end in the future", &models.TimeWindow{Start: hour, End: now.Add(time.Minute)}, []statsPart{{hourlyRollup, hour, now.Add(time.Minute)}}},
+	}
+
+	for _, tt := range tests {
+		parts := statsParts(tt.window, now)
+		if len(parts) != len(tt.expected) {
+			t.Errorf("%s: expected %d parts, got %+v", tt.name, len(tt.expected), parts)
+			continue
+		}
+		for i, part := range parts {
+			expected := tt.expected[i]
+			if part.source.table != expected.source.table || !part.start.Equal(expected.start) || !part.end.Equal(expected.end) {
+				t.Errorf("%s: expected %s from %v to %v, got %s from %v to %v", tt.name,
+					expected.source.table, expected.start, expected.end, part.source.table, part.start, part.end)
+			}
+		}
+	}
+}
+
+func TestStatsQuery(t *testing.T) {
+	projectID := uuid.New()
+	start := time.Date(2026, 10, 16, 9, 37, 12, 0, time.UTC)
+	head := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 10, 16, 10, 37, 12, 0, time.UTC)
+
+	query, args := statsQuery(projectID, []statsPart{{rawEvents, start, head}, {hourlyRollup, head, end}})
+
+	if len(args) != 5 || args[0] != projectID || args[1] != start || args[2] != head || args[3] != head || args[4] != end {
+		t.Errorf("Expected the project and the range of each part, got %v", args)
+	}
+	for _, expected := range []string{
+		"FROM error_events\n\t\t\tWHERE project_id = $1 AND timestamp >= $2 AND timestamp < $3",
+		"FROM event_rollups_hourly\n\t\t\tWHERE project_id = $1 AND bucket >= $4 AND bucket < $5",
+		"UNION ALL",
+		"uniqState(" + userKeyExpression + ") AS user_state",
+		"uniqMergeState(users) AS user_state",
+		// Users and issues seen in both parts count once
+		"uniqMerge(user_state) as affected_users",
+		"uniq(fingerprint) as total_issues",
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("Expected the query to contain %q:\n%s", expected, query)
+		}
+	}
+}
+
+func TestSeriesSource(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 37, 12, 0, time.UTC)
+	day := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		window   *models.TimeWindow
+		expected eventSource
+	}{
+		{"daily buckets", &models.TimeWindow{Start: day, End: now, Interval: 24 * time.Hour, Location: time.UTC}, dailyRollup},
+		{"hourly buckets", &models.TimeWindow{Start: day, End: now, Interval: time.Hour, Location: time.UTC}, hourlyRollup},
+		{"6 hour buckets", &models.TimeWindow{Start: day, End: now, Interval: 6 * time.Hour, Location: time.UTC}, hourlyRollup},
+		{"minute buckets", &models.TimeWindow{Start: day, End: now, Interval: 5 * time.Minute, Location: time.UTC}, rawEvents},
+		{"past days", &models.TimeWindow{Start: day, End: day.Add(3 * 24 * time.Hour), Interval: 24 * time.Hour, Location: time.UTC}, dailyRollup},
+		{"end after a boundary", &models.TimeWindow{Start: day, End: day.Add(time.Hour + time.Second), Interval: time.Hour, Location: time.UTC}, rawEvents},
+
+		// Local midnight falls on an hour boundary but not on a UTC day boundary
+		{"days in UTC+2", &models.TimeWindow{Start: day, End: now, Interval: 24 * time.Hour, Location: time.FixedZone("UTC+2", 2*60*60)}, hourlyRollup},
+		{"hours in UTC+5:30", &models.TimeWindow{Start: day, End: now, Interval: time.Hour, Location: time.FixedZone("UTC+5:30", 330*60)}, rawEvents},
+	}
+
+	for _, tt := range tests {
+		if source := seriesSource(tt.window, tt.window.Buckets(), now); source.table != tt.expected.table {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected.table, source.table)
+		}
+	}
+}
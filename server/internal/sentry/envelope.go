@@ -0,0 +1,141 @@
+// Package sentry implements the parts of the Sentry ingestion protocol needed
+// to accept events from unmodified Sentry SDKs.
+package sentry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Item types that carry error events
+const (
+	ItemTypeEvent = "event"
+	ItemTypeError = "error"
+)
+
+// EnvelopeHeader is the first line of an envelope
+type EnvelopeHeader struct {
+	EventID string `json:"event_id,omitempty"`
+	DSN     string `json:"dsn,omitempty"`
+	SentAt  string `json:"sent_at,omitempty"`
+}
+
+// ItemHeader describes a single envelope item
+type ItemHeader struct {
+	Type        string `json:"type"`
+	Length      *int   `json:"length,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+}
+
+// Item is a single envelope item with its raw payload
+type Item struct {
+	Header  ItemHeader
+	Payload []byte
+}
+
+// Envelope is a parsed Sentry envelope
+type Envelope struct {
+	Header EnvelopeHeader
+	Items  []Item
+}
+
+// ParseEnvelope parses the newline-delimited Sentry envelope format:
+//
+//	{"event_id":"...","dsn":"..."}
+//	{"type":"event","length":41}
+//	{"message":"hello","level":"error"}
+//
+// Items without an explicit length run until the next newline.
+func ParseEnvelope(r io.Reader) (*Envelope, error) {
+	reader := bufio.NewReader(r)
+
+	headerLine, err := readLine(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read envelope header: %w", err)
+	}
+
+	envelope := &Envelope{}
+	if len(bytes.TrimSpace(headerLine)) > 0 {
+		if err := json.Unmarshal(headerLine, &envelope.Header); err != nil {
+			return nil, fmt.Errorf("invalid envelope header: %w", err)
+		}
+	}
+
+	for {
+		itemLine, err := readLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read item header: %w", err)
+		}
+
+		// Tolerate blank lines between items
+		if len(bytes.TrimSpace(itemLine)) == 0 {
+			continue
+		}
+
+		var item Item
+		if err := json.Unmarshal(itemLine, &item.Header); err != nil {
+			return nil, fmt.Errorf("invalid item header: %w", err)
+		}
+
+		if item.Header.Length != nil {
+			if *item.Header.Length < 0 {
+				return nil, fmt.Errorf("invalid item length: %d", *item.Header.Length)
+			}
+			item.Payload = make([]byte, *item.Header.Length)
+			if _, err := io.ReadFull(reader, item.Payload); err != nil {
+				return nil, fmt.Errorf("failed to read item payload: %w", err)
+			}
+			// Consume the optional trailing newline
+			if next, err := reader.Peek(1); err == nil && next[0] == '\n' {
+				_, _ = reader.ReadByte()
+			}
+		} else {
+			item.Payload, err = readLine(reader)
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("failed to read item payload: %w", err)
+			}
+		}
+
+		envelope.Items = append(envelope.Items, item)
+	}
+
+	return envelope, nil
+}
+
+// Events returns the decoded error events contained in the envelope.
+// Other item types (sessions, transactions, attachments, ...) are skipped.
+func (e *Envelope) Events() ([]*Event, error) {
+	var events []*Event
+	for i, item := range e.Items {
+		if item.Header.Type != ItemTypeEvent && item.Header.Type != ItemTypeError {
+			continue
+		}
+
+		event, err := ParseEvent(item.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		if event.EventID == "" {
+			event.EventID = e.Header.EventID
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// readLine reads a single line without the trailing newline. It returns
+// io.EOF only when no data is left at all.
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return bytes.TrimRight(line, "\r\n"), err
+}
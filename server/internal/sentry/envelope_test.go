@@ -0,0 +1,157 @@
+package sentry
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"server/internal/models"
+)
+
+func TestParseEnvelope(t *testing.T) {
+	payload := `{"message":"hello","level":"error"}`
+	body := `{"event_id":"9ec79c33ec9942ab8353589fcb2e04dc","dsn":"https://key@errly.dev/1"}
+{"type":"event","length":` + strconv.Itoa(len(payload)) + `}
+` + payload + `
+{"type":"session"}
+{"started":"2020-02-07T14:16:00Z","status":"ok"}
+`
+
+	envelope, err := ParseEnvelope(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if envelope.Header.EventID != "9ec79c33ec9942ab8353589fcb2e04dc" {
+		t.Errorf("Expected event ID from header, got '%s'", envelope.Header.EventID)
+	}
+	if len(envelope.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(envelope.Items))
+	}
+	if string(envelope.Items[0].Payload) != payload {
+		t.Errorf("Expected payload %s, got %s", payload, envelope.Items[0].Payload)
+	}
+
+	events, err := envelope.Events()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if events[0].EventID != envelope.Header.EventID {
+		t.Errorf("Expected event to inherit envelope event ID, got '%s'", events[0].EventID)
+	}
+}
+
+func TestParseEnvelope_InvalidHeader(t *testing.T) {
+	if _, err := ParseEnvelope(strings.NewReader("not json\n")); err == nil {
+		t.Error("Expected error for invalid envelope header")
+	}
+}
+
+func TestEvent_ToIngestEvent(t *testing.T) {
+	payload := `{
+		"event_id": "fc6d8c0c43fc4630ad850ee518f1b9d0",
+		"timestamp": 1700000000.5,
+		"level": "fatal",
+		"platform": "python",
+		"release": "api@1.2.3",
+		"exception": {"values": [
+			{"type": "KeyError", "value": "'id'"},
+			{"type": "ValueError", "value": "bad input", "stacktrace": {"frames": [
+				{"filename": "app.py", "function": "main", "lineno": 10},
+				{"filename": "handlers.py", "function": "handle", "lineno": 42}
+			]}}
+		]},
+		"breadcrumbs": [{"category": "http", "message": "GET /"}],
+		"user": {"id": 42, "email": "jane@example.com", "ip_address": "{{auto}}"},
+		"request": {"url": "https://example.com/checkout"},
+		"tags": [["route", "/checkout"]],
+		"contexts": {"browser": {"name": "Chrome", "version": "120"}},
+		"extra": {"order": {"id": 7}}
+	}`
+
+	event, err := ParseEvent([]byte(payload))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ingest := event.ToIngestEvent(20)
+
+	if ingest.Message != "ValueError: bad input" {
+		t.Errorf("Expected message from outermost exception, got '%s'", ingest.Message)
+	}
+	if ingest.EventID == nil || *ingest.EventID != "fc6d8c0c43fc4630ad850ee518f1b9d0" {
+		t.Errorf("Expected event ID to be mapped, got %v", ingest.EventID)
+	}
+	if ingest.Level != models.LevelError {
+		t.Errorf("Expected fatal to map to error, got '%s'", ingest.Level)
+	}
+	if ingest.Environment != "production" {
+		t.Errorf("Expected default environment, got '%s'", ingest.Environment)
+	}
+	if ingest.ReleaseVersion == nil || *ingest.ReleaseVersion != "api@1.2.3" {
+		t.Errorf("Expected release to be mapped, got %v", ingest.ReleaseVersion)
+	}
+	if ingest.UserID == nil || *ingest.UserID != "42" {
+		t.Errorf("Expected numeric user ID to be mapped, got %v", ingest.UserID)
+	}
+	if ingest.UserIP != nil {
+		t.Errorf("Expected {{auto}} IP to be dropped, got %v", *ingest.UserIP)
+	}
+	if ingest.Browser == nil || *ingest.Browser != "Chrome 120" {
+		t.Errorf("Expected browser context to be mapped, got %v", ingest.Browser)
+	}
+	if ingest.Tags["platform"] != "python" {
+		t.Errorf("Expected platform to become a tag, got %v", ingest.Tags)
+	}
+	if ingest.Tags["route"] != "/checkout" {
+		t.Errorf("Expected tag pairs to be mapped, got %v", ingest.Tags)
+	}
+	if ingest.Extra["order"] != `{"id":7}` {
+		t.Errorf("Expected nested extra to be JSON encoded, got %v", ingest.Extra["order"])
+	}
+	if _, ok := ingest.Extra["breadcrumbs"]; !ok {
+		t.Error("Expected breadcrumbs to be stored in extra")
+	}
+	if ingest.Timestamp == nil || ingest.Timestamp.Unix() != 1700000000 {
+		t.Errorf("Expected numeric timestamp to be parsed, got %v", ingest.Timestamp)
+	}
+
+	expectedStack := "ValueError: bad input\n" +
+		"    at handle (handlers.py:42)\n" +
+		"    at main (app.py:10)\n" +
+		"Caused by: KeyError: 'id'"
+	if ingest.StackTrace == nil {
+		t.Fatal("Expected stack trace to be rendered")
+	}
+	if *ingest.StackTrace != expectedStack {
+		t.Errorf("Expected stack trace:\n%s\ngot:\n%s", expectedStack, *ingest.StackTrace)
+	}
+}
+
+func TestEvent_ToIngestEvent_DerivedTagsStayWithinLimit(t *testing.T) {
+	event, err := ParseEvent([]byte(`{
+		"message": "boom",
+		"server_name": "web-1",
+		"logger": "django",
+		"platform": "python",
+		"tags": {"a": "1", "logger": "custom"}
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ingest := event.ToIngestEvent(3)
+
+	expected := map[string]string{"a": "1", "logger": "custom", "server_name": "web-1"}
+	if len(ingest.Tags) != len(expected) {
+		t.Fatalf("Expected tags %v, got %v", expected, ingest.Tags)
+	}
+	for key, value := range expected {
+		if ingest.Tags[key] != value {
+			t.Errorf("Expected tag %s=%s, got %v", key, value, ingest.Tags)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"server/internal/models"
+	"server/internal/stacktrace"
+)
+
+// Event is the subset of the Sentry event payload that Errly understands
+type Event struct {
+	EventID     string                            `json:"event_id"`
+	Timestamp   Timestamp                         `json:"timestamp"`
+	Level       string                            `json:"level"`
+	Platform    string                            `json:"platform"`
+	Logger      string                            `json:"logger"`
+	ServerName  string                            `json:"server_name"`
+	Release     string                            `json:"release"`
+	Dist        string                            `json:"dist"`
+	Environment string                            `json:"environment"`
+	Message     Message                           `json:"message"`
+	LogEntry    *Message                          `json:"logentry"`
+	Exception   Exceptions                        `json:"exception"`
+	Breadcrumbs Breadcrumbs                       `json:"breadcrumbs"`
+	User        *User                             `json:"user"`
+	Request     *Request                          `json:"request"`
+	Tags        Tags                              `json:"tags"`
+	Contexts    map[string]map[string]interface{} `json:"contexts"`
+	Extra       map[string]interface{}            `json:"extra"`
+	Fingerprint []string                          `json:"fingerprint"`
+}
+
+// Exception is a single entry of the exception interface
+type Exception struct {
+	Type       string                 `json:"type"`
+	Value      string                 `json:"value"`
+	Module     string                 `json:"module"`
+	Mechanism  map[string]interface{} `json:"mechanism,omitempty"`
+	Stacktrace *Stacktrace            `json:"stacktrace"`
+}
+
+// Stacktrace holds frames ordered from oldest to newest call
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is a single stack frame
+type Frame struct {
+	Filename    string   `json:"filename"`
+	AbsPath     string   `json:"abs_path"`
+	Function    string   `json:"function"`
+	Module      string   `json:"module"`
+	Lineno      int      `json:"lineno"`
+	Colno       int      `json:"colno"`
+	InApp       *bool    `json:"in_app"`
+	ContextLine string   `json:"context_line"`
+	PreContext  []string `json:"pre_context"`
+	PostContext []string `json:"post_context"`
+}
+
+// Breadcrumb is a single breadcrumb entry
+type Breadcrumb struct {
+	Timestamp Timestamp              `json:"timestamp"`
+	Type      string                 `json:"type,omitempty"`
+	Category  string                 `json:"category,omitempty"`
+	Level     string                 `json:"level,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// User is the user interface
+type User struct {
+	ID        json.RawMessage `json:"id"`
+	Email     string          `json:"email"`
+	IPAddress string          `json:"ip_address"`
+	Username  string          `json:"username"`
+}
+
+// Request is the request interface
+type Request struct {
+	URL    string `json:"url"`
+	Method string `json:"method"`
+}
+
+// ParseEvent decodes a single Sentry event payload
+func ParseEvent(payload []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid event payload: %w", err)
+	}
+	return &event, nil
+}
+
+// ToIngestEvent converts the Sentry event into Errly's ingest format. The
+// server_name, logger and platform attributes become tags only while the
+// event has fewer than maxTags tags, so they never push an event over the
+// tag limit.
+func (e *Event) ToIngestEvent(maxTags int) models.IngestEvent {
+	event := models.IngestEvent{
+		Message:     e.message(),
+		Environment: e.Environment,
+		Level:       mapLevel(e.Level),
+		Tags:        make(map[string]string),
+		Extra:       make(map[string]interface{}),
+	}
+
+	if e.EventID != "" {
+		eventID := e.EventID
+		event.EventID = &eventID
+	}
+
+	if event.Environment == "" {
+		// Sentry SDKs default to "production" when no environment is configured
+		event.Environment = "production"
+	}
+
+	if !e.Timestamp.IsZero() {
+		event.Timestamp = &models.FlexibleTime{Time: e.Timestamp.Time}
+	}
+
+	if e.Release != "" {
+		release := e.Release
+		event.ReleaseVersion = &release
+	}
+
+	// Sentry fingerprints use the same {{ default }} placeholder
+	if len(e.Fingerprint) > 0 {
+		event.Fingerprint = e.Fingerprint
+	}
+
+	if exceptions := e.Exception.Models(); len(exceptions) > 0 {
+		event.Exceptions = exceptions
+		stack := stacktrace.Format(exceptions)
+		event.StackTrace = &stack
+	}
+
+	if e.User != nil {
+		if id := e.User.id(); id != "" {
+			event.UserID = &id
+		}
+		if e.User.Email != "" {
+			email := e.User.Email
+			event.UserEmail = &email
+		}
+		if e.User.IPAddress != "" && e.User.IPAddress != "{{auto}}" {
+			ip := e.User.IPAddress
+			event.UserIP = &ip
+		}
+	}
+
+	if e.Request != nil && e.Request.URL != "" {
+		url := e.Request.URL
+		event.URL = &url
+	}
+
+	if browser := contextName(e.Contexts["browser"]); browser != "" {
+		event.Browser = &browser
+	}
+	if os := contextName(e.Contexts["os"]); os != "" {
+		event.OS = &os
+	}
+
+	for key, value := range e.Tags {
+		event.Tags[key] = value
+	}
+	derived := []struct{ key, value string }{
+		{"server_name", e.ServerName},
+		{"logger", e.Logger},
+		{"platform", e.Platform},
+	}
+	for _, tag := range derived {
+		if tag.value == "" {
+			continue
+		}
+		// Tags sent by the SDK take precedence
+		if _, exists := event.Tags[tag.key]; exists || len(event.Tags) >= maxTags {
+			continue
+		}
+		event.Tags[tag.key] = tag.value
+	}
+
+	// Extra values are stored as strings, nested values are JSON encoded
+	for key, value := range e.Extra {
+		event.Extra[key] = stringify(value)
+	}
+	if len(e.Breadcrumbs) > 0 {
+		if encoded, err := json.Marshal(e.Breadcrumbs); err == nil {
+			event.Extra["breadcrumbs"] = string(encoded)
+		}
+	}
+
+	return event
+}
+
+// message picks the most descriptive title for the event
+func (e *Event) message() string {
+	if e.LogEntry != nil && e.LogEntry.String() != "" {
+		return e.LogEntry.String()
+	}
+	if e.Message.String() != "" {
+		return e.Message.String()
+	}
+	if exception := e.Exception.Primary(); exception != nil {
+		return exception.model().Title()
+	}
+	return "<unlabeled event>"
+}
+
+// mapLevel maps Sentry levels onto Errly levels
+func mapLevel(level string) models.ErrorLevel {
+	switch strings.ToLower(level) {
+	case "warning", "warn":
+		return models.LevelWarning
+	case "info", "log":
+		return models.LevelInfo
+	case "debug":
+		return models.LevelDebug
+	default:
+		// "fatal", "error" and missing levels are all treated as errors
+		return models.LevelError
+	}
+}
+
+// Message accepts both the plain string and the object form of the message
+// and logentry interfaces
+type Message struct {
+	Formatted string `json:"formatted"`
+	Message   string `json:"message"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		m.Formatted = text
+		return nil
+	}
+
+	type plain Message
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// String returns the formatted message, falling back to the raw template
+func (m Message) String() string {
+	if m.Formatted != "" {
+		return m.Formatted
+	}
+	return m.Message
+}
+
+// Exceptions accepts both {"values": [...]} and a bare list
+type Exceptions []Exception
+
+// UnmarshalJSON implements json.Unmarshaler
+func (e *Exceptions) UnmarshalJSON(data []byte) error {
+	var wrapped struct {
+		Values []Exception `json:"values"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil {
+		*e = wrapped.Values
+		return nil
+	}
+
+	var values []Exception
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*e = values
+	return nil
+}
+
+// Primary returns the outermost exception. Sentry orders chained exceptions
+// from the innermost cause to the exception that was actually raised.
+func (e Exceptions) Primary() *Exception {
+	if len(e) == 0 {
+		return nil
+	}
+	return &e[len(e)-1]
+}
+
+// Models converts the exception chain into Errly's structured exceptions,
+// which list the raised exception first followed by its causes
+func (e Exceptions) Models() []models.Exception {
+	if len(e) == 0 {
+		return nil
+	}
+
+	exceptions := make([]models.Exception, 0, len(e))
+	for i := len(e) - 1; i >= 0; i-- {
+		exceptions = append(exceptions, e[i].model())
+	}
+	return exceptions
+}
+
+// model converts a single exception
+func (e Exception) model() models.Exception {
+	exception := models.Exception{
+		Type:   e.Type,
+		Value:  e.Value,
+		Module: e.Module,
+	}
+
+	if len(e.Mechanism) > 0 {
+		mechanism := &models.Mechanism{}
+		mechanism.Type, _ = e.Mechanism["type"].(string)
+		mechanism.Description, _ = e.Mechanism["description"].(string)
+		if handled, ok := e.Mechanism["handled"].(bool); ok {
+			mechanism.Handled = &handled
+		}
+		exception.Mechanism = mechanism
+	}
+
+	if e.Stacktrace != nil && len(e.Stacktrace.Frames) > 0 {
+		frames := make([]models.StackFrame, len(e.Stacktrace.Frames))
+		for i, frame := range e.Stacktrace.Frames {
+			frames[i] = models.StackFrame{
+				Filename:    frame.Filename,
+				AbsPath:     frame.AbsPath,
+				Function:    frame.Function,
+				Module:      frame.Module,
+				Lineno:      frame.Lineno,
+				Colno:       frame.Colno,
+				InApp:       frame.InApp,
+				ContextLine: frame.ContextLine,
+				PreContext:  frame.PreContext,
+				PostContext: frame.PostContext,
+			}
+		}
+		exception.Stacktrace = &models.Stacktrace{Frames: frames}
+	}
+
+	return exception
+}
+
+// Breadcrumbs accepts both {"values": [...]} and a bare list
+type Breadcrumbs []Breadcrumb
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *Breadcrumbs) UnmarshalJSON(data []byte) error {
+	var wrapped struct {
+		Values []Breadcrumb `json:"values"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil {
+		*b = wrapped.Values
+		return nil
+	}
+
+	var values []Breadcrumb
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*b = values
+	return nil
+}
+
+// Tags accepts both an object and a list of [key, value] pairs
+type Tags map[string]string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Tags) UnmarshalJSON(data []byte) error {
+	tags := make(Tags)
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err == nil {
+		for key, value := range object {
+			tags[key] = stringify(value)
+		}
+		*t = tags
+		return nil
+	}
+
+	var pairs [][]interface{}
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		if len(pair) != 2 {
+			continue
+		}
+		tags[stringify(pair[0])] = stringify(pair[1])
+	}
+	*t = tags
+	return nil
+}
+
+// Timestamp accepts RFC3339 strings and numeric unix timestamps
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		whole := int64(seconds)
+		t.Time = time.Unix(whole, int64((seconds-float64(whole))*float64(time.Second))).UTC()
+		return nil
+	}
+
+	var flexible models.FlexibleTime
+	if err := flexible.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	t.Time = flexible.Time
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Format(time.RFC3339Nano))
+}
+
+// id returns the user ID, which SDKs send as either a string or a number
+func (u *User) id() string {
+	if len(u.ID) == 0 {
+		return u.Username
+	}
+	var value interface{}
+	if err := json.Unmarshal(u.ID, &value); err != nil || value == nil {
+		return u.Username
+	}
+	return stringify(value)
+}
+
+// contextName renders a browser or os context as "Name Version"
+func contextName(context map[string]interface{}) string {
+	if context == nil {
+		return ""
+	}
+	name, _ := context["name"].(string)
+	version, _ := context["version"].(string)
+	return strings.TrimSpace(name + " " + version)
+}
+
+// stringify converts arbitrary JSON values to strings
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
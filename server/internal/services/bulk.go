@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"server/internal/models"
+	"server/internal/repository"
+)
+
+// BulkTask applies a bulk operation to a chunk of issues
+type BulkTask func(ctx context.Context, issues []*models.Issue) error
+
+// BulkRunner runs bulk issue operations in the background. Jobs save their
+// progress after every chunk of issues so any replica can report it.
+type BulkRunner struct {
+	jobsRepo *repository.BulkJobsRepository
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewBulkRunner creates a new bulk runner
+func NewBulkRunner(jobsRepo *repository.BulkJobsRepository) *BulkRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BulkRunner{
+		jobsRepo: jobsRepo,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start saves a job and applies the task to the issues in chunks of
+// chunkSize in the background. The job stops at the first chunk that fails.
+func (r *BulkRunner) Start(ctx context.Context, job *models.BulkJob, issues []*models.Issue, chunkSize int, task BulkTask) error {
+	job.Total = len(issues)
+	if err := r.jobsRepo.Create(ctx, job); err != nil {
+		return err
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(job, issues, chunkSize, task)
+	}()
+
+	return nil
+}
+
+// Stop cancels the running jobs and waits for them to save their state
+func (r *BulkRunner) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// run applies the task to the issues of a job
+func (r *BulkRunner) run(job *models.BulkJob, issues []*models.Issue, chunkSize int, task BulkTask) {
+	processed := 0
+	for processed < len(issues) {
+		end := min(processed+chunkSize, len(issues))
+		if err := task(r.ctx, issues[processed:end]); err != nil {
+			message := err.Error()
+			if r.ctx.Err() != nil {
+				message = "interrupted by server shutdown"
+			}
+			log.Printf("Bulk job %s failed after %d of %d issues: %v", job.ID, processed, len(issues), err)
+			r.finish(job, models.BulkJobFailed, processed, message)
+			return
+		}
+
+		processed = end
+		if processed < len(issues) {
+			if err := r.jobsRepo.UpdateProgress(r.ctx, job.ID, processed); err != nil {
+				log.Printf("Failed to save progress of bulk job %s: %v", job.ID, err)
+			}
+		}
+	}
+
+	r.finish(job, models.BulkJobCompleted, processed, "")
+}
+
+// finish saves the outcome of a job. It doesn't use the runner context, which
+// is cancelled on shutdown.
+func (r *BulkRunner) finish(job *models.BulkJob, status models.BulkJobStatus, processed int, message string) {
+	if err := r.jobsRepo.Finish(context.Background(), job.ID, status, processed, message); err != nil {
+		log.Printf("Failed to save outcome of bulk job %s: %v", job.ID, err)
+	}
+}
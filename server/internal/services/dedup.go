@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"server/internal/database"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// EventDeduplicator remembers client supplied event IDs for a time window so
+// SDK retries don't store the same event twice
+type EventDeduplicator struct {
+	redis  *database.RedisDB
+	window time.Duration
+}
+
+// NewEventDeduplicator creates a new event deduplicator
+func NewEventDeduplicator(redis *database.RedisDB, window time.Duration) *EventDeduplicator {
+	return &EventDeduplicator{
+		redis:  redis,
+		window: window,
+	}
+}
+
+// Claim marks the event IDs as seen and reports for each ID whether it is new.
+// IDs that were already claimed within the window are duplicates.
+func (d *EventDeduplicator) Claim(ctx context.Context, projectID uuid.UUID, eventIDs []string) ([]bool, error) {
+	if len(eventIDs) == 0 {
+		return nil, nil
+	}
+
+	pipe := d.redis.Client().Pipeline()
+	cmds := make([]*redis.BoolCmd, len(eventIDs))
+	for i, eventID := range eventIDs {
+		cmds[i] = pipe.SetNX(ctx, d.key(projectID, eventID), 1, d.window)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to claim event IDs: %w", err)
+	}
+
+	fresh := make([]bool, len(cmds))
+	for i, cmd := range cmds {
+		fresh[i] = cmd.Val()
+	}
+	return fresh, nil
+}
+
+// Release forgets claimed event IDs so events that failed to store can be
+// retried
+func (d *EventDeduplicator) Release(ctx context.Context, projectID uuid.UUID, eventIDs []string) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(eventIDs))
+	for i, eventID := range eventIDs {
+		keys[i] = d.key(projectID, eventID)
+	}
+
+	if err := d.redis.Client().Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to release event IDs: %w", err)
+	}
+	return nil
+}
+
+// key returns the Redis key for an event ID
+func (d *EventDeduplicator) key(projectID uuid.UUID, eventID string) string {
+	return fmt.Sprintf("ingest_dedup:%s:%s", projectID, eventID)
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"server/internal/grouping"
+	"server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// GroupingRules loads the fingerprint rules of projects and keeps them for a
+// short time so ingest doesn't read project settings for every batch
+type GroupingRules struct {
+	projectsRepo *repository.ProjectsRepository
+	ttl          time.Duration
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]groupingRulesEntry
+}
+
+// groupingRulesEntry holds the cached rules of a project
+type groupingRulesEntry struct {
+	rules    []grouping.Rule
+	loadedAt time.Time
+}
+
+// NewGroupingRules creates a new rules loader. Rule changes take effect after
+// at most ttl.
+func NewGroupingRules(projectsRepo *repository.ProjectsRepository, ttl time.Duration) *GroupingRules {
+	return &GroupingRules{
+		projectsRepo: projectsRepo,
+		ttl:          ttl,
+		entries:      make(map[uuid.UUID]groupingRulesEntry),
+	}
+}
+
+// Get returns the fingerprint rules of a project. When the rules can't be
+// loaded the previously loaded rules are kept, or none at all, so ingestion
+// falls back to default grouping instead of failing.
+func (g *GroupingRules) Get(ctx context.Context, projectID uuid.UUID) []grouping.Rule {
+	g.mu.Lock()
+	entry, ok := g.entries[projectID]
+	g.mu.Unlock()
+	if ok && time.Since(entry.loadedAt) < g.ttl {
+		return entry.rules
+	}
+
+	rules, err := g.load(ctx, projectID)
+	if err != nil {
+		log.Printf("Failed to load fingerprint rules for project %s: %v", projectID, err)
+		rules = entry.rules
+	}
+
+	g.mu.Lock()
+	g.entries[projectID] = groupingRulesEntry{rules: rules, loadedAt: time.Now()}
+	g.mu.Unlock()
+
+	return rules
+}
+
+// Invalidate drops the cached rules of a project after they changed
+func (g *GroupingRules) Invalidate(projectID uuid.UUID) {
+	g.mu.Lock()
+	delete(g.entries, projectID)
+	g.mu.Unlock()
+}
+
+// load reads the rules from the project settings
+func (g *GroupingRules) load(ctx context.Context, projectID uuid.UUID) ([]grouping.Rule, error) {
+	project, err := g.projectsRepo.GetByID(ctx, projectID)
+	if err != nil || project == nil {
+		return nil, err
+	}
+	return grouping.ParseRules(project.Settings)
+}
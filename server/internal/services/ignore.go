@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"server/internal/models"
+)
+
+// checkIgnoreConditions reopens an ignored issue when one of the conditions
+// it was ignored until has tripped. The counts of the issue include the
+// events just inserted, since issue_stats is maintained on insert.
+func (s *IngestService) checkIgnoreConditions(ctx context.Context, issue *models.Issue, now time.Time) error {
+	if issue.Status != models.StatusIgnored || issue.Ignore.Empty() {
+		return nil
+	}
+
+	condition := issue.Ignore.Tripped(issue, now)
+	if condition == "" && issue.Ignore.EventRate > 0 {
+		since := now.Add(-issue.Ignore.RateWindowDuration())
+		recent, err := s.eventsRepo.CountIssueEvents(ctx, issue.ProjectID, issue.Fingerprints(), since)
+		if err != nil {
+			return err
+		}
+		if issue.Ignore.RateExceeded(recent) {
+			condition = models.IgnoreConditionEventRate
+		}
+	}
+	if condition == "" {
+		return nil
+	}
+
+	// Replicas reopening the issue at the same time may both record the
+	// unignore, see IssuesRepository.Unignore
+	unignored, err := s.issuesRepo.Unignore(ctx, issue.ID)
+	if err != nil || !unignored {
+		return err
+	}
+	log.Printf("Issue %s unignored in project %s: %s condition tripped", issue.ID, issue.ProjectID, condition)
+
+	if s.activityRepo == nil {
+		return nil
+	}
+	activity := &models.IssueActivity{
+		ProjectID: issue.ProjectID,
+		IssueID:   issue.ID,
+		Type:      models.ActivityUnignored,
+		Data: map[string]interface{}{
+			"condition": string(condition),
+			"ignore":    issue.Ignore,
+		},
+	}
+	if err := s.activityRepo.Create(ctx, activity); err != nil {
+		// The issue is reopened either way
+		log.Printf("Failed to record unignore of issue %s: %v", issue.ID, err)
+	}
+	return nil
+}
+
+// SweepIgnoredIssues checks the conditions of every conditionally ignored
+// issue. Conditions are also checked when events arrive, but durations run
+// out and rates drop without any. Issues that fail to be checked are logged
+// and left for the next sweep. It returns the number of issues checked.
+func (s *IngestService) SweepIgnoredIssues(ctx context.Context) (int, error) {
+	issues, err := s.issuesRepo.GetConditionallyIgnoredIssues(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	checked := 0
+	for _, issue := range issues {
+		if ctx.Err() != nil {
+			return checked, ctx.Err()
+		}
+		if err := s.checkIgnoreConditions(ctx, issue, now); err != nil {
+			log.Printf("Failed to check ignore conditions of issue %s: %v", issue.ID, err)
+			continue
+		}
+		checked++
+	}
+
+	return checked, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// IgnoreSweeper periodically reopens ignored issues whose ignore conditions
+// tripped while no events arrived for them
+type IgnoreSweeper struct {
+	ingestService *IngestService
+	interval      time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewIgnoreSweeper creates a new ignore sweeper
+func NewIgnoreSweeper(ingestService *IngestService, interval time.Duration) *IgnoreSweeper {
+	return &IgnoreSweeper{
+		ingestService: ingestService,
+		interval:      interval,
+	}
+}
+
+// Start starts the sweep loop
+func (w *IgnoreSweeper) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.sweep(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops the sweep loop and waits for an in-flight sweep to finish
+func (w *IgnoreSweeper) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
+
+// sweep checks the conditions of the ignored issues
+func (w *IgnoreSweeper) sweep(ctx context.Context) {
+	if _, err := w.ingestService.SweepIgnoredIssues(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("Ignore sweep stopped: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"server/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestSweepIgnoredIssues_ContinuesAfterFailures(t *testing.T) {
+	ts := newTestIngestService(t, false)
+	ts.events.countErr = errors.New("timeout")
+
+	expired := time.Now().Add(-time.Minute)
+	rateLimited := &models.Issue{
+		ID:        "rate",
+		ProjectID: uuid.New(),
+		Status:    models.StatusIgnored,
+		Ignore:    &models.IgnoreConditions{EventRate: 10, RateWindow: 60},
+	}
+	timeLimited := &models.Issue{
+		ID:        "until",
+		ProjectID: uuid.New(),
+		Status:    models.StatusIgnored,
+		Ignore:    &models.IgnoreConditions{Until: &expired},
+	}
+	ts.issues.ignored = []*models.Issue{rateLimited, timeLimited}
+
+	checked, err := ts.SweepIgnoredIssues(context.Background())
+	if err != nil {
+		t.Fatalf("Expected failures of single issues not to fail the sweep, got %v", err)
+	}
+	if checked != 1 {
+		t.Errorf("Expected 1 checked issue, got %d", checked)
+	}
+	if rateLimited.Status != models.StatusIgnored {
+		t.Errorf("Expected the issue that failed to stay ignored, got %s", rateLimited.Status)
+	}
+	if timeLimited.Status != models.StatusUnresolved {
+		t.Errorf("Expected the issues after a failure to be checked, got %s", timeLimited.Status)
+	}
+	if len(ts.activity.activities) != 1 || ts.activity.activities[0].Type != models.ActivityUnignored {
+		t.Errorf("Expected the unignore to be recorded, got %+v", ts.activity.activities)
+	}
+}
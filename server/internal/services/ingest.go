@@ -2,38 +2,128 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
+	"server/internal/grouping"
 	"server/internal/models"
+	"server/internal/ownership"
+	"server/internal/release"
 	"server/internal/repository"
+	"server/internal/spool"
+	"server/internal/stacktrace"
 
 	"github.com/google/uuid"
 )
 
+// errSpoolBacklog is the reason events are spooled while earlier events are
+// still waiting in the spool
+var errSpoolBacklog = errors.New("earlier events are still spooled")
+
+// eventStore is the part of the events repository used by ingestion
+type eventStore interface {
+	InsertEvents(ctx context.Context, events []*models.ErrorEvent) error
+	GetReleaseFirstSeen(ctx context.Context, projectID uuid.UUID, releases []string) (map[string]time.Time, error)
+	CountIssueEvents(ctx context.Context, projectID uuid.UUID, fingerprints []string, since time.Time) (uint64, error)
+}
+
+// issueStore is the part of the issues repository used by ingestion
+type issueStore interface {
+	GetIssuesByFingerprints(ctx context.Context, projectID uuid.UUID, fingerprints []string) (map[string]*models.Issue, error)
+	GetGroupingVersions(ctx context.Context, projectID uuid.UUID, fingerprints []string) (map[string]uint8, error)
+	GetConditionallyIgnoredIssues(ctx context.Context) ([]*models.Issue, error)
+	InsertIssue(ctx context.Context, issue *models.Issue) error
+	MarkRegressed(ctx context.Context, issueID string, regressedAt time.Time, release string) error
+	Unignore(ctx context.Context, issueID string) (bool, error)
+}
+
+// activityLog records the activity of issues
+type activityLog interface {
+	Create(ctx context.Context, activity *models.IssueActivity) error
+}
+
 // IngestService handles event ingestion logic
 type IngestService struct {
-	eventsRepo *repository.EventsRepository
-	issuesRepo *repository.IssuesRepository
+	eventsRepo   eventStore
+	issuesRepo   issueStore
+	spool        *spool.Spool
+	health       func() error
+	deduplicator *EventDeduplicator
+	symbolicator *Symbolicator
+	rules        *GroupingRules
+	activityRepo activityLog
+	ownership    *OwnershipRules
 }
 
-// NewIngestService creates a new ingest service
-func NewIngestService(eventsRepo *repository.EventsRepository, issuesRepo *repository.IssuesRepository) *IngestService {
-	return &IngestService{
-		eventsRepo: eventsRepo,
-		issuesRepo: issuesRepo,
+// spooledBatch is a batch of events written to the spool while ClickHouse is
+// unavailable
+type spooledBatch struct {
+	ProjectID uuid.UUID            `json:"project_id"`
+	Events    []*models.ErrorEvent `json:"events"`
+}
+
+// NewIngestService creates a new ingest service. The spool is optional; when
+// set, events are buffered on disk while health reports ClickHouse as
+// unavailable. The deduplicator is optional as well; without it client event
+// IDs are stored but not deduplicated. Without a symbolicator JavaScript
+// frames are stored as they were sent, and without grouping rules every event
+// uses the default fingerprint. Without an activity repository regressions
+// still reopen issues but aren't logged, and without ownership rules new
+// issues are unassigned.
+func NewIngestService(eventsRepo *repository.EventsRepository, issuesRepo *repository.IssuesRepository, eventSpool *spool.Spool, health func() error, deduplicator *EventDeduplicator, symbolicator *Symbolicator, rules *GroupingRules, activityRepo *repository.ActivityRepository, ownershipRules *OwnershipRules) *IngestService {
+	s := &IngestService{
+		eventsRepo:   eventsRepo,
+		issuesRepo:   issuesRepo,
+		spool:        eventSpool,
+		health:       health,
+		deduplicator: deduplicator,
+		symbolicator: symbolicator,
+		rules:        rules,
+		ownership:    ownershipRules,
+	}
+	// A nil repository would make a non-nil interface
+	if activityRepo != nil {
+		s.activityRepo = activityRepo
 	}
+	return s
 }
 
 // ProcessEvents processes incoming events and creates/updates issues
 func (s *IngestService) ProcessEvents(ctx context.Context, projectID uuid.UUID, ingestEvents []models.IngestEvent) error {
+	events, err := s.StoreEvents(ctx, projectID, ingestEvents)
+	if err != nil {
+		return err
+	}
+	return s.ProcessIssues(ctx, projectID, events)
+}
+
+// StoreEvents converts incoming events and inserts them into ClickHouse. It
+// returns the stored events, whose issues are processed by ProcessIssues.
+// Duplicates are left out, and so are spooled events since their issues are
+// processed when the spool is replayed.
+func (s *IngestService) StoreEvents(ctx context.Context, projectID uuid.UUID, ingestEvents []models.IngestEvent) ([]*models.ErrorEvent, error) {
 	if len(ingestEvents) == 0 {
-		return nil
+		return nil, nil
+	}
+
+	// Drop retries of events that were already ingested
+	ingestEvents, claimedIDs := s.dropDuplicates(ctx, projectID, ingestEvents)
+	if len(ingestEvents) == 0 {
+		return nil, nil
+	}
+
+	// Project specific fingerprint rules override the default grouping
+	var rules []grouping.Rule
+	if s.rules != nil {
+		rules = s.rules.Get(ctx, projectID)
 	}
 
 	// Convert ingest events to error events
 	var errorEvents []*models.ErrorEvent
-	fingerprintMap := make(map[string][]*models.ErrorEvent)
+	legacyFingerprints := make(map[*models.ErrorEvent]string)
 
 	for _, ingestEvent := range ingestEvents {
 		// Initialize nil maps to avoid panics
@@ -44,8 +134,25 @@ func (s *IngestService) ProcessEvents(ctx context.Context, projectID uuid.UUID,
 			ingestEvent.Extra = make(map[string]interface{})
 		}
 
+		// Keep the structured and the plain text stack trace in sync
+		resolveExceptions(&ingestEvent)
+
+		// Map minified JavaScript frames back to the original source. The
+		// text trace is re-rendered so grouping doesn't change per release.
+		if s.symbolicator != nil && s.symbolicator.Symbolicate(ctx, projectID, &ingestEvent) {
+			text := stacktrace.Format(ingestEvent.Exceptions)
+			ingestEvent.StackTrace = &text
+		}
+
 		// Generate fingerprint for grouping
-		fingerprint := s.eventsRepo.GenerateFingerprint(&ingestEvent)
+		defaultFingerprint := grouping.Fingerprint(&ingestEvent)
+		fingerprint := defaultFingerprint
+
+		// SDKs may override the default grouping; project rules still win
+		if len(ingestEvent.Fingerprint) > 0 {
+			fingerprint = grouping.ClientFingerprint(ingestEvent.Fingerprint, defaultFingerprint)
+		}
+		fingerprint, _ = grouping.Apply(rules, &ingestEvent, fingerprint)
 
 		// Create error event
 		errorEvent := &models.ErrorEvent{
@@ -64,6 +171,7 @@ func (s *IngestService) ProcessEvents(ctx context.Context, projectID uuid.UUID,
 			URL:            ingestEvent.URL,
 			Tags:           ingestEvent.Tags,
 			Extra:          ingestEvent.Extra,
+			Exceptions:     ingestEvent.Exceptions,
 			Fingerprint:    fingerprint,
 			Level:          ingestEvent.Level,
 			CreatedAt:      time.Now(),
@@ -74,15 +182,57 @@ func (s *IngestService) ProcessEvents(ctx context.Context, projectID uuid.UUID,
 			errorEvent.Timestamp = ingestEvent.Timestamp.Time
 		}
 
-
+		// Use the client event ID so retried events keep their identity
+		if eventID, ok := normalizeEventID(ingestEvent.EventID); ok {
+			errorEvent.ID = eventID
+		}
 
 		errorEvents = append(errorEvents, errorEvent)
-		fingerprintMap[fingerprint] = append(fingerprintMap[fingerprint], errorEvent)
+		if fingerprint == defaultFingerprint {
+			legacyFingerprints[errorEvent] = grouping.FingerprintVersion(grouping.VersionLegacy, &ingestEvent)
+		}
+	}
+
+	// Events keep joining issues created by the legacy grouping algorithm
+	s.applyLegacyFingerprints(ctx, projectID, legacyFingerprints)
+
+	// Events queue up behind spooled events so they are stored in order
+	if s.spool != nil && !s.spool.Empty() {
+		if err := s.spoolEvents(projectID, errorEvents, errSpoolBacklog); err != nil {
+			s.releaseEventIDs(projectID, claimedIDs)
+			return nil, err
+		}
+		return nil, nil
 	}
 
 	// Insert events into ClickHouse
 	if err := s.eventsRepo.InsertEvents(ctx, errorEvents); err != nil {
-		return fmt.Errorf("failed to insert events: %w", err)
+		if s.spool != nil && s.unavailable() {
+			// Keep the events on disk until ClickHouse recovers
+			err = s.spoolEvents(projectID, errorEvents, err)
+			if err == nil {
+				return nil, nil
+			}
+		} else {
+			err = fmt.Errorf("failed to insert events: %w", err)
+		}
+		s.releaseEventIDs(projectID, claimedIDs)
+		return nil, err
+	}
+
+	return errorEvents, nil
+}
+
+// ProcessIssues creates the issues of stored events and updates the existing
+// ones. It doesn't insert the events again, so it can be retried on its own.
+func (s *IngestService) ProcessIssues(ctx context.Context, projectID uuid.UUID, events []*models.ErrorEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	fingerprintMap := make(map[string][]*models.ErrorEvent)
+	for _, event := range events {
+		fingerprintMap[event.Fingerprint] = append(fingerprintMap[event.Fingerprint], event)
 	}
 
 	// Process issues (create or update)
@@ -93,60 +243,359 @@ func (s *IngestService) ProcessEvents(ctx context.Context, projectID uuid.UUID,
 	return nil
 }
 
-// processIssues creates or updates issues based on fingerprints
+// dropDuplicates removes events whose client event ID was already ingested
+// within the deduplication window, including repeats within the batch. It
+// returns the remaining events and the IDs claimed for them. Deduplication is
+// skipped when Redis is unavailable so ingestion keeps working.
+func (s *IngestService) dropDuplicates(ctx context.Context, projectID uuid.UUID, events []models.IngestEvent) ([]models.IngestEvent, []string) {
+	if s.deduplicator == nil {
+		return events, nil
+	}
+
+	var eventIDs []string
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if eventID, ok := normalizeEventID(event.EventID); ok && !seen[eventID] {
+			seen[eventID] = true
+			eventIDs = append(eventIDs, eventID)
+		}
+	}
+	if len(eventIDs) == 0 {
+		return events, nil
+	}
+
+	fresh, err := s.deduplicator.Claim(ctx, projectID, eventIDs)
+	if err != nil {
+		log.Printf("Skipping event deduplication for project %s: %v", projectID, err)
+		return events, nil
+	}
+
+	var claimedIDs []string
+	claimed := make(map[string]bool)
+	for i, eventID := range eventIDs {
+		if fresh[i] {
+			claimed[eventID] = true
+			claimedIDs = append(claimedIDs, eventID)
+		}
+	}
+
+	unique := make([]models.IngestEvent, 0, len(events))
+	for _, event := range events {
+		eventID, ok := normalizeEventID(event.EventID)
+		if !ok {
+			unique = append(unique, event)
+			continue
+		}
+		if claimed[eventID] {
+			// Only the first occurrence within the batch is kept
+			delete(claimed, eventID)
+			unique = append(unique, event)
+		}
+	}
+
+	if dropped := len(events) - len(unique); dropped > 0 {
+		log.Printf("Dropped %d duplicate events for project %s", dropped, projectID)
+	}
+	return unique, claimedIDs
+}
+
+// applyLegacyFingerprints switches events to their legacy fingerprint when
+// there is no issue for their current fingerprint yet but there is a legacy
+// issue for the legacy one. Grouping upgrades therefore don't split existing
+// issues. Lookup failures keep the current fingerprints.
+func (s *IngestService) applyLegacyFingerprints(ctx context.Context, projectID uuid.UUID, legacyFingerprints map[*models.ErrorEvent]string) {
+	if len(legacyFingerprints) == 0 {
+		return
+	}
+
+	fingerprints := make([]string, 0, 2*len(legacyFingerprints))
+	for event, legacy := range legacyFingerprints {
+		fingerprints = append(fingerprints, event.Fingerprint, legacy)
+	}
+
+	versions, err := s.issuesRepo.GetGroupingVersions(ctx, projectID, fingerprints)
+	if err != nil {
+		log.Printf("Failed to look up legacy issues for project %s: %v", projectID, err)
+		return
+	}
+
+	for event, legacy := range legacyFingerprints {
+		if _, exists := versions[event.Fingerprint]; exists {
+			continue
+		}
+		if version, exists := versions[legacy]; exists && version == grouping.VersionLegacy {
+			event.Fingerprint = legacy
+		}
+	}
+}
+
+// releaseEventIDs releases claimed event IDs after a failed insert so the
+// client's retry is accepted
+func (s *IngestService) releaseEventIDs(projectID uuid.UUID, eventIDs []string) {
+	if s.deduplicator == nil || len(eventIDs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.deduplicator.Release(ctx, projectID, eventIDs); err != nil {
+		log.Printf("Failed to release event IDs for project %s: %v", projectID, err)
+	}
+}
+
+// resolveExceptions parses structured exceptions out of plain text stack
+// traces and renders a text stack trace for structured exceptions
+func resolveExceptions(event *models.IngestEvent) {
+	switch {
+	case len(event.Exceptions) == 0 && event.StackTrace != nil:
+		event.Exceptions = stacktrace.Parse(*event.StackTrace)
+	case len(event.Exceptions) > 0 && event.StackTrace == nil:
+		text := stacktrace.Format(event.Exceptions)
+		event.StackTrace = &text
+	}
+}
+
+// normalizeEventID returns the canonical form of a client event ID. Both
+// dashed UUIDs and the 32 character hex form used by Sentry SDKs are accepted.
+func normalizeEventID(eventID *string) (string, bool) {
+	if eventID == nil || *eventID == "" {
+		return "", false
+	}
+
+	id, err := uuid.Parse(*eventID)
+	if err != nil {
+		return "", false
+	}
+	return id.String(), true
+}
+
+// unavailable reports whether ClickHouse is down. Inserts that fail while it
+// is up were rejected, and spooling them would only block the spool.
+func (s *IngestService) unavailable() bool {
+	return s.health != nil && s.health() != nil
+}
+
+// spoolEvents appends events that can't be inserted yet to the spool
+func (s *IngestService) spoolEvents(projectID uuid.UUID, events []*models.ErrorEvent, insertErr error) error {
+	payload, err := json.Marshal(spooledBatch{ProjectID: projectID, Events: events})
+	if err != nil {
+		return fmt.Errorf("failed to insert events: %w (spool encoding failed: %v)", insertErr, err)
+	}
+
+	if err := s.spool.Append(payload); err != nil {
+		return fmt.Errorf("failed to insert events: %w (spool append failed: %v)", insertErr, err)
+	}
+
+	log.Printf("Spooled %d events for project %s: %v", len(events), projectID, insertErr)
+	return nil
+}
+
+// ReplaySpool replays spooled events into ClickHouse in the order they were
+// spooled. Replay stops at the first batch that can't be inserted while
+// ClickHouse is unavailable; batches it rejects while available are dropped.
+func (s *IngestService) ReplaySpool(ctx context.Context) (int, error) {
+	if s.spool == nil {
+		return 0, nil
+	}
+
+	return s.spool.Replay(func(payload []byte) error {
+		var batch spooledBatch
+		if err := json.Unmarshal(payload, &batch); err != nil {
+			// Undecodable batches can never succeed, drop them
+			log.Printf("Dropping undecodable spooled batch: %v", err)
+			return nil
+		}
+
+		if err := s.eventsRepo.InsertEvents(ctx, batch.Events); err != nil {
+			if s.unavailable() {
+				return fmt.Errorf("failed to insert spooled events: %w", err)
+			}
+			// Retrying would hold up every batch behind this one
+			log.Printf("Dropping spooled batch of %d events for project %s: %v", len(batch.Events), batch.ProjectID, err)
+			return nil
+		}
+
+		// The events are stored at this point, so issue failures are logged
+		// rather than retried to avoid inserting the events twice
+		if err := s.ProcessIssues(ctx, batch.ProjectID, batch.Events); err != nil {
+			log.Printf("Failed to process issues for spooled events of project %s: %v", batch.ProjectID, err)
+		}
+
+		return nil
+	})
+}
+
+// SpoolStats returns the spool statistics, or nil when spooling is disabled
+func (s *IngestService) SpoolStats() *spool.Stats {
+	if s.spool == nil {
+		return nil
+	}
+	stats := s.spool.Stats()
+	return &stats
+}
+
+// processIssues creates the issues of new fingerprints and reopens resolved
+// issues that received events. The counters and affected users of issues are
+// aggregated from the inserted events by ClickHouse.
 func (s *IngestService) processIssues(ctx context.Context, projectID uuid.UUID, fingerprintMap map[string][]*models.ErrorEvent) error {
+	fingerprints := make([]string, 0, len(fingerprintMap))
+	for fingerprint := range fingerprintMap {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	existingIssues, err := s.issuesRepo.GetIssuesByFingerprints(ctx, projectID, fingerprints)
+	if err != nil {
+		return fmt.Errorf("failed to check existing issues: %w", err)
+	}
+
+	releaseFirstSeen := s.releaseFirstSeen(ctx, projectID, existingIssues, fingerprintMap)
+
+	// Fingerprints merged into the same issue share its events
+	issues := make(map[string]*models.Issue)
+	issueEvents := make(map[string][]*models.ErrorEvent)
 	for fingerprint, events := range fingerprintMap {
 		if len(events) == 0 {
 			continue
 		}
+		if existingIssue, exists := existingIssues[fingerprint]; exists {
+			issues[existingIssue.ID] = existingIssue
+			issueEvents[existingIssue.ID] = append(issueEvents[existingIssue.ID], events...)
+			continue
+		}
 
-		// Check if issue already exists
-		existingIssue, err := s.getIssueByFingerprint(ctx, projectID, fingerprint)
-		if err != nil {
-			return fmt.Errorf("failed to check existing issue: %w", err)
+		if err := s.createNewIssue(ctx, projectID, fingerprint, events); err != nil {
+			return fmt.Errorf("failed to create new issue: %w", err)
 		}
+	}
 
-		if existingIssue != nil {
-			// Update existing issue
-			if err := s.updateExistingIssue(ctx, existingIssue, events); err != nil {
-				return fmt.Errorf("failed to update existing issue: %w", err)
-			}
-		} else {
-			// Create new issue
-			if err := s.createNewIssue(ctx, projectID, fingerprint, events); err != nil {
-				return fmt.Errorf("failed to create new issue: %w", err)
-			}
+	for issueID, issue := range issues {
+		if err := s.detectRegression(ctx, issue, issueEvents[issueID], releaseFirstSeen); err != nil {
+			return fmt.Errorf("failed to reopen regressed issue: %w", err)
+		}
+		if err := s.checkIgnoreConditions(ctx, issue, time.Now()); err != nil {
+			return fmt.Errorf("failed to check ignore conditions: %w", err)
 		}
 	}
 
 	return nil
 }
 
-// getIssueByFingerprint retrieves an issue by fingerprint
-func (s *IngestService) getIssueByFingerprint(ctx context.Context, projectID uuid.UUID, fingerprint string) (*models.Issue, error) {
-	query := &models.IssuesQuery{
-		ProjectID: &projectID,
-		Page:      1,
-		Limit:     1,
+// detectRegression reopens a resolved issue that received events from a
+// release it isn't resolved in. It records when and in which release the
+// issue regressed, taken from the earliest of those events.
+func (s *IngestService) detectRegression(ctx context.Context, issue *models.Issue, events []*models.ErrorEvent, releaseFirstSeen map[string]time.Time) error {
+	if issue.Status != models.StatusResolved {
+		return nil
 	}
 
-	// This is a simplified approach - in a real implementation, you'd want a direct fingerprint lookup
-	response, err := s.issuesRepo.GetIssues(ctx, query)
-	if err != nil {
-		return nil, err
+	var first *models.ErrorEvent
+	for _, event := range events {
+		if !regresses(issue, event, releaseFirstSeen) {
+			continue
+		}
+		if first == nil || event.Timestamp.Before(first.Timestamp) {
+			first = event
+		}
+	}
+	if first == nil {
+		return nil
 	}
 
-	// Find issue with matching fingerprint
-	for _, issue := range response.Data {
-		if issue.Fingerprint == fingerprint {
-			return &issue, nil
+	regressedIn := ""
+	if first.ReleaseVersion != nil {
+		regressedIn = *first.ReleaseVersion
+	}
+
+	if err := s.issuesRepo.MarkRegressed(ctx, issue.ID, first.Timestamp, regressedIn); err != nil {
+		return err
+	}
+	log.Printf("Issue %s regressed in project %s", issue.ID, issue.ProjectID)
+
+	if s.activityRepo == nil {
+		return nil
+	}
+	activity := &models.IssueActivity{
+		ProjectID: issue.ProjectID,
+		IssueID:   issue.ID,
+		Type:      models.ActivityRegression,
+		Data: map[string]interface{}{
+			"event_id": first.ID,
+			"release":  regressedIn,
+		},
+	}
+	if err := s.activityRepo.Create(ctx, activity); err != nil {
+		// The issue is reopened either way
+		log.Printf("Failed to record regression of issue %s: %v", issue.ID, err)
+	}
+	return nil
+}
+
+// regresses reports whether an event reopens a resolved issue. Issues resolved
+// without a release only reopen for events that happened after they were
+// resolved, not for older events delivered late from the queue or the spool.
+// Issues resolved in a release only reopen for events from that release or
+// newer ones, and issues resolved in the next release for events from
+// releases newer than the latest one when they were resolved. Events without
+// a release can't be ordered and don't reopen them.
+func regresses(issue *models.Issue, event *models.ErrorEvent, releaseFirstSeen map[string]time.Time) bool {
+	if issue.Resolution == "" {
+		// Issues resolved before resolution times were recorded have none
+		return issue.ResolvedAt == nil || event.Timestamp.After(*issue.ResolvedAt)
+	}
+	if event.ReleaseVersion == nil || *event.ReleaseVersion == "" {
+		return false
+	}
+
+	switch issue.Resolution {
+	case models.ResolutionInRelease:
+		return release.Compare(*event.ReleaseVersion, issue.ResolvedInRelease, releaseFirstSeen) >= 0
+	case models.ResolutionInNextRelease:
+		return issue.ResolvedInRelease == "" ||
+			release.Compare(*event.ReleaseVersion, issue.ResolvedInRelease, releaseFirstSeen) > 0
+	default:
+		return true
+	}
+}
+
+// releaseFirstSeen loads when the releases involved in release resolutions of
+// the batch were first seen, to order releases that aren't semantic versions.
+// It returns nil when no issue of the batch is resolved in a release or the
+// lookup fails, in which case such releases are ordered by name.
+func (s *IngestService) releaseFirstSeen(ctx context.Context, projectID uuid.UUID, issues map[string]*models.Issue, fingerprintMap map[string][]*models.ErrorEvent) map[string]time.Time {
+	releaseSet := make(map[string]bool)
+	for fingerprint, issue := range issues {
+		if issue.Status != models.StatusResolved || issue.Resolution == "" {
+			continue
+		}
+		if issue.ResolvedInRelease != "" {
+			releaseSet[issue.ResolvedInRelease] = true
+		}
+		for _, event := range fingerprintMap[fingerprint] {
+			if event.ReleaseVersion != nil && *event.ReleaseVersion != "" {
+				releaseSet[*event.ReleaseVersion] = true
+			}
 		}
 	}
+	if len(releaseSet) == 0 {
+		return nil
+	}
 
-	return nil, nil
+	releases := make([]string, 0, len(releaseSet))
+	for name := range releaseSet {
+		releases = append(releases, name)
+	}
+	firstSeen, err := s.eventsRepo.GetReleaseFirstSeen(ctx, projectID, releases)
+	if err != nil {
+		log.Printf("Failed to look up releases for project %s: %v", projectID, err)
+		return nil
+	}
+	return firstSeen
 }
 
-// createNewIssue creates a new issue
+// createNewIssue creates a new issue and assigns it by the ownership rules of
+// the project. Another replica may create the same issue concurrently; both
+// use the ID derived from the fingerprint, so the rows collapse into one issue.
 func (s *IngestService) createNewIssue(ctx context.Context, projectID uuid.UUID, fingerprint string, events []*models.ErrorEvent) error {
 	firstEvent := events[0]
 
@@ -156,8 +605,8 @@ func (s *IngestService) createNewIssue(ctx context.Context, projectID uuid.UUID,
 
 	for _, event := range events {
 		envMap[event.Environment] = true
-		if event.UserID != nil {
-			userMap[*event.UserID] = true
+		if key := userKey(event); key != "" {
+			userMap[key] = true
 		}
 	}
 
@@ -168,19 +617,20 @@ func (s *IngestService) createNewIssue(ctx context.Context, projectID uuid.UUID,
 
 	// Create issue
 	issue := &models.Issue{
-		ID:           uuid.New().String(),
-		ProjectID:    projectID,
-		Fingerprint:  fingerprint,
-		Message:      firstEvent.Message,
-		Level:        firstEvent.Level,
-		Status:       models.StatusUnresolved,
-		FirstSeen:    firstEvent.Timestamp,
-		LastSeen:     firstEvent.Timestamp,
-		EventCount:   uint64(len(events)),
-		UserCount:    uint64(len(userMap)),
-		Environments: environments,
-		Tags:         firstEvent.Tags,
-		UpdatedAt:    time.Now(),
+		ID:              repository.IssueID(projectID, fingerprint),
+		ProjectID:       projectID,
+		Fingerprint:     fingerprint,
+		Message:         firstEvent.Message,
+		Level:           firstEvent.Level,
+		Status:          models.StatusUnresolved,
+		FirstSeen:       firstEvent.Timestamp,
+		LastSeen:        firstEvent.Timestamp,
+		EventCount:      uint64(len(events)),
+		UserCount:       uint64(len(userMap)),
+		Environments:    environments,
+		Tags:            firstEvent.Tags,
+		UpdatedAt:       time.Now(),
+		GroupingVersion: grouping.LatestVersion,
 	}
 
 	// Find latest timestamp
@@ -193,66 +643,65 @@ func (s *IngestService) createNewIssue(ctx context.Context, projectID uuid.UUID,
 		}
 	}
 
-	// Insert issue into ClickHouse
-	return s.insertIssue(ctx, issue)
-}
-
-// updateExistingIssue updates an existing issue with new events
-func (s *IngestService) updateExistingIssue(ctx context.Context, issue *models.Issue, events []*models.ErrorEvent) error {
-	// Update counters and timestamps
-	userMap := make(map[string]bool)
-	envMap := make(map[string]bool)
+	// The first event decides the owner
+	var rule *ownership.Rule
+	if s.ownership != nil {
+		issue.Assignee, rule = s.ownership.Assign(ctx, projectID, firstEvent)
+	}
 
-	// Add existing environments
-	for _, env := range issue.Environments {
-		envMap[env] = true
+	// Insert issue into ClickHouse
+	if err := s.issuesRepo.InsertIssue(ctx, issue); err != nil {
+		return err
 	}
 
-	latestTimestamp := issue.LastSeen
+	if s.activityRepo == nil {
+		return nil
+	}
+	activity := &models.IssueActivity{
+		ProjectID: projectID,
+		IssueID:   issue.ID,
+		Type:      models.ActivityFirstSeen,
+		Data: map[string]interface{}{
+			"event_id":    firstEvent.ID,
+			"environment": firstEvent.Environment,
+			"release":     firstEvent.ReleaseVersion,
+		},
+	}
+	if err := s.activityRepo.Create(ctx, activity); err != nil {
+		// The issue is created either way
+		log.Printf("Failed to record first event of issue %s: %v", issue.ID, err)
+	}
 
-	for _, event := range events {
-		if event.UserID != nil {
-			userMap[*event.UserID] = true
+	if rule != nil {
+		activity = &models.IssueActivity{
+			ProjectID: projectID,
+			IssueID:   issue.ID,
+			Type:      models.ActivityAssigned,
+			Data: map[string]interface{}{
+				"assignee": issue.Assignee.String(),
+				"rule":     rule.String(),
+				"line":     rule.Line,
+			},
 		}
-		envMap[event.Environment] = true
-
-		if event.Timestamp.After(latestTimestamp) {
-			latestTimestamp = event.Timestamp
+		if err := s.activityRepo.Create(ctx, activity); err != nil {
+			// The issue is assigned either way
+			log.Printf("Failed to record assignment of issue %s: %v", issue.ID, err)
 		}
 	}
-
-	// Update environments
-	environments := make([]string, 0, len(envMap))
-	for env := range envMap {
-		environments = append(environments, env)
-	}
-
-	// Update issue
-	updatedIssue := &models.Issue{
-		ID:           issue.ID,
-		ProjectID:    issue.ProjectID,
-		Fingerprint:  issue.Fingerprint,
-		Message:      issue.Message,
-		Level:        issue.Level,
-		Status:       issue.Status,
-		FirstSeen:    issue.FirstSeen,
-		LastSeen:     latestTimestamp,
-		EventCount:   issue.EventCount + uint64(len(events)),
-		UserCount:    issue.UserCount + uint64(len(userMap)),
-		Environments: environments,
-		Tags:         issue.Tags,
-		UpdatedAt:    time.Now(),
-	}
-
-	return s.updateIssue(ctx, updatedIssue)
-}
-
-// insertIssue inserts a new issue into ClickHouse
-func (s *IngestService) insertIssue(ctx context.Context, issue *models.Issue) error {
-	return s.issuesRepo.InsertIssue(ctx, issue)
+	return nil
 }
 
-// updateIssue updates an existing issue in ClickHouse
-func (s *IngestService) updateIssue(ctx context.Context, issue *models.Issue) error {
-	return s.issuesRepo.UpdateIssue(ctx, issue)
+// userKey identifies the user of an event by ID, then email, then IP address
+// like the issue_stats materialized view. It is empty for anonymous events.
+func userKey(event *models.ErrorEvent) string {
+	switch {
+	case event.UserID != nil && *event.UserID != "":
+		return "id:" + *event.UserID
+	case event.UserEmail != nil && *event.UserEmail != "":
+		return "email:" + *event.UserEmail
+	case event.UserIP != nil && *event.UserIP != "":
+		return "ip:" + *event.UserIP
+	default:
+		return ""
+	}
 }
@@ -0,0 +1,493 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrQueueFull is returned when the queue depth exceeds the configured maximum
+var ErrQueueFull = errors.New("ingest queue is full")
+
+// processTimeout bounds the processing of a single batch
+const processTimeout = 60 * time.Second
+
+// ackTimeout bounds acknowledging, requeueing and dead-lettering entries. They
+// get their own deadline so they still run when processing used up its own.
+const ackTimeout = 5 * time.Second
+
+// QueueStats describes the state of the ingest queue
+type QueueStats struct {
+	Depth      int64 `json:"depth"`       // Entries in the stream that are not yet acknowledged
+	Pending    int64 `json:"pending"`     // Entries delivered to a worker but not yet acknowledged
+	Lag        int64 `json:"lag"`         // Entries not yet delivered to any worker
+	DeadLetter int64 `json:"dead_letter"` // Entries that exhausted their retries
+	Workers    int   `json:"workers"`
+}
+
+// queuedBatch is the payload of a single stream entry. Entries hold either
+// received events or events that are stored already and only wait for their
+// issues to be processed.
+type queuedBatch struct {
+	ProjectID  uuid.UUID            `json:"project_id"`
+	Events     []models.IngestEvent `json:"events"`
+	Stored     []*models.ErrorEvent `json:"stored,omitempty"`
+	EnqueuedAt time.Time            `json:"enqueued_at"`
+}
+
+// batchProcessor stores queued events and processes their issues, see
+// IngestService
+type batchProcessor interface {
+	StoreEvents(ctx context.Context, projectID uuid.UUID, events []models.IngestEvent) ([]*models.ErrorEvent, error)
+	ProcessIssues(ctx context.Context, projectID uuid.UUID, events []*models.ErrorEvent) error
+}
+
+// IngestQueue is a durable ingest queue backed by a Redis stream. Requests
+// are acknowledged as soon as their events are appended to the stream, and a
+// pool of workers feeds them to the IngestService in batches.
+type IngestQueue struct {
+	client        *redis.Client
+	ingestService batchProcessor
+	config        *config.IngestConfig
+	consumer      string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewIngestQueue creates a new ingest queue
+func NewIngestQueue(redis *database.RedisDB, ingestService *IngestService, cfg *config.IngestConfig) *IngestQueue {
+	hostname, _ := os.Hostname()
+	return &IngestQueue{
+		client:        redis.Client(),
+		ingestService: ingestService,
+		config:        cfg,
+		consumer:      fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Enqueue durably appends the events to the queue
+func (q *IngestQueue) Enqueue(ctx context.Context, projectID uuid.UUID, events []models.IngestEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	depth, err := q.client.XLen(ctx, q.config.Stream).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get queue length: %w", err)
+	}
+	if q.config.MaxQueueLength > 0 && depth >= q.config.MaxQueueLength {
+		return ErrQueueFull
+	}
+
+	// Stamp events with the receive time so queueing delays don't shift them
+	now := time.Now()
+	for i := range events {
+		if events[i].Timestamp == nil {
+			events[i].Timestamp = &models.FlexibleTime{Time: now}
+		}
+	}
+
+	payload, err := json.Marshal(queuedBatch{
+		ProjectID:  projectID,
+		Events:     events,
+		EnqueuedAt: now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+
+	err = q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.config.Stream,
+		Values: map[string]interface{}{"batch": payload},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to enqueue events: %w", err)
+	}
+
+	return nil
+}
+
+// Start creates the consumer group and starts the workers
+func (q *IngestQueue) Start(ctx context.Context) error {
+	err := q.client.XGroupCreateMkStream(ctx, q.config.Stream, q.config.ConsumerGroup, "0").Err()
+	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	ctx, q.cancel = context.WithCancel(ctx)
+
+	for i := 0; i < q.config.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx, fmt.Sprintf("%s-%d", q.consumer, i))
+	}
+
+	q.wg.Add(1)
+	go q.reclaimer(ctx, q.consumer+"-reclaimer")
+
+	log.Printf("Started %d ingest queue workers on stream %s", q.config.Workers, q.config.Stream)
+	return nil
+}
+
+// Stop stops the workers and waits for in-flight batches to finish
+func (q *IngestQueue) Stop() {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	q.wg.Wait()
+}
+
+// Stats returns the current queue depth
+func (q *IngestQueue) Stats(ctx context.Context) (*QueueStats, error) {
+	stats := &QueueStats{Workers: q.config.Workers}
+
+	depth, err := q.client.XLen(ctx, q.config.Stream).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue length: %w", err)
+	}
+	stats.Depth = depth
+
+	groups, err := q.client.XInfoGroups(ctx, q.config.Stream).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get consumer groups: %w", err)
+	}
+	for _, group := range groups {
+		if group.Name == q.config.ConsumerGroup {
+			stats.Pending = group.Pending
+			stats.Lag = group.Lag
+		}
+	}
+
+	deadLetter, err := q.client.XLen(ctx, q.config.DeadLetterStream).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter length: %w", err)
+	}
+	stats.DeadLetter = deadLetter
+
+	return stats, nil
+}
+
+// worker reads new entries for the consumer group and processes them
+func (q *IngestQueue) worker(ctx context.Context, consumer string) {
+	defer q.wg.Done()
+
+	for ctx.Err() == nil {
+		streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    q.config.ConsumerGroup,
+			Consumer: consumer,
+			Streams:  []string{q.config.Stream, ">"},
+			Count:    int64(q.config.BatchSize),
+			Block:    q.config.BlockTimeout,
+		}).Result()
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				log.Printf("Ingest queue read failed: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		for _, stream := range streams {
+			q.processMessages(consumer, stream.Messages)
+		}
+	}
+}
+
+// reclaimer periodically retries entries that a worker failed to process or
+// that were left behind by a crashed replica, dead-lettering entries that
+// exhausted their retries
+func (q *IngestQueue) reclaimer(ctx context.Context, consumer string) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.config.ClaimIdle / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.reclaim(ctx, consumer); err != nil && ctx.Err() == nil {
+				log.Printf("Ingest queue reclaim failed: %v", err)
+			}
+		}
+	}
+}
+
+// reclaim claims idle pending entries and retries or dead-letters them
+func (q *IngestQueue) reclaim(ctx context.Context, consumer string) error {
+
+	pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: q.config.Stream,
+		Group:  q.config.ConsumerGroup,
+		Idle:   q.config.ClaimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  int64(q.config.BatchSize),
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to list pending entries: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var retryIDs, deadIDs []string
+	for _, entry := range pending {
+		if entry.RetryCount > int64(q.config.MaxRetries) {
+			deadIDs = append(deadIDs, entry.ID)
+		} else {
+			retryIDs = append(retryIDs, entry.ID)
+		}
+	}
+
+	if len(deadIDs) > 0 {
+		messages, err := q.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   q.config.Stream,
+			Group:    q.config.ConsumerGroup,
+			Consumer: consumer,
+			MinIdle:  q.config.ClaimIdle,
+			Messages: deadIDs,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("failed to claim entries: %w", err)
+		}
+		for _, message := range messages {
+			q.deadLetter(message, "max retries exceeded")
+		}
+	}
+
+	if len(retryIDs) > 0 {
+		messages, err := q.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   q.config.Stream,
+			Group:    q.config.ConsumerGroup,
+			Consumer: consumer,
+			MinIdle:  q.config.ClaimIdle,
+			Messages: retryIDs,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("failed to claim entries: %w", err)
+		}
+		q.processMessages(consumer, messages)
+	}
+
+	return nil
+}
+
+// processMessages groups entries by project and processes each group as a
+// single batch. Entries are acknowledged only after successful processing, so
+// failures are retried by the reclaimer. Events are inserted only once: when
+// their issues fail, the stored events replace the entries so that only the
+// issues are retried. The entries stay claimed by the consumer while they
+// are processed, so the reclaimer doesn't retry entries of slow batches.
+func (q *IngestQueue) processMessages(consumer string, messages []redis.XMessage) {
+	if len(messages) == 0 {
+		return
+	}
+
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	defer q.keepClaimed(consumer, ids)()
+
+	type projectBatch struct {
+		events    []models.IngestEvent
+		ids       []string
+		stored    []*models.ErrorEvent
+		storedIDs []string
+	}
+	batches := make(map[uuid.UUID]*projectBatch)
+
+	for _, message := range messages {
+		batch, err := decodeQueuedBatch(message)
+		if err != nil {
+			q.deadLetter(message, err.Error())
+			continue
+		}
+
+		group, ok := batches[batch.ProjectID]
+		if !ok {
+			group = &projectBatch{}
+			batches[batch.ProjectID] = group
+		}
+		if len(batch.Stored) > 0 {
+			group.stored = append(group.stored, batch.Stored...)
+			group.storedIDs = append(group.storedIDs, message.ID)
+		} else {
+			group.events = append(group.events, batch.Events...)
+			group.ids = append(group.ids, message.ID)
+		}
+	}
+
+	for projectID, batch := range batches {
+		ctx, cancel := context.WithTimeout(context.Background(), processTimeout)
+		if len(batch.storedIDs) > 0 {
+			q.processIssues(ctx, projectID, batch.stored, batch.storedIDs)
+		}
+		if len(batch.ids) > 0 {
+			q.processEvents(ctx, projectID, batch.events, batch.ids)
+		}
+		cancel()
+	}
+}
+
+// processEvents stores the events of entries and processes their issues
+func (q *IngestQueue) processEvents(ctx context.Context, projectID uuid.UUID, events []models.IngestEvent, ids []string) {
+	stored, err := q.ingestService.StoreEvents(ctx, projectID, events)
+	if err != nil {
+		log.Printf("Failed to store %d queued events for project %s: %v", len(events), projectID, err)
+		return
+	}
+
+	if err := q.ingestService.ProcessIssues(ctx, projectID, stored); err != nil {
+		log.Printf("Failed to process issues of %d queued events for project %s: %v", len(stored), projectID, err)
+		q.requeueIssues(projectID, stored, ids)
+		return
+	}
+	q.ack(ids...)
+}
+
+// processIssues retries the issues of entries whose events are stored
+func (q *IngestQueue) processIssues(ctx context.Context, projectID uuid.UUID, stored []*models.ErrorEvent, ids []string) {
+	if err := q.ingestService.ProcessIssues(ctx, projectID, stored); err != nil {
+		log.Printf("Failed to process issues of %d stored events for project %s: %v", len(stored), projectID, err)
+		return
+	}
+	q.ack(ids...)
+}
+
+// requeueIssues replaces entries whose events were stored but whose issues
+// failed with a single entry of the stored events. Both happen in one
+// transaction, so a retry never inserts the events again.
+func (q *IngestQueue) requeueIssues(projectID uuid.UUID, stored []*models.ErrorEvent, ids []string) {
+	payload, err := json.Marshal(queuedBatch{
+		ProjectID:  projectID,
+		Stored:     stored,
+		EnqueuedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to encode stored events for project %s: %v", projectID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+
+	pipe := q.client.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.config.Stream,
+		Values: map[string]interface{}{"batch": payload},
+	})
+	pipe.XAck(ctx, q.config.Stream, q.config.ConsumerGroup, ids...)
+	pipe.XDel(ctx, q.config.Stream, ids...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to requeue issues of ingest entries: %v", err)
+	}
+}
+
+// deadLetter moves an entry to the dead-letter stream
+func (q *IngestQueue) deadLetter(message redis.XMessage, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+
+	values := map[string]interface{}{
+		"original_id": message.ID,
+		"reason":      reason,
+		"failed_at":   time.Now().UTC().Format(time.RFC3339),
+	}
+	for key, value := range message.Values {
+		values[key] = value
+	}
+
+	err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.config.DeadLetterStream,
+		Values: values,
+	}).Err()
+	if err != nil {
+		log.Printf("Failed to dead-letter ingest entry %s: %v", message.ID, err)
+		return
+	}
+
+	log.Printf("Dead-lettered ingest entry %s: %s", message.ID, reason)
+	q.ack(message.ID)
+}
+
+// ack acknowledges and deletes processed entries so the stream length
+// reflects the queue depth
+func (q *IngestQueue) ack(ids ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+
+	pipe := q.client.TxPipeline()
+	pipe.XAck(ctx, q.config.Stream, q.config.ConsumerGroup, ids...)
+	pipe.XDel(ctx, q.config.Stream, ids...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to acknowledge ingest entries: %v", err)
+	}
+}
+
+// keepClaimed claims entries for the consumer again every third of the claim
+// idle time, which resets their idle time without counting a delivery, until
+// the returned function is called. Acknowledged entries are skipped by the
+// claim.
+func (q *IngestQueue) keepClaimed(consumer string, ids []string) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(q.config.ClaimIdle / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := q.client.XClaimJustID(ctx, &redis.XClaimArgs{
+					Stream:   q.config.Stream,
+					Group:    q.config.ConsumerGroup,
+					Consumer: consumer,
+					Messages: ids,
+				}).Err()
+				if err != nil && ctx.Err() == nil {
+					log.Printf("Failed to keep ingest entries claimed: %v", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// decodeQueuedBatch decodes a stream entry
+func decodeQueuedBatch(message redis.XMessage) (*queuedBatch, error) {
+	raw, ok := message.Values["batch"].(string)
+	if !ok {
+		return nil, fmt.Errorf("entry has no batch payload")
+	}
+
+	var batch queuedBatch
+	if err := json.Unmarshal([]byte(raw), &batch); err != nil {
+		return nil, fmt.Errorf("invalid batch payload: %w", err)
+	}
+	return &batch, nil
+}
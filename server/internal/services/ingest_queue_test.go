@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"server/internal/config"
+	"server/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeProcessor records the batches the queue processes
+type fakeProcessor struct {
+	mu            sync.Mutex
+	storeErr      error
+	storeDelay    time.Duration
+	issueFailures int // ProcessIssues calls that fail before one succeeds
+	storeCalls    int
+	issueCalls    int
+	stored        []*models.ErrorEvent
+}
+
+func (p *fakeProcessor) StoreEvents(ctx context.Context, projectID uuid.UUID, events []models.IngestEvent) ([]*models.ErrorEvent, error) {
+	time.Sleep(p.storeDelay)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.storeCalls++
+	if p.storeErr != nil {
+		return nil, p.storeErr
+	}
+
+	var stored []*models.ErrorEvent
+	for _, event := range events {
+		stored = append(stored, &models.ErrorEvent{
+			ID:          uuid.New().String(),
+			ProjectID:   projectID,
+			Message:     event.Message,
+			Fingerprint: event.Message,
+		})
+	}
+	p.stored = append(p.stored, stored...)
+	return stored, nil
+}
+
+func (p *fakeProcessor) ProcessIssues(ctx context.Context, projectID uuid.UUID, events []*models.ErrorEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.issueCalls++
+	if p.issueFailures > 0 {
+		p.issueFailures--
+		return errors.New("issues unavailable")
+	}
+	return nil
+}
+
+func newTestQueue(t *testing.T, processor batchProcessor) (*IngestQueue, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	q := &IngestQueue{
+		client:        client,
+		ingestService: processor,
+		config: &config.IngestConfig{
+			Stream:           "ingest",
+			DeadLetterStream: "ingest:dead",
+			ConsumerGroup:    "workers",
+			BatchSize:        10,
+			ClaimIdle:        time.Minute,
+			MaxRetries:       2,
+			MaxQueueLength:   2,
+		},
+		consumer: "test",
+	}
+	err := client.XGroupCreateMkStream(context.Background(), q.config.Stream, q.config.ConsumerGroup, "0").Err()
+	if err != nil {
+		t.Fatalf("Failed to create consumer group: %v", err)
+	}
+	return q, server
+}
+
+// readNew delivers the entries no worker has seen yet, like a worker does
+func readNew(t *testing.T, q *IngestQueue) []redis.XMessage {
+	t.Helper()
+
+	streams, err := q.client.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    q.config.ConsumerGroup,
+		Consumer: "test-0",
+		Streams:  []string{q.config.Stream, ">"},
+		Count:    int64(q.config.BatchSize),
+		Block:    -1,
+	}).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("Failed to read entries: %v", err)
+	}
+	return streams[0].Messages
+}
+
+func queueStats(t *testing.T, q *IngestQueue) *QueueStats {
+	t.Helper()
+
+	stats, err := q.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get queue stats: %v", err)
+	}
+	return stats
+}
+
+func TestIngestQueue_Enqueue(t *testing.T) {
+	q, _ := newTestQueue(t, &fakeProcessor{})
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	sent := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	events := []models.IngestEvent{
+		{Message: "stamped"},
+		{Message: "sent", Timestamp: &models.FlexibleTime{Time: sent}},
+	}
+	if err := q.Enqueue(ctx, projectID, events); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	messages := readNew(t, q)
+	if len(messages) != 1 {
+		t.Fatalf("Expected one entry per request, got %d", len(messages))
+	}
+	batch, err := decodeQueuedBatch(messages[0])
+	if err != nil {
+		t.Fatalf("Failed to decode entry: %v", err)
+	}
+	if batch.ProjectID != projectID || len(batch.Events) != 2 {
+		t.Fatalf("Expected both events of the project, got %+v", batch)
+	}
+	if batch.Events[0].Timestamp == nil {
+		t.Error("Expected events without a timestamp to be stamped with the receive time")
+	}
+	if !batch.Events[1].Timestamp.Time.Equal(sent) {
+		t.Errorf("Expected the sent timestamp to be kept, got %v", batch.Events[1].Timestamp.Time)
+	}
+
+	if err := q.Enqueue(ctx, projectID, events); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := q.Enqueue(ctx, projectID, events); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull at the maximum queue length, got %v", err)
+	}
+}
+
+func TestIngestQueue_RetriesOnlyIssuesOfStoredEvents(t *testing.T) {
+	processor := &fakeProcessor{issueFailures: 1}
+	q, _ := newTestQueue(t, processor)
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	if err := q.Enqueue(ctx, projectID, []models.IngestEvent{{Message: "a"}}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := q.Enqueue(ctx, projectID, []models.IngestEvent{{Message: "b"}}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	q.processMessages("test-0", readNew(t, q))
+
+	if processor.storeCalls != 1 || processor.issueCalls != 1 {
+		t.Fatalf("Expected one store and one issue call, got %d and %d", processor.storeCalls, processor.issueCalls)
+	}
+
+	// The received entries are replaced by one entry of the stored events
+	if stats := queueStats(t, q); stats.Depth != 1 || stats.Pending != 0 {
+		t.Fatalf("Expected only the requeued entry, got %+v", stats)
+	}
+	messages := readNew(t, q)
+	if len(messages) != 1 {
+		t.Fatalf("Expected the requeued entry to be delivered, got %d entries", len(messages))
+	}
+	batch, err := decodeQueuedBatch(messages[0])
+	if err != nil {
+		t.Fatalf("Failed to decode entry: %v", err)
+	}
+	if len(batch.Stored) != 2 || batch.Stored[0].ID != processor.stored[0].ID {
+		t.Fatalf("Expected the stored events to be requeued, got %+v", batch)
+	}
+
+	q.processMessages("test-0", messages)
+
+	if processor.storeCalls != 1 {
+		t.Errorf("Expected stored events not to be inserted again, got %d store calls", processor.storeCalls)
+	}
+	if processor.issueCalls != 2 {
+		t.Errorf("Expected the issues to be retried, got %d issue calls", processor.issueCalls)
+	}
+	if stats := queueStats(t, q); stats.Depth != 0 || stats.Pending != 0 {
+		t.Errorf("Expected the queue to be drained, got %+v", stats)
+	}
+}
+
+func TestIngestQueue_ReclaimRetriesAndDeadLetters(t *testing.T) {
+	processor := &fakeProcessor{storeErr: errors.New("clickhouse unavailable")}
+	q, server := newTestQueue(t, processor)
+	ctx := context.Background()
+
+	// Idle times are measured on the server clock
+	now := time.Now()
+	server.SetTime(now)
+
+	if err := q.Enqueue(ctx, uuid.New(), []models.IngestEvent{{Message: "a"}}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	q.processMessages("test-0", readNew(t, q))
+
+	if stats := queueStats(t, q); stats.Pending != 1 {
+		t.Fatalf("Expected the failed entry to stay pending, got %+v", stats)
+	}
+
+	// Entries are only reclaimed once idle
+	if err := q.reclaim(ctx, "test-reclaimer"); err != nil {
+		t.Fatalf("Reclaim failed: %v", err)
+	}
+	if processor.storeCalls != 1 {
+		t.Fatalf("Expected a busy entry not to be reclaimed, got %d store calls", processor.storeCalls)
+	}
+
+	// Delivered once by the worker and then retried up to MaxRetries
+	for i := 0; i < q.config.MaxRetries; i++ {
+		now = now.Add(2 * q.config.ClaimIdle)
+		server.SetTime(now)
+		if err := q.reclaim(ctx, "test-reclaimer"); err != nil {
+			t.Fatalf("Reclaim failed: %v", err)
+		}
+	}
+	if processor.storeCalls != 1+q.config.MaxRetries {
+		t.Fatalf("Expected %d attempts, got %d", 1+q.config.MaxRetries, processor.storeCalls)
+	}
+
+	now = now.Add(2 * q.config.ClaimIdle)
+	server.SetTime(now)
+	if err := q.reclaim(ctx, "test-reclaimer"); err != nil {
+		t.Fatalf("Reclaim failed: %v", err)
+	}
+	if processor.storeCalls != 1+q.config.MaxRetries {
+		t.Errorf("Expected no attempt after the retries ran out, got %d", processor.storeCalls)
+	}
+
+	stats := queueStats(t, q)
+	if stats.Depth != 0 || stats.Pending != 0 || stats.DeadLetter != 1 {
+		t.Fatalf("Expected the entry to be dead-lettered, got %+v", stats)
+	}
+
+	dead, err := q.client.XRange(ctx, q.config.DeadLetterStream, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("Failed to read dead letters: %v", err)
+	}
+	if dead[0].Values["reason"] != "max retries exceeded" || dead[0].Values["batch"] == nil {
+		t.Errorf("Expected the entry with its reason, got %v", dead[0].Values)
+	}
+}
+
+func TestIngestQueue_KeepsEntriesClaimedWhileProcessing(t *testing.T) {
+	processor := &fakeProcessor{storeDelay: 300 * time.Millisecond}
+	q, _ := newTestQueue(t, processor)
+	q.config.ClaimIdle = 90 * time.Millisecond
+	ctx := context.Background()
+
+	if err := q.Enqueue(ctx, uuid.New(), []models.IngestEvent{{Message: "a"}}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	messages := readNew(t, q)
+
+	done := make(chan struct{})
+	go func() {
+		q.processMessages("test-0", messages)
+		close(done)
+	}()
+
+	// The batch takes several times the claim idle time
+	for i := 0; i < 5; i++ {
+		time.Sleep(50 * time.Millisecond)
+		if err := q.reclaim(ctx, "test-reclaimer"); err != nil {
+			t.Fatalf("Reclaim failed: %v", err)
+		}
+	}
+	<-done
+
+	if processor.storeCalls != 1 {
+		t.Errorf("Expected entries in flight not to be reclaimed, got %d store calls", processor.storeCalls)
+	}
+	if stats := queueStats(t, q); stats.Depth != 0 || stats.Pending != 0 {
+		t.Errorf("Expected the queue to be drained, got %+v", stats)
+	}
+}
+
+func TestIngestQueue_DeadLettersUndecodableEntries(t *testing.T) {
+	processor := &fakeProcessor{}
+	q, _ := newTestQueue(t, processor)
+	ctx := context.Background()
+
+	err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.config.Stream,
+		Values: map[string]interface{}{"batch": "{"},
+	}).Err()
+	if err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	q.processMessages("test-0", readNew(t, q))
+
+	if processor.storeCalls != 0 {
+		t.Errorf("Expected undecodable entries not to be processed, got %d store calls", processor.storeCalls)
+	}
+	if stats := queueStats(t, q); stats.Depth != 0 || stats.Pending != 0 || stats.DeadLetter != 1 {
+		t.Errorf("Expected the entry to be dead-lettered, got %+v", stats)
+	}
+}
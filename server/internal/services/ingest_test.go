@@ -0,0 +1,470 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"server/internal/grouping"
+	"server/internal/models"
+	"server/internal/spool"
+
+	"github.com/google/uuid"
+)
+
+// fakeEventStore keeps inserted events in memory
+type fakeEventStore struct {
+	insertErr        func(events []*models.ErrorEvent) error
+	inserted         []*models.ErrorEvent
+	releaseFirstSeen map[string]time.Time
+	countErr         error
+}
+
+func (f *fakeEventStore) InsertEvents(ctx context.Context, events []*models.ErrorEvent) error {
+	if f.insertErr != nil {
+		if err := f.insertErr(events); err != nil {
+			return err
+		}
+	}
+	f.inserted = append(f.inserted, events...)
+	return nil
+}
+
+func (f *fakeEventStore) GetReleaseFirstSeen(ctx context.Context, projectID uuid.UUID, releases []string) (map[string]time.Time, error) {
+	return f.releaseFirstSeen, nil
+}
+
+func (f *fakeEventStore) CountIssueEvents(ctx context.Context, projectID uuid.UUID, fingerprints []string, since time.Time) (uint64, error) {
+	return 0, f.countErr
+}
+
+// fakeIssueStore keeps issues in memory by fingerprint
+type fakeIssueStore struct {
+	issues   map[string]*models.Issue
+	inserted []*models.Issue
+	ignored  []*models.Issue // Conditionally ignored issues
+}
+
+func (f *fakeIssueStore) GetIssuesByFingerprints(ctx context.Context, projectID uuid.UUID, fingerprints []string) (map[string]*models.Issue, error) {
+	issues := make(map[string]*models.Issue)
+	for _, fingerprint := range fingerprints {
+		if issue, ok := f.issues[fingerprint]; ok {
+			issues[fingerprint] = issue
+		}
+	}
+	return issues, nil
+}
+
+func (f *fakeIssueStore) GetGroupingVersions(ctx context.Context, projectID uuid.UUID, fingerprints []string) (map[string]uint8, error) {
+	return map[string]uint8{}, nil
+}
+
+func (f *fakeIssueStore) GetConditionallyIgnoredIssues(ctx context.Context) ([]*models.Issue, error) {
+	return f.ignored, nil
+}
+
+func (f *fakeIssueStore) InsertIssue(ctx context.Context, issue *models.Issue) error {
+	if f.issues == nil {
+		f.issues = make(map[string]*models.Issue)
+	}
+	f.issues[issue.Fingerprint] = issue
+	f.inserted = append(f.inserted, issue)
+	return nil
+}
+
+// MarkRegressed reopens the issue like the ALTER TABLE UPDATE of the
+// repository, which only applies to resolved issues
+func (f *fakeIssueStore) MarkRegressed(ctx context.Context, issueID string, regressedAt time.Time, release string) error {
+	for _, issue := range f.issues {
+		if issue.ID != issueID || issue.Status != models.StatusResolved {
+			continue
+		}
+		issue.Status = models.StatusUnresolved
+		issue.Substatus = models.SubstatusRegressed
+		issue.Resolution = ""
+		issue.ResolvedInRelease = ""
+		issue.ResolvedAt = nil
+		issue.RegressedAt = &regressedAt
+		issue.RegressedInRelease = release
+	}
+	return nil
+}
+
+func (f *fakeIssueStore) Unignore(ctx context.Context, issueID string) (bool, error) {
+	for _, issue := range f.ignored {
+		if issue.ID == issueID && issue.Status == models.StatusIgnored {
+			issue.Status = models.StatusUnresolved
+			issue.Substatus = models.SubstatusEscalating
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fakeActivityLog keeps recorded activity in memory
+type fakeActivityLog struct {
+	activities []*models.IssueActivity
+}
+
+func (f *fakeActivityLog) Create(ctx context.Context, activity *models.IssueActivity) error {
+	f.activities = append(f.activities, activity)
+	return nil
+}
+
+// testIngestService wires an ingest service to in-memory stores. ClickHouse
+// is reported as down while clickhouseDown is set.
+type testIngestService struct {
+	*IngestService
+	events         *fakeEventStore
+	issues         *fakeIssueStore
+	activity       *fakeActivityLog
+	clickhouseDown bool
+}
+
+func newTestIngestService(t *testing.T, withSpool bool) *testIngestService {
+	t.Helper()
+
+	ts := &testIngestService{
+		events:   &fakeEventStore{},
+		issues:   &fakeIssueStore{},
+		activity: &fakeActivityLog{},
+	}
+	ts.IngestService = &IngestService{
+		eventsRepo:   ts.events,
+		issuesRepo:   ts.issues,
+		activityRepo: ts.activity,
+		health: func() error {
+			if ts.clickhouseDown {
+				return errors.New("clickhouse down")
+			}
+			return nil
+		},
+	}
+
+	if withSpool {
+		eventSpool, err := spool.Open(spool.Options{Dir: t.TempDir(), SegmentSize: 1 << 20})
+		if err != nil {
+			t.Fatalf("Failed to open spool: %v", err)
+		}
+		t.Cleanup(func() { eventSpool.Close() })
+		ts.spool = eventSpool
+	}
+	return ts
+}
+
+func TestProcessEvents_StoresEventsAndCreatesIssues(t *testing.T) {
+	ts := newTestIngestService(t, false)
+	projectID := uuid.New()
+
+	events := []models.IngestEvent{{Message: "Payment declined"}, {Message: "Payment declined"}}
+	if err := ts.ProcessEvents(context.Background(), projectID, events); err != nil {
+		t.Fatalf("ProcessEvents failed: %v", err)
+	}
+
+	if len(ts.events.inserted) != 2 {
+		t.Fatalf("Expected 2 stored events, got %d", len(ts.events.inserted))
+	}
+	if len(ts.issues.inserted) != 1 {
+		t.Fatalf("Expected one issue for both events, got %d", len(ts.issues.inserted))
+	}
+	issue := ts.issues.inserted[0]
+	if issue.EventCount != 2 || issue.Fingerprint != ts.events.inserted[0].Fingerprint {
+		t.Errorf("Expected the issue of both events, got %+v", issue)
+	}
+	if len(ts.activity.activities) != 1 || ts.activity.activities[0].Type != models.ActivityFirstSeen {
+		t.Errorf("Expected the first event to be recorded, got %+v", ts.activity.activities)
+	}
+}
+
+// resolvedIssue adds a resolved issue for the fingerprint of a message
+func (ts *testIngestService) resolvedIssue(t *testing.T, projectID uuid.UUID, message string, resolution models.IssueResolution, release string) *models.Issue {
+	t.Helper()
+
+	ingestEvent := models.IngestEvent{Message: message}
+	issue := &models.Issue{
+		ID:                uuid.New().String(),
+		ProjectID:         projectID,
+		Fingerprint:       grouping.Fingerprint(&ingestEvent),
+		Message:           message,
+		Status:            models.StatusResolved,
+		Resolution:        resolution,
+		ResolvedInRelease: release,
+	}
+	ts.issues.issues = map[string]*models.Issue{issue.Fingerprint: issue}
+	return issue
+}
+
+func TestProcessEvents_ReopensResolvedIssues(t *testing.T) {
+	ts := newTestIngestService(t, false)
+	ctx := context.Background()
+	projectID := uuid.New()
+	issue := ts.resolvedIssue(t, projectID, "Payment declined", "", "")
+
+	late, early := time.Now(), time.Now().Add(-time.Minute)
+	v2, v1 := "2.0.0", "1.0.0"
+	events := []models.IngestEvent{
+		{Message: "Payment declined", ReleaseVersion: &v2, Timestamp: &models.FlexibleTime{Time: late}},
+		{Message: "Payment declined", ReleaseVersion: &v1, Timestamp: &models.FlexibleTime{Time: early}},
+	}
+	if err := ts.ProcessEvents(ctx, projectID, events); err != nil {
+		t.Fatalf("ProcessEvents failed: %v", err)
+	}
+
+	if issue.Status != models.StatusUnresolved || issue.Substatus != models.SubstatusRegressed {
+		t.Fatalf("Expected the issue to be unresolved as regressed, got %s/%s", issue.Status, issue.Substatus)
+	}
+	// The earliest event decides when and in which release the issue regressed
+	if issue.RegressedInRelease != v1 {
+		t.Errorf("Expected the issue to regress in %s, got %q", v1, issue.RegressedInRelease)
+	}
+	if issue.RegressedAt == nil || !issue.RegressedAt.Equal(early) {
+		t.Errorf("Expected the issue to regress at %v, got %v", early, issue.RegressedAt)
+	}
+
+	if len(ts.activity.activities) != 1 {
+		t.Fatalf("Expected one regression entry, got %+v", ts.activity.activities)
+	}
+	activity := ts.activity.activities[0]
+	if activity.Type != models.ActivityRegression || activity.IssueID != issue.ID {
+		t.Errorf("Expected a regression entry of the issue, got %+v", activity)
+	}
+	var firstEvent *models.ErrorEvent
+	for _, event := range ts.events.inserted {
+		if event.Timestamp.Equal(early) {
+			firstEvent = event
+		}
+	}
+	if firstEvent == nil || activity.Data["event_id"] != firstEvent.ID || activity.Data["release"] != v1 {
+		t.Errorf("Expected the earliest event and its release, got %v", activity.Data)
+	}
+}
+
+func TestProcessEvents_KeepsIssuesResolvedAfterLateEvents(t *testing.T) {
+	ts := newTestIngestService(t, false)
+	ctx := context.Background()
+	projectID := uuid.New()
+	issue := ts.resolvedIssue(t, projectID, "Payment declined", "", "")
+	resolvedAt := time.Now().Add(-time.Minute)
+	issue.ResolvedAt = &resolvedAt
+
+	// Events from before the resolution, e.g. replayed from the spool
+	late := []models.IngestEvent{{Message: "Payment declined", Timestamp: &models.FlexibleTime{Time: resolvedAt.Add(-time.Hour)}}}
+	if err := ts.ProcessEvents(ctx, projectID, late); err != nil {
+		t.Fatalf("ProcessEvents failed: %v", err)
+	}
+	if issue.Status != models.StatusResolved || len(ts.activity.activities) != 0 {
+		t.Fatalf("Expected events from before the resolution not to reopen the issue, got %s", issue.Status)
+	}
+
+	if err := ts.ProcessEvents(ctx, projectID, []models.IngestEvent{{Message: "Payment declined"}}); err != nil {
+		t.Fatalf("ProcessEvents failed: %v", err)
+	}
+	if issue.Status != models.StatusUnresolved || issue.ResolvedAt != nil {
+		t.Errorf("Expected a new event to reopen the issue, got %s", issue.Status)
+	}
+}
+
+func TestProcessEvents_ReopensIssuesResolvedInRelease(t *testing.T) {
+	older, resolved, newer := "1.0.0", "1.1.0", "1.2.0"
+
+	tests := []struct {
+		name       string
+		resolution models.IssueResolution
+		release    *string
+		expected   bool
+	}{
+		{"older release", models.ResolutionInRelease, &older, false},
+		{"same release", models.ResolutionInRelease, &resolved, true},
+		{"newer release", models.ResolutionInRelease, &newer, true},
+		{"without release", models.ResolutionInRelease, nil, false},
+		{"next release, same release", models.ResolutionInNextRelease, &resolved, false},
+		{"next release, newer release", models.ResolutionInNextRelease, &newer, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestIngestService(t, false)
+			projectID := uuid.New()
+			issue := ts.resolvedIssue(t, projectID, "Payment declined", tt.resolution, resolved)
+
+			events := []models.IngestEvent{{Message: "Payment declined", ReleaseVersion: tt.release}}
+			if err := ts.ProcessEvents(context.Background(), projectID, events); err != nil {
+				t.Fatalf("ProcessEvents failed: %v", err)
+			}
+
+			if regressed := issue.Status == models.StatusUnresolved; regressed != tt.expected {
+				t.Fatalf("Expected regressed to be %v, got status %s", tt.expected, issue.Status)
+			}
+			if !tt.expected {
+				if issue.Resolution != tt.resolution || len(ts.activity.activities) != 0 {
+					t.Errorf("Expected the issue to stay resolved without activity, got %+v", ts.activity.activities)
+				}
+				return
+			}
+			if issue.RegressedInRelease != *tt.release || issue.ResolvedInRelease != "" {
+				t.Errorf("Expected the issue to regress in %s, got %+v", *tt.release, issue)
+			}
+		})
+	}
+}
+
+func TestProcessEvents_SpoolsOnlyWhileClickHouseIsDown(t *testing.T) {
+	insertErr := func(events []*models.ErrorEvent) error { return errors.New("insert failed") }
+
+	t.Run("down", func(t *testing.T) {
+		ts := newTestIngestService(t, true)
+		ts.events.insertErr = insertErr
+		ts.clickhouseDown = true
+
+		if err := ts.ProcessEvents(context.Background(), uuid.New(), []models.IngestEvent{{Message: "a"}}); err != nil {
+			t.Fatalf("Expected events to be spooled, got %v", err)
+		}
+		if ts.spool.Empty() {
+			t.Error("Expected events in the spool")
+		}
+		if len(ts.issues.inserted) != 0 {
+			t.Error("Expected issues to wait for the replay")
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		ts := newTestIngestService(t, true)
+		ts.events.insertErr = insertErr
+
+		if err := ts.ProcessEvents(context.Background(), uuid.New(), []models.IngestEvent{{Message: "a"}}); err == nil {
+			t.Fatal("Expected the insert error while ClickHouse is up")
+		}
+		if !ts.spool.Empty() {
+			t.Error("Expected rejected events not to be spooled")
+		}
+	})
+}
+
+func TestProcessEvents_KeepsOrderBehindSpooledEvents(t *testing.T) {
+	ts := newTestIngestService(t, true)
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	ts.clickhouseDown = true
+	ts.events.insertErr = func(events []*models.ErrorEvent) error { return errors.New("connection refused") }
+	if err := ts.ProcessEvents(ctx, projectID, []models.IngestEvent{{Message: "first"}}); err != nil {
+		t.Fatalf("ProcessEvents failed: %v", err)
+	}
+
+	// ClickHouse is back, but the spool hasn't been replayed yet
+	ts.clickhouseDown = false
+	ts.events.insertErr = nil
+	if err := ts.ProcessEvents(ctx, projectID, []models.IngestEvent{{Message: "second"}}); err != nil {
+		t.Fatalf("ProcessEvents failed: %v", err)
+	}
+	if len(ts.events.inserted) != 0 {
+		t.Fatalf("Expected new events to be spooled behind the earlier ones, got %d inserted", len(ts.events.inserted))
+	}
+
+	replayed, err := ts.ReplaySpool(ctx)
+	if err != nil || replayed != 2 {
+		t.Fatalf("Expected 2 replayed batches, got %d (%v)", replayed, err)
+	}
+	if len(ts.events.inserted) != 2 || ts.events.inserted[0].Message != "first" || ts.events.inserted[1].Message != "second" {
+		t.Fatalf("Expected the events in the order they were received, got %+v", ts.events.inserted)
+	}
+	if len(ts.issues.inserted) != 2 {
+		t.Errorf("Expected the issues of replayed events, got %d", len(ts.issues.inserted))
+	}
+	if !ts.spool.Empty() {
+		t.Error("Expected the spool to be empty after the replay")
+	}
+}
+
+func TestReplaySpool_DropsRejectedBatches(t *testing.T) {
+	ts := newTestIngestService(t, true)
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	ts.clickhouseDown = true
+	ts.events.insertErr = func(events []*models.ErrorEvent) error { return errors.New("connection refused") }
+	for _, message := range []string{"invalid", "valid"} {
+		if err := ts.ProcessEvents(ctx, projectID, []models.IngestEvent{{Message: message}}); err != nil {
+			t.Fatalf("ProcessEvents failed: %v", err)
+		}
+	}
+
+	ts.clickhouseDown = false
+	ts.events.insertErr = func(events []*models.ErrorEvent) error {
+		if events[0].Message == "invalid" {
+			return errors.New("cannot parse input")
+		}
+		return nil
+	}
+
+	replayed, err := ts.ReplaySpool(ctx)
+	if err != nil || replayed != 2 {
+		t.Fatalf("Expected the replay to pass the rejected batch, got %d (%v)", replayed, err)
+	}
+	if len(ts.events.inserted) != 1 || ts.events.inserted[0].Message != "valid" {
+		t.Errorf("Expected only the valid batch to be stored, got %+v", ts.events.inserted)
+	}
+	if !ts.spool.Empty() {
+		t.Error("Expected the rejected batch to be dropped")
+	}
+}
+
+func TestReplaySpool_StopsWhileClickHouseIsDown(t *testing.T) {
+	ts := newTestIngestService(t, true)
+	ctx := context.Background()
+
+	ts.clickhouseDown = true
+	ts.events.insertErr = func(events []*models.ErrorEvent) error { return errors.New("connection refused") }
+	if err := ts.ProcessEvents(ctx, uuid.New(), []models.IngestEvent{{Message: "a"}}); err != nil {
+		t.Fatalf("ProcessEvents failed: %v", err)
+	}
+
+	replayed, err := ts.ReplaySpool(ctx)
+	if err == nil || replayed != 0 {
+		t.Fatalf("Expected the replay to stop, got %d (%v)", replayed, err)
+	}
+	if ts.spool.Empty() {
+		t.Error("Expected the batch to be kept for the next replay")
+	}
+}
+
+func TestUserKey(t *testing.T) {
+	id, email, ip, empty := "42", "ana@example.com", "10.0.0.1", ""
+
+	tests := []struct {
+		name     string
+		event    models.ErrorEvent
+		expected string
+	}{
+		{"id first", models.ErrorEvent{UserID: &id, UserEmail: &email, UserIP: &ip}, "id:42"},
+		{"email without id", models.ErrorEvent{UserID: &empty, UserEmail: &email, UserIP: &ip}, "email:ana@example.com"},
+		{"ip last", models.ErrorEvent{UserIP: &ip}, "ip:10.0.0.1"},
+		{"anonymous", models.ErrorEvent{UserID: &empty}, ""},
+	}
+
+	for _, tt := range tests {
+		if key := userKey(&tt.event); key != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, key)
+		}
+	}
+}
+
+func TestProcessEvents_CountsUniqueUsersOfNewIssues(t *testing.T) {
+	ts := newTestIngestService(t, false)
+	ana, bob, empty := "ana@example.com", "bob@example.com", ""
+
+	events := []models.IngestEvent{
+		{Message: "boom", UserEmail: &ana},
+		{Message: "boom", UserEmail: &ana},
+		{Message: "boom", UserEmail: &bob},
+		{Message: "boom", UserID: &empty},
+	}
+	if err := ts.ProcessEvents(context.Background(), uuid.New(), events); err != nil {
+		t.Fatalf("ProcessEvents failed: %v", err)
+	}
+
+	if len(ts.issues.inserted) != 1 || ts.issues.inserted[0].UserCount != 2 {
+		t.Errorf("Expected 2 users on the new issue, got %+v", ts.issues.inserted)
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"server/internal/models"
+	"server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// IssueMerger merges issues that grouping split apart into one issue and
+// splits them out again
+type IssueMerger struct {
+	issuesRepo   *repository.IssuesRepository
+	activityRepo *repository.ActivityRepository
+}
+
+// NewIssueMerger creates a new issue merger. Without an activity repository
+// merges aren't logged.
+func NewIssueMerger(issuesRepo *repository.IssuesRepository, activityRepo *repository.ActivityRepository) *IssueMerger {
+	return &IssueMerger{
+		issuesRepo:   issuesRepo,
+		activityRepo: activityRepo,
+	}
+}
+
+// Merge merges issues into a primary issue of the same project. The primary
+// counts the events of the merged issues from then on, including their past
+// events, and receives their future events. When the statuses of the issues
+// differ the primary becomes unresolved. apiKeyID is the key that made the
+// change.
+func (m *IssueMerger) Merge(ctx context.Context, primary *models.Issue, issues []*models.Issue, apiKeyID *uuid.UUID) error {
+	var issueIDs, fingerprints []string
+	for _, issue := range issues {
+		if issue.ID == primary.ID {
+			continue
+		}
+		issueIDs = append(issueIDs, issue.ID)
+		fingerprints = append(fingerprints, issue.Fingerprints()...)
+	}
+	if len(issueIDs) == 0 {
+		return nil
+	}
+
+	if err := m.issuesRepo.MergeIssues(ctx, primary.ProjectID, primary.ID, issueIDs, fingerprints); err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"issue_ids":    issueIDs,
+		"fingerprints": fingerprints,
+	}
+	status := models.MergedStatus(append([]*models.Issue{primary}, issues...))
+	if status != primary.Status {
+		if err := m.issuesRepo.UpdateIssueStatus(ctx, primary.ID, &models.StatusUpdate{Status: status}); err != nil {
+			return err
+		}
+		data["status"] = string(status)
+		data["previous"] = string(primary.Status)
+	}
+
+	m.record(ctx, primary, models.ActivityMerge, apiKeyID, data)
+	return nil
+}
+
+// Unmerge splits fingerprints merged into an issue out of it. The issues
+// they belonged to are restored as unresolved issues and receive their events
+// again. The fingerprints must have been merged into the issue.
+func (m *IssueMerger) Unmerge(ctx context.Context, primary *models.Issue, fingerprints []string, apiKeyID *uuid.UUID) error {
+	if err := m.issuesRepo.UnmergeFingerprints(ctx, primary.ProjectID, primary.ID, fingerprints); err != nil {
+		return err
+	}
+
+	m.record(ctx, primary, models.ActivityUnmerge, apiKeyID, map[string]interface{}{
+		"fingerprints": fingerprints,
+	})
+	return nil
+}
+
+// record adds an entry to the activity log of an issue. Failures are logged
+// only, since the change was made either way.
+func (m *IssueMerger) record(ctx context.Context, issue *models.Issue, activityType models.ActivityType, apiKeyID *uuid.UUID, data map[string]interface{}) {
+	if m.activityRepo == nil {
+		return
+	}
+	activity := &models.IssueActivity{
+		ProjectID: issue.ProjectID,
+		IssueID:   issue.ID,
+		Type:      activityType,
+		APIKeyID:  apiKeyID,
+		Data:      data,
+	}
+	if err := m.activityRepo.Create(ctx, activity); err != nil {
+		log.Printf("Failed to record %s of issue %s: %v", activityType, issue.ID, err)
+	}
+}
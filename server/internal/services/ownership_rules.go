@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"server/internal/models"
+	"server/internal/ownership"
+	"server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// OwnershipRules loads the ownership rules of projects with their owners
+// resolved to users and teams, and keeps them for a short time so ingest
+// doesn't read project settings for every new issue
+type OwnershipRules struct {
+	projectsRepo  *repository.ProjectsRepository
+	assigneesRepo *repository.AssigneesRepository
+	ttl           time.Duration
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]ownershipRulesEntry
+}
+
+// ownershipRulesEntry holds the cached rules of a project and their owners
+type ownershipRulesEntry struct {
+	rules    []ownership.Rule
+	owners   map[ownership.Owner]*models.Assignee
+	loadedAt time.Time
+}
+
+// NewOwnershipRules creates a new rules loader. Rule changes take effect
+// after at most ttl.
+func NewOwnershipRules(projectsRepo *repository.ProjectsRepository, assigneesRepo *repository.AssigneesRepository, ttl time.Duration) *OwnershipRules {
+	return &OwnershipRules{
+		projectsRepo:  projectsRepo,
+		assigneesRepo: assigneesRepo,
+		ttl:           ttl,
+		entries:       make(map[uuid.UUID]ownershipRulesEntry),
+	}
+}
+
+// Assign returns the owner of a new issue of a project from the event that
+// created it, along with the rule that matched. The first owner of the rule
+// that still exists is used. Both are nil when no rule assigns the issue.
+func (o *OwnershipRules) Assign(ctx context.Context, projectID uuid.UUID, event *models.ErrorEvent) (*models.Assignee, *ownership.Rule) {
+	entry := o.get(ctx, projectID)
+
+	rule := ownership.Match(entry.rules, event)
+	if rule == nil {
+		return nil, nil
+	}
+	for _, owner := range rule.Owners {
+		if assignee := entry.owners[owner]; assignee != nil {
+			return assignee, rule
+		}
+	}
+	return nil, nil
+}
+
+// Resolve looks up the owners of rules among the users and teams of a space.
+// Owners that don't exist are left out of the result.
+func (o *OwnershipRules) Resolve(ctx context.Context, spaceID uuid.UUID, rules []ownership.Rule) (map[ownership.Owner]*models.Assignee, error) {
+	owners := make(map[ownership.Owner]*models.Assignee)
+	for _, rule := range rules {
+		for _, owner := range rule.Owners {
+			if _, resolved := owners[owner]; resolved {
+				continue
+			}
+			assignee, err := o.assigneesRepo.Resolve(ctx, spaceID, owner.Type, owner.Identifier)
+			if err != nil {
+				return nil, err
+			}
+			owners[owner] = assignee
+		}
+	}
+
+	for owner, assignee := range owners {
+		if assignee == nil {
+			delete(owners, owner)
+		}
+	}
+	return owners, nil
+}
+
+// Invalidate drops the cached rules of a project after they changed
+func (o *OwnershipRules) Invalidate(projectID uuid.UUID) {
+	o.mu.Lock()
+	delete(o.entries, projectID)
+	o.mu.Unlock()
+}
+
+// get returns the rules of a project. When the rules can't be loaded the
+// previously loaded rules are kept, or none at all, so new issues are left
+// unassigned instead of failing ingestion.
+func (o *OwnershipRules) get(ctx context.Context, projectID uuid.UUID) ownershipRulesEntry {
+	o.mu.Lock()
+	entry, ok := o.entries[projectID]
+	o.mu.Unlock()
+	if ok && time.Since(entry.loadedAt) < o.ttl {
+		return entry
+	}
+
+	loaded, err := o.load(ctx, projectID)
+	if err != nil {
+		log.Printf("Failed to load ownership rules for project %s: %v", projectID, err)
+		loaded = entry
+	}
+	loaded.loadedAt = time.Now()
+
+	o.mu.Lock()
+	o.entries[projectID] = loaded
+	o.mu.Unlock()
+
+	return loaded
+}
+
+// load reads the rules from the project settings and resolves their owners
+func (o *OwnershipRules) load(ctx context.Context, projectID uuid.UUID) (ownershipRulesEntry, error) {
+	project, err := o.projectsRepo.GetByID(ctx, projectID)
+	if err != nil || project == nil {
+		return ownershipRulesEntry{}, err
+	}
+
+	_, rules, err := ownership.ParseSettings(project.Settings)
+	if err != nil || rules == nil {
+		return ownershipRulesEntry{}, err
+	}
+
+	owners, err := o.Resolve(ctx, project.SpaceID, rules)
+	if err != nil {
+		return ownershipRulesEntry{}, err
+	}
+	return ownershipRulesEntry{rules: rules, owners: owners}, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// SpoolReplayer periodically replays spooled events once ClickHouse is
+// healthy again
+type SpoolReplayer struct {
+	ingestService *IngestService
+	health        func() error
+	interval      time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSpoolReplayer creates a new spool replayer. health reports whether
+// ClickHouse is reachable.
+func NewSpoolReplayer(ingestService *IngestService, health func() error, interval time.Duration) *SpoolReplayer {
+	return &SpoolReplayer{
+		ingestService: ingestService,
+		health:        health,
+		interval:      interval,
+	}
+}
+
+// Start starts the replay loop
+func (r *SpoolReplayer) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.replay(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops the replay loop and waits for an in-flight replay to finish
+func (r *SpoolReplayer) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// replay replays the spool when it has data and ClickHouse is healthy. New
+// events are spooled behind the replayed ones until the spool is empty, so
+// replay continues as long as it makes progress.
+func (r *SpoolReplayer) replay(ctx context.Context) {
+	if r.ingestService.spool == nil || r.ingestService.spool.Empty() {
+		return
+	}
+	if err := r.health(); err != nil {
+		return
+	}
+
+	for ctx.Err() == nil {
+		replayed, err := r.ingestService.ReplaySpool(ctx)
+		if replayed > 0 {
+			log.Printf("Replayed %d spooled event batches", replayed)
+		}
+		if err != nil {
+			log.Printf("Spool replay stopped: %v", err)
+			return
+		}
+		if replayed == 0 || r.ingestService.spool.Empty() {
+			return
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/models"
+	"server/internal/repository"
+	"server/internal/sourcemap"
+	"server/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// sourceContextLines is the number of lines kept around a symbolicated line
+	sourceContextLines = 5
+
+	// missingMapTTL is how long a missing source map is remembered. Uploads
+	// invalidate the cache, so this only matters for other server instances.
+	missingMapTTL = time.Minute
+)
+
+// Symbolicator rewrites minified JavaScript frames to their original file,
+// line, column and function using the source maps uploaded for the release
+type Symbolicator struct {
+	artifactsRepo *repository.ArtifactsRepository
+	store         storage.Store
+	cache         *sourceMapCache
+}
+
+// NewSymbolicator creates a new symbolicator keeping up to cacheSize parsed
+// source maps in memory
+func NewSymbolicator(artifactsRepo *repository.ArtifactsRepository, store storage.Store, cacheSize int) *Symbolicator {
+	return &Symbolicator{
+		artifactsRepo: artifactsRepo,
+		store:         store,
+		cache:         newSourceMapCache(cacheSize),
+	}
+}
+
+// Symbolicate rewrites the JavaScript frames of the event's exceptions in
+// place. It reports whether any frame was rewritten. Frames without a source
+// map are left untouched.
+func (s *Symbolicator) Symbolicate(ctx context.Context, projectID uuid.UUID, event *models.IngestEvent) bool {
+	if event.ReleaseVersion == nil || *event.ReleaseVersion == "" {
+		return false
+	}
+	release := *event.ReleaseVersion
+
+	changed := false
+	for i := range event.Exceptions {
+		stacktrace := event.Exceptions[i].Stacktrace
+		if stacktrace == nil {
+			continue
+		}
+
+		frames := stacktrace.Frames
+		tokens := make([]*sourcemap.Token, len(frames))
+		for j := range frames {
+			if !isMinifiedFrame(frames[j]) {
+				continue
+			}
+
+			sourceMap := s.sourceMap(ctx, projectID, release, frames[j].AbsPath)
+			if sourceMap == nil {
+				continue
+			}
+
+			token, ok := sourceMap.Lookup(frames[j].Lineno-1, frames[j].Colno-1)
+			if !ok {
+				continue
+			}
+			tokens[j] = &token
+			applyToken(&frames[j], sourceMap, token)
+			changed = true
+		}
+
+		// The name at a call site is the name of the called function, so a
+		// frame's original function name comes from the frame that called it.
+		// Frames are ordered oldest first.
+		for j := 1; j < len(frames); j++ {
+			if tokens[j] != nil && tokens[j-1] != nil && tokens[j-1].Name != "" {
+				frames[j].Function = tokens[j-1].Name
+			}
+		}
+	}
+
+	return changed
+}
+
+// Invalidate drops the cached source maps of a release after its artifacts
+// changed
+func (s *Symbolicator) Invalidate(projectID uuid.UUID, release string) {
+	s.cache.removePrefix(sourceMapCacheKey(projectID, release, ""))
+}
+
+// sourceMap returns the parsed source map of a generated file, or nil when
+// none was uploaded
+func (s *Symbolicator) sourceMap(ctx context.Context, projectID uuid.UUID, release, fileURL string) *sourcemap.Map {
+	key := sourceMapCacheKey(projectID, release, fileURL)
+	if sourceMap, ok := s.cache.get(key); ok {
+		return sourceMap
+	}
+
+	sourceMap, err := s.loadSourceMap(ctx, projectID, release, fileURL)
+	if err != nil {
+		log.Printf("Failed to load source map for %s of project %s release %s: %v", fileURL, projectID, release, err)
+	}
+	s.cache.add(key, sourceMap)
+	return sourceMap
+}
+
+// loadSourceMap finds the source map of a generated file. The map referenced
+// by the file's sourceMappingURL comment is preferred; otherwise the map is
+// expected next to the file with a ".map" suffix.
+func (s *Symbolicator) loadSourceMap(ctx context.Context, projectID uuid.UUID, release, fileURL string) (*sourcemap.Map, error) {
+	mapURL := fileURL + ".map"
+
+	generated, err := s.readArtifact(ctx, projectID, release, fileURL)
+	if err != nil {
+		return nil, err
+	}
+	if generated != nil {
+		if reference := sourcemap.SourceMappingURL(generated); reference != "" {
+			if strings.HasPrefix(reference, "data:") {
+				data, err := decodeDataURL(reference)
+				if err != nil {
+					return nil, err
+				}
+				return sourcemap.Parse(data)
+			}
+			mapURL = resolveURL(fileURL, reference)
+		}
+	}
+
+	data, err := s.readArtifact(ctx, projectID, release, mapURL)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return sourcemap.Parse(data)
+}
+
+// readArtifact returns the content of the artifact uploaded for fileURL, or
+// nil when there is none
+func (s *Symbolicator) readArtifact(ctx context.Context, projectID uuid.UUID, release, fileURL string) ([]byte, error) {
+	for _, name := range artifactNames(fileURL) {
+		artifact, err := s.artifactsRepo.GetByName(ctx, projectID, release, name)
+		if err != nil {
+			return nil, err
+		}
+		if artifact == nil {
+			continue
+		}
+
+		body, err := s.store.Get(ctx, artifact.StorageKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open artifact %s: %w", name, err)
+		}
+		defer body.Close()
+
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact %s: %w", name, err)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// artifactNames returns the names an artifact for fileURL may be uploaded
+// under: the URL itself and the URL with the scheme and host replaced by "~"
+func artifactNames(fileURL string) []string {
+	names := []string{fileURL}
+
+	parsed, err := url.Parse(fileURL)
+	if err != nil || parsed.Path == "" {
+		return names
+	}
+
+	tilde := "~" + parsed.EscapedPath()
+	if parsed.RawQuery != "" {
+		names = append(names, tilde+"?"+parsed.RawQuery)
+	}
+	if tilde != fileURL {
+		names = append(names, tilde)
+	}
+	return names
+}
+
+// resolveURL resolves a sourceMappingURL relative to the generated file
+func resolveURL(fileURL, reference string) string {
+	base, err := url.Parse(fileURL)
+	if err != nil {
+		return path.Join(path.Dir(fileURL), reference)
+	}
+	ref, err := url.Parse(reference)
+	if err != nil {
+		return path.Join(path.Dir(fileURL), reference)
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// decodeDataURL decodes an inline "data:application/json;base64," source map
+func decodeDataURL(dataURL string) ([]byte, error) {
+	comma := strings.Index(dataURL, ",")
+	if comma < 0 {
+		return nil, fmt.Errorf("invalid data URL")
+	}
+
+	header, payload := dataURL[:comma], dataURL[comma+1:]
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data URL: %w", err)
+	}
+	return []byte(decoded), nil
+}
+
+// isMinifiedFrame reports whether a frame points into a JavaScript file with
+// a line and column that a source map can resolve
+func isMinifiedFrame(frame models.StackFrame) bool {
+	if frame.Lineno <= 0 || frame.Colno <= 0 || frame.AbsPath == "" {
+		return false
+	}
+
+	file := frame.AbsPath
+	if end := strings.IndexAny(file, "?#"); end >= 0 {
+		file = file[:end]
+	}
+	return strings.HasSuffix(file, ".js") || strings.HasSuffix(file, ".mjs") || strings.HasSuffix(file, ".cjs")
+}
+
+// applyToken rewrites a frame to the original position of token
+func applyToken(frame *models.StackFrame, sourceMap *sourcemap.Map, token sourcemap.Token) {
+	frame.Filename = token.Source
+	frame.AbsPath = token.Source
+	frame.Lineno = token.Line + 1
+	frame.Colno = token.Column + 1
+
+	if strings.Contains(token.Source, "/node_modules/") {
+		inApp := false
+		frame.InApp = &inApp
+	}
+
+	frame.ContextLine, frame.PreContext, frame.PostContext = "", nil, nil
+	lines := sourceMap.SourceLines(token)
+	if token.Line >= len(lines) {
+		return
+	}
+
+	start := token.Line - sourceContextLines
+	if start < 0 {
+		start = 0
+	}
+	end := token.Line + 1 + sourceContextLines
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	frame.PreContext = append([]string(nil), lines[start:token.Line]...)
+	frame.ContextLine = lines[token.Line]
+	frame.PostContext = append([]string(nil), lines[token.Line+1:end]...)
+}
+
+// sourceMapCacheKey builds the cache key of a generated file
+func sourceMapCacheKey(projectID uuid.UUID, release, fileURL string) string {
+	return projectID.String() + "\x00" + release + "\x00" + fileURL
+}
+
+// sourceMapCache is an LRU cache of parsed source maps. Missing maps are
+// cached as nil for missingMapTTL.
+type sourceMapCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+// sourceMapCacheEntry is a cached source map
+type sourceMapCacheEntry struct {
+	key       string
+	sourceMap *sourcemap.Map
+	expiresAt time.Time // Zero for entries that don't expire
+}
+
+func newSourceMapCache(capacity int) *sourceMapCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &sourceMapCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached map for key and whether there was an entry
+func (c *sourceMapCache) get(key string) (*sourcemap.Map, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*sourceMapCacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.sourceMap, true
+}
+
+// add caches a map, evicting the least recently used entry when full
+func (c *sourceMapCache) add(key string, sourceMap *sourcemap.Map) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &sourceMapCacheEntry{key: key, sourceMap: sourceMap}
+	if sourceMap == nil {
+		entry.expiresAt = time.Now().Add(missingMapTTL)
+	}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sourceMapCacheEntry).key)
+	}
+}
+
+// removePrefix drops all entries whose key starts with prefix
+func (c *sourceMapCache) removePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Package sourcemap parses JavaScript source maps (revision 3) and maps
+// generated positions back to their original source.
+package sourcemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Token is the original position of a generated position. Lines and columns
+// are zero-based.
+type Token struct {
+	Source string
+	Line   int
+	Column int
+	Name   string
+
+	sourceIndex int
+}
+
+// Map is a parsed source map
+type Map struct {
+	File    string
+	Sources []string
+	Names   []string
+
+	mappings    []mapping
+	sourceLines [][]string
+}
+
+// mapping is a single segment of the mappings field
+type mapping struct {
+	generatedLine   int
+	generatedColumn int
+	source          int // -1 when the segment has no source
+	line            int
+	column          int
+	name            int // -1 when the segment has no name
+}
+
+// rawMap is the JSON representation of a source map
+type rawMap struct {
+	Version        int               `json:"version"`
+	File           string            `json:"file"`
+	SourceRoot     string            `json:"sourceRoot"`
+	Sources        []string          `json:"sources"`
+	SourcesContent []*string         `json:"sourcesContent"`
+	Names          []string          `json:"names"`
+	Mappings       string            `json:"mappings"`
+	Sections       []json.RawMessage `json:"sections"`
+}
+
+// Parse parses a source map
+func Parse(data []byte) (*Map, error) {
+	// Maps may start with an XSSI guard
+	data = bytes.TrimPrefix(data, []byte(")]}'"))
+
+	var raw rawMap
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", raw.Version)
+	}
+	if len(raw.Sections) > 0 {
+		return nil, fmt.Errorf("indexed source maps are not supported")
+	}
+
+	sources := make([]string, len(raw.Sources))
+	for i, source := range raw.Sources {
+		if raw.SourceRoot != "" && !strings.Contains(source, "://") && !strings.HasPrefix(source, "/") {
+			source = strings.TrimSuffix(raw.SourceRoot, "/") + "/" + source
+		}
+		sources[i] = source
+	}
+
+	mappings, err := decodeMappings(raw.Mappings, len(sources), len(raw.Names))
+	if err != nil {
+		return nil, err
+	}
+
+	// Embedded sources are split once so lookups can share the map
+	sourceLines := make([][]string, len(sources))
+	for i, content := range raw.SourcesContent {
+		if i < len(sourceLines) && content != nil {
+			sourceLines[i] = strings.Split(strings.ReplaceAll(*content, "\r\n", "\n"), "\n")
+		}
+	}
+
+	return &Map{
+		File:        raw.File,
+		Sources:     sources,
+		Names:       raw.Names,
+		mappings:    mappings,
+		sourceLines: sourceLines,
+	}, nil
+}
+
+// Lookup returns the original position of a zero-based generated position.
+// It uses the closest segment at or before the column on the same line.
+func (m *Map) Lookup(line, column int) (Token, bool) {
+	// First mapping past the position
+	index := sort.Search(len(m.mappings), func(i int) bool {
+		mapping := m.mappings[i]
+		if mapping.generatedLine != line {
+			return mapping.generatedLine > line
+		}
+		return mapping.generatedColumn > column
+	})
+	if index == 0 {
+		return Token{}, false
+	}
+
+	mapping := m.mappings[index-1]
+	if mapping.generatedLine != line || mapping.source < 0 {
+		return Token{}, false
+	}
+
+	token := Token{
+		Source:      m.Sources[mapping.source],
+		Line:        mapping.line,
+		Column:      mapping.column,
+		sourceIndex: mapping.source,
+	}
+	if mapping.name >= 0 {
+		token.Name = m.Names[mapping.name]
+	}
+	return token, true
+}
+
+// SourceLines returns the embedded source of the token's file split into
+// lines, or nil when the map doesn't embed it
+func (m *Map) SourceLines(token Token) []string {
+	if token.sourceIndex < 0 || token.sourceIndex >= len(m.sourceLines) {
+		return nil
+	}
+	return m.sourceLines[token.sourceIndex]
+}
+
+// decodeMappings decodes the VLQ encoded mappings field
+func decodeMappings(encoded string, sources, names int) ([]mapping, error) {
+	var mappings []mapping
+	var source, line, column, name int
+
+	for generatedLine, group := range strings.Split(encoded, ";") {
+		generatedColumn := 0
+
+		for _, segment := range strings.Split(group, ",") {
+			if segment == "" {
+				continue
+			}
+
+			fields, err := decodeVLQ(segment)
+			if err != nil {
+				return nil, err
+			}
+
+			generatedColumn += fields[0]
+			current := mapping{
+				generatedLine:   generatedLine,
+				generatedColumn: generatedColumn,
+				source:          -1,
+				name:            -1,
+			}
+
+			switch len(fields) {
+			case 1:
+			case 4, 5:
+				source += fields[1]
+				line += fields[2]
+				column += fields[3]
+				if source < 0 || source >= sources {
+					return nil, fmt.Errorf("invalid source index %d", source)
+				}
+				current.source = source
+				current.line = line
+				current.column = column
+
+				if len(fields) == 5 {
+					name += fields[4]
+					if name < 0 || name >= names {
+						return nil, fmt.Errorf("invalid name index %d", name)
+					}
+					current.name = name
+				}
+			default:
+				return nil, fmt.Errorf("invalid mapping segment %q", segment)
+			}
+
+			mappings = append(mappings, current)
+		}
+	}
+
+	// Segments are usually sorted already, but the spec doesn't require it
+	sort.SliceStable(mappings, func(i, j int) bool {
+		if mappings[i].generatedLine != mappings[j].generatedLine {
+			return mappings[i].generatedLine < mappings[j].generatedLine
+		}
+		return mappings[i].generatedColumn < mappings[j].generatedColumn
+	})
+	return mappings, nil
+}
+
+// base64Values maps base64 characters to their value
+var base64Values = func() [256]int {
+	var values [256]int
+	for i := range values {
+		values[i] = -1
+	}
+	for i, c := range "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/" {
+		values[c] = i
+	}
+	return values
+}()
+
+// decodeVLQ decodes the base64 VLQ values of a segment
+func decodeVLQ(segment string) ([]int, error) {
+	var values []int
+	value, shift := 0, 0
+
+	for i := 0; i < len(segment); i++ {
+		digit := base64Values[segment[i]]
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base64 character %q in mappings", segment[i])
+		}
+
+		value += (digit & 0x1f) << shift
+		if digit&0x20 != 0 {
+			shift += 5
+			if shift > 30 {
+				return nil, fmt.Errorf("VLQ value too large in mappings")
+			}
+			continue
+		}
+
+		// The lowest bit is the sign
+		if value&1 != 0 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+
+	if shift != 0 {
+		return nil, fmt.Errorf("truncated VLQ value in mappings")
+	}
+	return values, nil
+}
+
+// SourceMappingURL returns the URL in the "//# sourceMappingURL=" comment at
+// the end of a generated file, or "" when there is none
+func SourceMappingURL(generated []byte) string {
+	// The comment is on one of the last lines, so only the tail is scanned
+	if len(generated) > 4096 {
+		generated = generated[len(generated)-4096:]
+	}
+
+	lines := strings.Split(string(generated), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		for _, prefix := range []string{"//# sourceMappingURL=", "//@ sourceMappingURL="} {
+			if strings.HasPrefix(line, prefix) {
+				return strings.TrimSpace(strings.TrimPrefix(line, prefix))
+			}
+		}
+		// Only trailing comments may carry the URL
+		if !strings.HasPrefix(line, "//") {
+			return ""
+		}
+	}
+	return ""
+}
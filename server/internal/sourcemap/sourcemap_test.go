@@ -0,0 +1,168 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// encodeVLQ encodes values as a base64 VLQ segment
+func encodeVLQ(values ...int) string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+	var builder strings.Builder
+	for _, value := range values {
+		vlq := value << 1
+		if value < 0 {
+			vlq = (-value << 1) | 1
+		}
+		for {
+			digit := vlq & 0x1f
+			vlq >>= 5
+			if vlq > 0 {
+				digit |= 0x20
+			}
+			builder.WriteByte(alphabet[digit])
+			if vlq == 0 {
+				break
+			}
+		}
+	}
+	return builder.String()
+}
+
+func TestDecodeVLQ(t *testing.T) {
+	tests := []struct {
+		segment  string
+		expected []int
+	}{
+		{"AAAA", []int{0, 0, 0, 0}},
+		{"C", []int{1}},
+		{"D", []int{-1}},
+		{"2H", []int{123}},
+		{"gBAAkC", []int{16, 0, 0, 34}},
+	}
+
+	for _, tt := range tests {
+		values, err := decodeVLQ(tt.segment)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", tt.segment, err)
+		}
+		if len(values) != len(tt.expected) {
+			t.Fatalf("Expected %v for %q, got %v", tt.expected, tt.segment, values)
+		}
+		for i := range values {
+			if values[i] != tt.expected[i] {
+				t.Errorf("Expected %v for %q, got %v", tt.expected, tt.segment, values)
+			}
+		}
+		if encoded := encodeVLQ(tt.expected...); encoded != tt.segment {
+			t.Errorf("Expected %v to encode to %q, got %q", tt.expected, tt.segment, encoded)
+		}
+	}
+
+	if _, err := decodeVLQ("g"); err == nil {
+		t.Error("Expected truncated VLQ to fail")
+	}
+}
+
+func TestMap_Lookup(t *testing.T) {
+	source := "export function loadUser(user) {\n  return user.id;\n}\n"
+
+	// Generated: "function a(b){return b.id}" on a single line.
+	// Segments: col 0 -> users.js 0:0, col 9 "a" -> 0:16 loadUser,
+	// col 14 -> 1:2, col 21 "b" -> 1:9 user
+	mappings := strings.Join([]string{
+		encodeVLQ(0, 0, 0, 0),
+		encodeVLQ(9, 0, 0, 16, 0),
+		encodeVLQ(5, 0, 1, -14),
+		encodeVLQ(7, 0, 0, 7, 1),
+	}, ",")
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"version":        3,
+		"file":           "app.min.js",
+		"sourceRoot":     "webpack:///",
+		"sources":        []string{"src/users.js"},
+		"sourcesContent": []string{source},
+		"names":          []string{"loadUser", "user"},
+		"mappings":       mappings,
+	})
+
+	sourceMap, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse source map: %v", err)
+	}
+
+	tests := []struct {
+		column   int
+		line     int
+		original int
+		name     string
+	}{
+		{0, 0, 0, ""},
+		{9, 0, 16, "loadUser"},
+		{12, 0, 16, "loadUser"},
+		{16, 1, 2, ""},
+		{23, 1, 9, "user"},
+	}
+
+	for _, tt := range tests {
+		token, ok := sourceMap.Lookup(0, tt.column)
+		if !ok {
+			t.Fatalf("Expected a mapping for column %d", tt.column)
+		}
+		if token.Source != "webpack:///src/users.js" {
+			t.Errorf("Expected source with source root, got %s", token.Source)
+		}
+		if token.Line != tt.line || token.Column != tt.original || token.Name != tt.name {
+			t.Errorf("Column %d: expected %d:%d %q, got %d:%d %q",
+				tt.column, tt.line, tt.original, tt.name, token.Line, token.Column, token.Name)
+		}
+	}
+
+	if _, ok := sourceMap.Lookup(1, 0); ok {
+		t.Error("Expected no mapping past the last generated line")
+	}
+
+	token, _ := sourceMap.Lookup(0, 23)
+	lines := sourceMap.SourceLines(token)
+	if len(lines) < 2 || lines[token.Line] != "  return user.id;" {
+		t.Errorf("Expected embedded source lines, got %v", lines)
+	}
+}
+
+func TestParse_RejectsInvalidMaps(t *testing.T) {
+	tests := map[string]string{
+		"version 2":     `{"version":2,"sources":[],"names":[],"mappings":""}`,
+		"indexed map":   `{"version":3,"sections":[{"offset":{"line":0,"column":0},"map":{}}]}`,
+		"bad source":    `{"version":3,"sources":[],"names":[],"mappings":"AAAA"}`,
+		"bad character": `{"version":3,"sources":["a.js"],"names":[],"mappings":"A!AA"}`,
+	}
+
+	for name, raw := range tests {
+		if _, err := Parse([]byte(raw)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSourceMappingURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		generated string
+		expected  string
+	}{
+		{"trailing comment", "function a(){}\n//# sourceMappingURL=app.min.js.map\n", "app.min.js.map"},
+		{"legacy syntax", "function a(){}\n//@ sourceMappingURL=/static/app.js.map", "/static/app.js.map"},
+		{"other trailing comments", "a();\n//# sourceMappingURL=app.js.map\n// built at 12:00\n", "app.js.map"},
+		{"not at the end", "//# sourceMappingURL=app.js.map\nfunction a(){}\n", ""},
+		{"none", "function a(){}", ""},
+	}
+
+	for _, tt := range tests {
+		if url := SourceMappingURL([]byte(tt.generated)); url != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, url)
+		}
+	}
+}
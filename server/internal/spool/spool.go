@@ -0,0 +1,464 @@
+// Package spool implements a segmented write-ahead log on local disk. It
+// buffers ingested events while ClickHouse is unavailable so they can be
+// replayed in order once it recovers.
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentPrefix = "segment-"
+	segmentSuffix = ".log"
+	cursorFile    = "cursor.json"
+
+	// recordHeaderSize is the length and CRC32 prefix of every record
+	recordHeaderSize = 8
+
+	// maxRecordSize protects replay from allocating huge buffers when a
+	// corrupted length prefix is read
+	maxRecordSize = 64 << 20 // 64MB
+)
+
+// ErrRecordTooLarge is returned when a record exceeds the maximum size
+var ErrRecordTooLarge = errors.New("spool record too large")
+
+// Options configures a spool
+type Options struct {
+	Dir         string        // Directory holding the segment files
+	SegmentSize int64         // Size at which the active segment is sealed
+	MaxSize     int64         // Total size cap, the oldest segments are dropped beyond it
+	MaxAge      time.Duration // Age cap, older sealed segments are dropped
+}
+
+// Stats describes the state of the spool
+type Stats struct {
+	Segments        int        `json:"segments"`
+	Bytes           int64      `json:"bytes"`
+	AppendedRecords uint64     `json:"appended_records"`
+	ReplayedRecords uint64     `json:"replayed_records"`
+	CorruptRecords  uint64     `json:"corrupt_records"`
+	DroppedSegments uint64     `json:"dropped_segments"`
+	Replaying       bool       `json:"replaying"`
+	LastReplayAt    *time.Time `json:"last_replay_at,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+}
+
+// segment is a single segment file
+type segment struct {
+	seq       uint64
+	path      string
+	size      int64
+	createdAt time.Time
+}
+
+// cursor records replay progress within the oldest segment so a restart
+// doesn't replay records twice
+type cursor struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// Spool is a segmented, checksummed write-ahead log
+type Spool struct {
+	opts Options
+
+	mu       sync.Mutex
+	segments []*segment // Sorted by sequence, the last one is active
+	active   *os.File
+	cursor   cursor
+	stats    Stats
+
+	replayMu sync.Mutex
+}
+
+// Open opens or creates a spool in the configured directory
+func Open(opts Options) (*Spool, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{opts: opts}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the active segment
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+// Append durably appends a record to the active segment
+func (s *Spool) Append(payload []byte) error {
+	if len(payload) > maxRecordSize {
+		return ErrRecordTooLarge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		if err := s.openSegment(); err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+
+	if _, err := s.active.Write(record); err != nil {
+		return fmt.Errorf("failed to write spool record: %w", err)
+	}
+	if err := s.active.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool segment: %w", err)
+	}
+
+	current := s.segments[len(s.segments)-1]
+	current.size += int64(len(record))
+	s.stats.AppendedRecords++
+
+	if current.size >= s.opts.SegmentSize {
+		if err := s.sealActive(); err != nil {
+			return err
+		}
+	}
+
+	s.enforceLimits()
+	return nil
+}
+
+// Empty reports whether the spool holds no records
+func (s *Spool) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, seg := range s.segments {
+		if seg.size > s.startOffset(seg) {
+			return false
+		}
+	}
+	return true
+}
+
+// Stats returns a snapshot of the spool statistics
+func (s *Spool) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Segments = len(s.segments)
+	for _, seg := range s.segments {
+		stats.Bytes += seg.size - s.startOffset(seg)
+	}
+	return stats
+}
+
+// Replay feeds all spooled records to fn in append order. Replay stops at the
+// first error returned by fn, and the failed record is retried on the next
+// call. Fully replayed segments are deleted.
+func (s *Spool) Replay(fn func(payload []byte) error) (int, error) {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	s.mu.Lock()
+	// Seal the active segment so new appends don't race with the replay
+	if s.active != nil && s.segments[len(s.segments)-1].size > 0 {
+		if err := s.sealActive(); err != nil {
+			s.mu.Unlock()
+			return 0, err
+		}
+	}
+	var sealed []*segment
+	for _, seg := range s.segments {
+		if s.active == nil || seg != s.segments[len(s.segments)-1] {
+			sealed = append(sealed, seg)
+		}
+	}
+	s.stats.Replaying = true
+	s.mu.Unlock()
+
+	replayed := 0
+	var replayErr error
+	for _, seg := range sealed {
+		n, err := s.replaySegment(seg, fn)
+		replayed += n
+		if err != nil {
+			replayErr = err
+			break
+		}
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	s.stats.Replaying = false
+	s.stats.LastReplayAt = &now
+	s.stats.LastError = ""
+	if replayErr != nil {
+		s.stats.LastError = replayErr.Error()
+	}
+	s.mu.Unlock()
+
+	return replayed, replayErr
+}
+
+// replaySegment replays a single sealed segment starting at the cursor
+func (s *Spool) replaySegment(seg *segment, fn func(payload []byte) error) (int, error) {
+	s.mu.Lock()
+	offset := s.startOffset(seg)
+	s.mu.Unlock()
+
+	file, err := os.Open(seg.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Dropped by the size or age limits while replaying
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to seek spool segment: %w", err)
+	}
+	reader := bufio.NewReader(file)
+
+	replayed := 0
+	for {
+		payload, size, err := readRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// A corrupt or torn record ends the readable part of the segment
+			log.Printf("Skipping rest of spool segment %s at offset %d: %v", seg.path, offset, err)
+			s.mu.Lock()
+			s.stats.CorruptRecords++
+			s.mu.Unlock()
+			break
+		}
+
+		if err := fn(payload); err != nil {
+			return replayed, err
+		}
+
+		offset += size
+		replayed++
+
+		s.mu.Lock()
+		s.stats.ReplayedRecords++
+		s.cursor = cursor{Segment: seg.seq, Offset: offset}
+		err = s.saveCursor()
+		s.mu.Unlock()
+		if err != nil {
+			return replayed, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeSegment(seg)
+	return replayed, nil
+}
+
+// readRecord reads and verifies a single record, returning the payload and
+// the number of bytes consumed
+func readRecord(reader io.Reader) ([]byte, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if err == io.EOF {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, fmt.Errorf("truncated record header: %w", err)
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	checksum := binary.BigEndian.Uint32(header[4:8])
+	if length > maxRecordSize {
+		return nil, 0, ErrRecordTooLarge
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, 0, fmt.Errorf("truncated record payload: %w", err)
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, 0, fmt.Errorf("record checksum mismatch")
+	}
+
+	return payload, int64(recordHeaderSize) + int64(length), nil
+}
+
+// load discovers existing segments and the replay cursor
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.opts.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat spool segment: %w", err)
+		}
+		s.segments = append(s.segments, &segment{
+			seq:       seq,
+			path:      filepath.Join(s.opts.Dir, name),
+			size:      info.Size(),
+			createdAt: info.ModTime(),
+		})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+
+	data, err := os.ReadFile(filepath.Join(s.opts.Dir, cursorFile))
+	if err == nil {
+		if err := json.Unmarshal(data, &s.cursor); err != nil {
+			log.Printf("Ignoring invalid spool cursor: %v", err)
+			s.cursor = cursor{}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read spool cursor: %w", err)
+	}
+
+	// Existing segments are sealed, appends after a restart go to a new one
+	return nil
+}
+
+// openSegment creates a new active segment. Must be called with mu held.
+func (s *Spool) openSegment() error {
+	seq := uint64(1)
+	if len(s.segments) > 0 {
+		seq = s.segments[len(s.segments)-1].seq + 1
+	}
+
+	path := filepath.Join(s.opts.Dir, fmt.Sprintf("%s%020d%s", segmentPrefix, seq, segmentSuffix))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+
+	s.active = file
+	s.segments = append(s.segments, &segment{seq: seq, path: path, createdAt: time.Now()})
+	return nil
+}
+
+// sealActive closes the active segment. Must be called with mu held.
+func (s *Spool) sealActive() error {
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	if err != nil {
+		return fmt.Errorf("failed to close spool segment: %w", err)
+	}
+	return nil
+}
+
+// enforceLimits drops the oldest sealed segments that exceed the size or age
+// caps. Must be called with mu held.
+func (s *Spool) enforceLimits() {
+	for len(s.segments) > 0 {
+		oldest := s.segments[0]
+		if s.active != nil && oldest == s.segments[len(s.segments)-1] {
+			return
+		}
+
+		var total int64
+		for _, seg := range s.segments {
+			total += seg.size
+		}
+
+		tooLarge := s.opts.MaxSize > 0 && total > s.opts.MaxSize
+		tooOld := s.opts.MaxAge > 0 && time.Since(oldest.createdAt) > s.opts.MaxAge
+		if !tooLarge && !tooOld {
+			return
+		}
+
+		log.Printf("Dropping spool segment %s (%d bytes) to stay within limits", oldest.path, oldest.size)
+		s.stats.DroppedSegments++
+		s.removeSegment(oldest)
+	}
+}
+
+// removeSegment deletes a segment file. Must be called with mu held.
+func (s *Spool) removeSegment(seg *segment) {
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove spool segment %s: %v", seg.path, err)
+	}
+
+	for i, existing := range s.segments {
+		if existing == seg {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+
+	if s.cursor.Segment == seg.seq {
+		s.cursor = cursor{}
+		if err := s.saveCursor(); err != nil {
+			log.Printf("Failed to reset spool cursor: %v", err)
+		}
+	}
+}
+
+// startOffset returns the replay offset within a segment. Must be called with
+// mu held.
+func (s *Spool) startOffset(seg *segment) int64 {
+	if s.cursor.Segment == seg.seq {
+		return s.cursor.Offset
+	}
+	return 0
+}
+
+// saveCursor atomically persists the replay cursor. Must be called with mu
+// held.
+func (s *Spool) saveCursor() error {
+	data, err := json.Marshal(s.cursor)
+	if err != nil {
+		return fmt.Errorf("failed to encode spool cursor: %w", err)
+	}
+
+	path := filepath.Join(s.opts.Dir, cursorFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write spool cursor: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save spool cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package spool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestSpool(t *testing.T, dir string, segmentSize int64) *Spool {
+	t.Helper()
+
+	s, err := Open(Options{Dir: dir, SegmentSize: segmentSize})
+	if err != nil {
+		t.Fatalf("Failed to open spool: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSpool_AppendAndReplayInOrder(t *testing.T) {
+	s := openTestSpool(t, t.TempDir(), 64)
+
+	for i := 0; i < 10; i++ {
+		if err := s.Append([]byte(fmt.Sprintf("record-%d", i))); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	if s.Stats().Segments < 2 {
+		t.Errorf("Expected records to span several segments, got %d", s.Stats().Segments)
+	}
+
+	var replayed []string
+	n, err := s.Replay(func(payload []byte) error {
+		replayed = append(replayed, string(payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if n != 10 {
+		t.Errorf("Expected 10 replayed records, got %d", n)
+	}
+	for i, record := range replayed {
+		if record != fmt.Sprintf("record-%d", i) {
+			t.Errorf("Expected record-%d at position %d, got %s", i, i, record)
+		}
+	}
+
+	if !s.Empty() {
+		t.Error("Expected spool to be empty after replay")
+	}
+	if stats := s.Stats(); stats.Segments != 0 || stats.Bytes != 0 {
+		t.Errorf("Expected replayed segments to be removed, got %+v", stats)
+	}
+}
+
+func TestSpool_ReplayResumesAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, dir, 1024)
+
+	for i := 0; i < 5; i++ {
+		if err := s.Append([]byte(fmt.Sprintf("record-%d", i))); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	failure := errors.New("clickhouse unavailable")
+	n, err := s.Replay(func(payload []byte) error {
+		if string(payload) == "record-2" {
+			return failure
+		}
+		return nil
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected replay failure, got %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 records before the failure, got %d", n)
+	}
+	s.Close()
+
+	// Reopen to make sure progress survives a restart
+	reopened := openTestSpool(t, dir, 1024)
+	var replayed []string
+	if _, err := reopened.Replay(func(payload []byte) error {
+		replayed = append(replayed, string(payload))
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	expected := []string{"record-2", "record-3", "record-4"}
+	if fmt.Sprint(replayed) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, replayed)
+	}
+}
+
+func TestSpool_SkipsCorruptTail(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, dir, 1024)
+
+	for i := 0; i < 3; i++ {
+		if err := s.Append([]byte(fmt.Sprintf("record-%d", i))); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	s.Close()
+
+	// Flip a byte in the last record's payload
+	matches, _ := filepath.Glob(filepath.Join(dir, segmentPrefix+"*"))
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 segment, got %d", len(matches))
+	}
+	data, _ := os.ReadFile(matches[0])
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(matches[0], data, 0o644); err != nil {
+		t.Fatalf("Failed to corrupt segment: %v", err)
+	}
+
+	reopened := openTestSpool(t, dir, 1024)
+	var replayed []string
+	if _, err := reopened.Replay(func(payload []byte) error {
+		replayed = append(replayed, string(payload))
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	if len(replayed) != 2 {
+		t.Errorf("Expected 2 intact records, got %v", replayed)
+	}
+	if reopened.Stats().CorruptRecords != 1 {
+		t.Errorf("Expected 1 corrupt record, got %d", reopened.Stats().CorruptRecords)
+	}
+}
+
+func TestSpool_EnforcesSizeLimit(t *testing.T) {
+	s, err := Open(Options{Dir: t.TempDir(), SegmentSize: 32, MaxSize: 96})
+	if err != nil {
+		t.Fatalf("Failed to open spool: %v", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 20; i++ {
+		if err := s.Append([]byte(fmt.Sprintf("record-%02d-payload", i))); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	stats := s.Stats()
+	if stats.Bytes > 96+32 {
+		t.Errorf("Expected spool to stay near its size cap, got %d bytes", stats.Bytes)
+	}
+	if stats.DroppedSegments == 0 {
+		t.Error("Expected oldest segments to be dropped")
+	}
+
+	var first string
+	if _, err := s.Replay(func(payload []byte) error {
+		if first == "" {
+			first = string(payload)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if first == "record-00-payload" {
+		t.Error("Expected the oldest records to have been dropped")
+	}
+}
@@ -0,0 +1,113 @@
+package stacktrace
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"server/internal/models"
+)
+
+const (
+	dotNetInnerSeparator = "--->"
+	dotNetInnerEnd       = "--- End of inner exception stack trace ---"
+)
+
+var (
+	// dotNetFramePattern matches "at Namespace.Type.Method(Int32 id) in /src/File.cs:line 42"
+	dotNetFramePattern = regexp.MustCompile(`^at (.+?)\((.*?)\)(?: in (.+):line (\d+))?$`)
+
+	// dotNetLinePattern matches any frame with file information
+	dotNetLinePattern = regexp.MustCompile(`^\s*at .+\) in .+:line \d+$`)
+)
+
+// detectDotNet recognises .NET exception traces
+func detectDotNet(lines []string) bool {
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == dotNetInnerEnd || strings.Contains(trimmed, " "+dotNetInnerSeparator+" ") || dotNetLinePattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDotNet parses the output of Exception.ToString(). The header lists the
+// outer exception followed by its inner exceptions, and the frames of the
+// innermost exception are printed first, each block closed by an "End of
+// inner exception stack trace" marker.
+func parseDotNet(lines []string) []models.Exception {
+	var chain []models.Exception
+	sections := [][]models.StackFrame{nil}
+	inFrames := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			continue
+		case trimmed == dotNetInnerEnd:
+			sections = append(sections, nil)
+		case strings.HasPrefix(trimmed, "--- End of stack trace from previous location"):
+			continue
+		case strings.HasPrefix(trimmed, "at "):
+			if frame, ok := dotNetFrame(trimmed); ok {
+				sections[len(sections)-1] = append(sections[len(sections)-1], frame)
+				inFrames = true
+			}
+		case inFrames:
+			continue
+		case strings.HasPrefix(trimmed, dotNetInnerSeparator) || len(chain) == 0:
+			for _, part := range strings.Split(trimmed, dotNetInnerSeparator) {
+				if part = strings.TrimSpace(part); part != "" {
+					chain = append(chain, parseHeader(part))
+				}
+			}
+		default:
+			// Multi-line exception messages
+			chain[len(chain)-1].Value += "\n" + trimmed
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil
+	}
+
+	// The first block of frames belongs to the innermost exception
+	for i, frames := range sections {
+		index := len(chain) - 1 - i
+		if index < 0 || len(frames) == 0 {
+			continue
+		}
+		reverseFrames(frames)
+		chain[index].Stacktrace = &models.Stacktrace{Frames: frames}
+	}
+	return chain
+}
+
+// dotNetFrame parses a single frame line
+func dotNetFrame(line string) (models.StackFrame, bool) {
+	match := dotNetFramePattern.FindStringSubmatch(line)
+	if match == nil {
+		return models.StackFrame{}, false
+	}
+
+	frame := models.StackFrame{Function: match[1], Filename: match[3], AbsPath: match[3]}
+	frame.Lineno, _ = strconv.Atoi(match[4])
+
+	// Split "Namespace.Type.Method[T]" into the type and the method
+	name := match[1]
+	if bracket := strings.Index(name, "["); bracket > 0 {
+		name = name[:bracket]
+	}
+	if dot := strings.LastIndex(name, "."); dot > 0 {
+		frame.Module = name[:dot]
+		frame.Function = match[1][dot+1:]
+	}
+
+	if strings.HasPrefix(frame.Module, "System.") || strings.HasPrefix(frame.Module, "Microsoft.") {
+		frame.InApp = inApp(false)
+	}
+	return frame, true
+}
@@ -0,0 +1,128 @@
+package stacktrace
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"server/internal/models"
+)
+
+var (
+	// goGoroutinePattern matches "goroutine 1 [running]:"
+	goGoroutinePattern = regexp.MustCompile(`^goroutine \d+ \[[^\]]*\]:$`)
+
+	// goPanicPattern matches the panic and fatal error headers
+	goPanicPattern = regexp.MustCompile(`^\s*(panic|fatal error): (.*?)(?: \[recovered\])?$`)
+
+	// goLocationPattern matches "\t/path/file.go:42 +0x1d"
+	goLocationPattern = regexp.MustCompile(`^\s+(.+?):(\d+)(?: \+0x[0-9a-f]+)?$`)
+
+	// goSourcePattern matches any location line of a Go trace
+	goSourcePattern = regexp.MustCompile(`^\s+.+\.go:\d+(?: \+0x[0-9a-f]+)?$`)
+)
+
+// detectGo recognises Go panics and goroutine dumps
+func detectGo(lines []string) bool {
+	return anyLine(lines, goGoroutinePattern) && anyLine(lines, goSourcePattern)
+}
+
+// parseGo parses a Go panic or goroutine dump. Only the first goroutine, the
+// one that panicked, is kept as the stack of the exception.
+func parseGo(lines []string) []models.Exception {
+	var panics []models.Exception
+	var frames []models.StackFrame
+	var pending *models.StackFrame
+	goroutines := 0
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case goroutines == 0:
+			if match := goPanicPattern.FindStringSubmatch(line); match != nil {
+				panics = append(panics, goPanic(match[1], match[2]))
+			} else if goGoroutinePattern.MatchString(trimmed) {
+				goroutines++
+			} else if len(panics) == 0 && trimmed != "" && !strings.HasPrefix(trimmed, "[") {
+				// Dumps triggered by signals, e.g. "SIGQUIT: quit"
+				panics = append(panics, parseHeader(trimmed))
+			}
+		case goGoroutinePattern.MatchString(trimmed):
+			goroutines++
+		case goroutines > 1:
+			// Only the first goroutine is structured
+		case trimmed == "" || strings.HasPrefix(trimmed, "..."):
+			pending = nil
+		case strings.HasPrefix(trimmed, "created by "):
+			pending = nil
+		case pending != nil && goLocationPattern.MatchString(line):
+			match := goLocationPattern.FindStringSubmatch(line)
+			pending.AbsPath = match[1]
+			pending.Filename = match[1]
+			pending.Lineno, _ = strconv.Atoi(match[2])
+			frames = append(frames, *pending)
+			pending = nil
+		default:
+			frame := goFrame(trimmed)
+			pending = &frame
+		}
+	}
+
+	if goroutines == 0 {
+		return nil
+	}
+	if len(panics) == 0 {
+		panics = append(panics, models.Exception{Type: "goroutine dump"})
+	}
+
+	// A panic raised while recovering from another is printed last, so it is
+	// the raised exception and the earlier panics are its causes
+	for i, j := 0, len(panics)-1; i < j; i, j = i+1, j-1 {
+		panics[i], panics[j] = panics[j], panics[i]
+	}
+
+	reverseFrames(frames)
+	if len(frames) > 0 {
+		panics[0].Stacktrace = &models.Stacktrace{Frames: frames}
+	}
+	return panics
+}
+
+// goPanic converts a panic header into an exception
+func goPanic(kind, message string) models.Exception {
+	if kind == "fatal error" {
+		return models.Exception{Type: kind, Value: message}
+	}
+	if strings.HasPrefix(message, "runtime error: ") {
+		return models.Exception{Type: "runtime error", Value: strings.TrimPrefix(message, "runtime error: ")}
+	}
+	return models.Exception{Type: "panic", Value: message}
+}
+
+// goFrame parses a function line such as "main.(*Server).handle(0x0, 0x1)"
+func goFrame(line string) models.StackFrame {
+	name := line
+	if strings.HasSuffix(name, ")") {
+		if paren := strings.LastIndex(name, "("); paren > 0 {
+			name = name[:paren]
+		}
+	}
+
+	// The package path ends at the first dot after the last slash
+	frame := models.StackFrame{Function: name}
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		frame.Module = name[:slash+1+dot]
+		frame.Function = name[slash+1+dot+1:]
+	}
+
+	switch {
+	case frame.Module == "main":
+		frame.InApp = inApp(true)
+	case frame.Module != "" && !strings.Contains(strings.SplitN(frame.Module, "/", 2)[0], "."):
+		// Standard library and runtime packages have no domain in their path
+		frame.InApp = inApp(false)
+	}
+	return frame
+}
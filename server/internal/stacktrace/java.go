@@ -0,0 +1,71 @@
+package stacktrace
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"server/internal/models"
+)
+
+var (
+	// javaFramePattern matches "at com.example.Service.process(Service.java:42)"
+	// including module prefixes such as "java.base/"
+	javaFramePattern = regexp.MustCompile(`^\s*at (?:[\w$.@-]*/)*([\w$.<>-]+)\.([\w$<>-]+)\(([^()]*)\)$`)
+
+	// javaLocationPattern matches the locations only JVM traces use
+	javaLocationPattern = regexp.MustCompile(`\((?:[\w$-]+\.(?:java|kt|scala|groovy|clj):\d+|Native Method|Unknown Source)\)$`)
+
+	// javaThreadPrefix matches the `Exception in thread "main" ` prefix
+	javaThreadPrefix = regexp.MustCompile(`^Exception in thread ".*?" `)
+
+	// javaLibraryPrefixes are packages of the JDK and language runtimes
+	javaLibraryPrefixes = []string{"java.", "javax.", "jdk.", "sun.", "com.sun.", "kotlin.", "kotlinx.", "scala."}
+)
+
+// detectJava recognises Java, Kotlin and other JVM traces
+func detectJava(lines []string) bool {
+	for _, line := range lines {
+		if javaFramePattern.MatchString(line) && javaLocationPattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJava parses a JVM stack trace with "Caused by:" chains
+func parseJava(lines []string) []models.Exception {
+	if len(lines) > 0 {
+		lines = append([]string{javaThreadPrefix.ReplaceAllString(lines[0], "")}, lines[1:]...)
+	}
+	return parseChained(lines, javaFrame)
+}
+
+// javaFrame parses a single JVM frame
+func javaFrame(line string) (models.StackFrame, bool) {
+	match := javaFramePattern.FindStringSubmatch(line)
+	if match == nil {
+		return models.StackFrame{}, false
+	}
+
+	frame := models.StackFrame{Module: match[1], Function: match[2]}
+
+	location := match[3]
+	if colon := strings.LastIndex(location, ":"); colon > 0 {
+		if lineno, err := strconv.Atoi(location[colon+1:]); err == nil {
+			frame.Lineno = lineno
+			location = location[:colon]
+		}
+	}
+	if location != "Native Method" && location != "Unknown Source" {
+		frame.Filename = location
+	}
+
+	for _, prefix := range javaLibraryPrefixes {
+		if strings.HasPrefix(frame.Module, prefix) {
+			frame.InApp = inApp(false)
+			break
+		}
+	}
+	return frame, true
+}
@@ -0,0 +1,129 @@
+package stacktrace
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"server/internal/models"
+)
+
+var (
+	// v8FramePattern matches "at async Service.load (/app/src/users.js:42:17)"
+	v8FramePattern = regexp.MustCompile(`^at (?:async )?(?:new )?(.+?) \((.+)\)$`)
+
+	// v8BareFramePattern matches "at /app/src/index.js:10:3"
+	v8BareFramePattern = regexp.MustCompile(`^at (?:async )?(.+)$`)
+
+	// v8DetectPattern matches V8 frames with a line and column
+	v8DetectPattern = regexp.MustCompile(`^\s*at .*:\d+:\d+\)?$`)
+
+	// spiderMonkeyFramePattern matches "load@https://example.com/app.js:42:17"
+	// as printed by Firefox and Safari
+	spiderMonkeyFramePattern = regexp.MustCompile(`^(.*?)@(.*?(?::\d+(?::\d+)?|\[native code\]))$`)
+
+	// jsLocationPattern splits "file:line:column"
+	jsLocationPattern = regexp.MustCompile(`^(.*?)(?::(\d+))?(?::(\d+))?$`)
+)
+
+// detectV8 recognises Node.js and Chromium traces
+func detectV8(lines []string) bool {
+	return anyLine(lines, v8DetectPattern)
+}
+
+// parseV8 parses a V8 Error.stack
+func parseV8(lines []string) []models.Exception {
+	return parseChained(lines, v8Frame)
+}
+
+// detectSpiderMonkey recognises Firefox and Safari traces
+func detectSpiderMonkey(lines []string) bool {
+	for _, line := range lines {
+		if spiderMonkeyFramePattern.MatchString(strings.TrimSpace(line)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSpiderMonkey parses a SpiderMonkey or JavaScriptCore Error.stack. The
+// browsers leave out the message, so clients usually prepend it themselves.
+func parseSpiderMonkey(lines []string) []models.Exception {
+	return parseChained(lines, spiderMonkeyFrame)
+}
+
+// v8Frame parses a single V8 frame
+func v8Frame(line string) (models.StackFrame, bool) {
+	if !strings.HasPrefix(line, "at ") {
+		return models.StackFrame{}, false
+	}
+
+	var frame models.StackFrame
+	location := ""
+	if match := v8FramePattern.FindStringSubmatch(line); match != nil {
+		frame.Function = match[1]
+		location = match[2]
+	} else {
+		location = v8BareFramePattern.FindStringSubmatch(line)[1]
+	}
+
+	// Frames inside eval report the location of the eval call
+	if strings.HasPrefix(location, "eval at ") {
+		if open := strings.LastIndex(location, "("); open >= 0 {
+			location = strings.TrimSuffix(location[open+1:], ")")
+			if comma := strings.Index(location, "),"); comma >= 0 {
+				location = location[:comma]
+			}
+		}
+	}
+
+	jsLocation(&frame, location)
+	return frame, true
+}
+
+// spiderMonkeyFrame parses a single SpiderMonkey or JavaScriptCore frame
+func spiderMonkeyFrame(line string) (models.StackFrame, bool) {
+	match := spiderMonkeyFramePattern.FindStringSubmatch(line)
+	if match == nil {
+		return models.StackFrame{}, false
+	}
+
+	// Async frames are prefixed with their cause, e.g. "promise callback*load"
+	function := match[1]
+	if star := strings.LastIndex(function, "*"); star >= 0 {
+		function = function[star+1:]
+	}
+
+	frame := models.StackFrame{Function: function}
+	if function == "global code" {
+		frame.Function = ""
+	}
+	jsLocation(&frame, match[2])
+	return frame, true
+}
+
+// jsLocation fills the file, line and column of a frame
+func jsLocation(frame *models.StackFrame, location string) {
+	if frame.Function == "<anonymous>" {
+		frame.Function = ""
+	}
+
+	// Built-ins such as "Promise.all (index 0)" have no source location
+	if location == "native" || strings.HasPrefix(location, "index ") {
+		frame.InApp = inApp(false)
+		return
+	}
+
+	match := jsLocationPattern.FindStringSubmatch(location)
+	frame.Filename = match[1]
+	frame.AbsPath = match[1]
+	frame.Lineno, _ = strconv.Atoi(match[2])
+	frame.Colno, _ = strconv.Atoi(match[3])
+
+	switch {
+	case frame.Filename == "[native code]", strings.HasPrefix(frame.Filename, "node:"):
+		frame.InApp = inApp(false)
+	case strings.HasPrefix(frame.Filename, "internal/"), strings.Contains(frame.Filename, "/node_modules/"):
+		frame.InApp = inApp(false)
+	}
+}
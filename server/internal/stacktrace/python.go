@@ -0,0 +1,106 @@
+package stacktrace
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"server/internal/models"
+)
+
+var (
+	// pythonFramePattern matches `  File "/app/main.py", line 10, in main`
+	pythonFramePattern = regexp.MustCompile(`^\s*File "(.+)", line (\d+)(?:, in (.+))?$`)
+
+	// pythonCaretPattern matches the error location markers of Python 3.11+
+	pythonCaretPattern = regexp.MustCompile(`^\s*[\^~]+\s*$`)
+)
+
+const pythonTracebackHeader = "Traceback (most recent call last):"
+
+// detectPython recognises Python tracebacks
+func detectPython(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) == pythonTracebackHeader {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePython parses a Python traceback, including chained exceptions
+// ("During handling of the above exception" and "The above exception was the
+// direct cause"). Python prints the raised exception last.
+func parsePython(lines []string) []models.Exception {
+	var exceptions []models.Exception
+	var frames []models.StackFrame
+	inTraceback := false
+	var current *models.Exception
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == pythonTracebackHeader:
+			inTraceback = true
+			frames = nil
+			current = nil
+		case strings.HasPrefix(trimmed, "During handling of the above exception") ||
+			strings.HasPrefix(trimmed, "The above exception was the direct cause"):
+			current = nil
+		case trimmed == "" || pythonCaretPattern.MatchString(line):
+			continue
+		case inTraceback && pythonFramePattern.MatchString(line):
+			match := pythonFramePattern.FindStringSubmatch(line)
+			frame := models.StackFrame{Filename: match[1], AbsPath: match[1], Function: match[3]}
+			frame.Lineno, _ = strconv.Atoi(match[2])
+			frame.Module = pythonModule(match[1])
+			frame.InApp = pythonInApp(match[1])
+			frames = append(frames, frame)
+		case inTraceback && strings.HasPrefix(line, " ") && len(frames) > 0:
+			// Source line of the previous frame
+			if frames[len(frames)-1].ContextLine == "" {
+				frames[len(frames)-1].ContextLine = trimmed
+			}
+		case inTraceback:
+			// The exception line ends the traceback
+			exception := parseHeader(trimmed)
+			if len(frames) > 0 {
+				exception.Stacktrace = &models.Stacktrace{Frames: frames}
+			}
+			exceptions = append(exceptions, exception)
+			current = &exceptions[len(exceptions)-1]
+			inTraceback = false
+		case current != nil:
+			// Multi-line exception messages
+			current.Value += "\n" + trimmed
+		}
+	}
+
+	// Python frames are already ordered from the oldest call, but the raised
+	// exception comes last
+	for i, j := 0, len(exceptions)-1; i < j; i, j = i+1, j-1 {
+		exceptions[i], exceptions[j] = exceptions[j], exceptions[i]
+	}
+	return exceptions
+}
+
+// pythonModule derives the module name from the file name
+func pythonModule(filename string) string {
+	name := filename[strings.LastIndexAny(filename, `/\`)+1:]
+	if strings.HasSuffix(name, ".py") {
+		return strings.TrimSuffix(name, ".py")
+	}
+	return ""
+}
+
+// pythonInApp marks installed packages and the standard library as not in-app
+func pythonInApp(filename string) *bool {
+	switch {
+	case strings.Contains(filename, "site-packages"), strings.Contains(filename, "dist-packages"):
+		return inApp(false)
+	case strings.Contains(filename, "/lib/python"), strings.HasPrefix(filename, "<frozen "):
+		return inApp(false)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package stacktrace
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"server/internal/models"
+)
+
+var (
+	// rubyHeaderPattern matches "/app/worker.rb:42:in `process': message (NoMethodError)"
+	rubyHeaderPattern = regexp.MustCompile("^(.+?):(\\d+):in [`'](.+?)': (.*) \\(([\\w:]+)\\)$")
+
+	// rubyFramePattern matches "from /app/worker.rb:10:in `block in run'"
+	rubyFramePattern = regexp.MustCompile("^(?:from )?(.+?):(\\d+):in [`'](.+?)'$")
+)
+
+// detectRuby recognises Ruby backtraces
+func detectRuby(lines []string) bool {
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if rubyHeaderPattern.MatchString(trimmed) || rubyFramePattern.MatchString(trimmed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRuby parses a Ruby backtrace. Ruby prints the raised exception first,
+// followed by its causes, each with the most recent call first.
+func parseRuby(lines []string) []models.Exception {
+	var exceptions []models.Exception
+	var current *models.Exception
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		if match := rubyHeaderPattern.FindStringSubmatch(trimmed); match != nil {
+			exceptions = append(exceptions, models.Exception{Type: match[5], Value: match[4]})
+			current = &exceptions[len(exceptions)-1]
+			current.Stacktrace = &models.Stacktrace{Frames: []models.StackFrame{rubyFrame(match[1], match[2], match[3])}}
+			continue
+		}
+
+		if match := rubyFramePattern.FindStringSubmatch(trimmed); match != nil {
+			if current == nil {
+				// Bare backtraces without an exception header
+				exceptions = append(exceptions, models.Exception{Stacktrace: &models.Stacktrace{}})
+				current = &exceptions[len(exceptions)-1]
+			}
+			current.Stacktrace.Frames = append(current.Stacktrace.Frames, rubyFrame(match[1], match[2], match[3]))
+			continue
+		}
+
+		// Exceptions printed without a location, continuation lines of
+		// multi-line messages are ignored
+		if current == nil {
+			exceptions = append(exceptions, parseHeader(trimmed))
+			current = &exceptions[len(exceptions)-1]
+			current.Stacktrace = &models.Stacktrace{}
+		}
+	}
+
+	for i := range exceptions {
+		if len(exceptions[i].Frames()) == 0 {
+			exceptions[i].Stacktrace = nil
+		}
+		reverseFrames(exceptions[i].Frames())
+	}
+	return exceptions
+}
+
+// rubyFrame builds a frame from a backtrace location
+func rubyFrame(file, line, label string) models.StackFrame {
+	frame := models.StackFrame{Filename: file, AbsPath: file, Function: label}
+	frame.Lineno, _ = strconv.Atoi(line)
+
+	// "Worker#process" and "Worker.process" carry the class name
+	if separator := strings.LastIndexAny(label, "#."); separator > 0 && !strings.Contains(label, " ") {
+		frame.Module = label[:separator]
+		frame.Function = label[separator+1:]
+	}
+
+	if strings.Contains(file, "/gems/") || strings.Contains(file, "/lib/ruby/") {
+		frame.InApp = inApp(false)
+	}
+	return frame
+}
@@ -0,0 +1,151 @@
+package stacktrace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRuntime_Fixtures(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		runtime    string
+		exceptions int
+		errorType  string
+		value      string
+		frames     int
+		// The frame that raised the exception
+		function string
+		module   string
+		filename string
+		lineno   int
+		// The first cause, if any
+		causeType   string
+		causeFrames int
+	}{
+		{
+			fixture: "go_panic.txt", runtime: "go", exceptions: 1,
+			errorType: "runtime error", value: "invalid memory address or nil pointer dereference", frames: 3,
+			function: "(*Service).Load", module: "github.com/acme/api/internal/users",
+			filename: "/home/app/internal/users/service.go", lineno: 42,
+		},
+		{
+			fixture: "go_goroutines.txt", runtime: "go", exceptions: 2,
+			errorType: "panic", value: "send on closed channel", frames: 1,
+			function: "worker", module: "main", filename: "/home/app/worker.go", lineno: 31,
+			causeType: "panic",
+		},
+		{
+			fixture: "js_v8.txt", runtime: "javascript", exceptions: 1,
+			errorType: "TypeError", value: "Cannot read properties of undefined (reading 'id')", frames: 5,
+			function: "UserService.load", filename: "/app/src/users.js", lineno: 42,
+		},
+		{
+			fixture: "js_spidermonkey.txt", runtime: "javascript", exceptions: 1,
+			errorType: "TypeError", value: "user is undefined", frames: 3,
+			function: "load", filename: "https://example.com/static/app.min.js", lineno: 1,
+		},
+		{
+			fixture: "js_jsc.txt", runtime: "javascript", exceptions: 1,
+			errorType: "TypeError", value: "undefined is not an object (evaluating 'user.id')", frames: 3,
+			function: "load", filename: "https://example.com/static/app.js", lineno: 42,
+		},
+		{
+			fixture: "python.txt", runtime: "python", exceptions: 2,
+			errorType: "orders.errors.OrderNotFound", value: "order order-42 does not exist", frames: 3,
+			function: "handle", module: "handlers", filename: "/app/orders/handlers.py", lineno: 42,
+			causeType: "KeyError", causeFrames: 1,
+		},
+		{
+			fixture: "java.txt", runtime: "java", exceptions: 2,
+			errorType: "java.lang.IllegalStateException", value: "Failed to process order 42", frames: 3,
+			function: "process", module: "com.acme.orders.OrderService", filename: "OrderService.java", lineno: 42,
+			causeType: "java.io.IOException", causeFrames: 2,
+		},
+		{
+			fixture: "kotlin.txt", runtime: "java", exceptions: 1,
+			errorType: "kotlin.KotlinNullPointerException", frames: 4,
+			function: "load", module: "com.acme.app.ProfileViewModel", filename: "ProfileViewModel.kt", lineno: 31,
+		},
+		{
+			fixture: "dotnet.txt", runtime: "dotnet", exceptions: 2,
+			errorType: "System.InvalidOperationException", value: "Failed to load order 42", frames: 3,
+			function: "Load", module: "Acme.Orders.OrderService", filename: "/src/Acme.Orders/OrderService.cs", lineno: 42,
+			causeType: "System.ArgumentNullException", causeFrames: 2,
+		},
+		{
+			fixture: "ruby.txt", runtime: "ruby", exceptions: 2,
+			errorType: "NoMethodError", value: "undefined method `id' for nil:NilClass", frames: 4,
+			function: "process", filename: "/app/lib/worker.rb", lineno: 42,
+			causeType: "Acme::RecordNotFound", causeFrames: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			exceptions, runtime := ParseRuntime(string(data))
+			if runtime != tt.runtime {
+				t.Errorf("Expected runtime %s, got %s", tt.runtime, runtime)
+			}
+			if len(exceptions) != tt.exceptions {
+				t.Fatalf("Expected %d exceptions, got %d", tt.exceptions, len(exceptions))
+			}
+
+			raised := exceptions[0]
+			if raised.Type != tt.errorType {
+				t.Errorf("Expected type %q, got %q", tt.errorType, raised.Type)
+			}
+			if raised.Value != tt.value {
+				t.Errorf("Expected value %q, got %q", tt.value, raised.Value)
+			}
+
+			frames := raised.Frames()
+			if len(frames) != tt.frames {
+				t.Fatalf("Expected %d frames, got %d", tt.frames, len(frames))
+			}
+			crashing := frames[len(frames)-1]
+			if crashing.Function != tt.function || crashing.Module != tt.module {
+				t.Errorf("Expected crashing frame %s %s, got %s %s", tt.module, tt.function, crashing.Module, crashing.Function)
+			}
+			if crashing.Filename != tt.filename || crashing.Lineno != tt.lineno {
+				t.Errorf("Expected crashing frame at %s:%d, got %s:%d", tt.filename, tt.lineno, crashing.Filename, crashing.Lineno)
+			}
+
+			if tt.causeType != "" {
+				cause := exceptions[1]
+				if cause.Type != tt.causeType {
+					t.Errorf("Expected cause %q, got %q", tt.causeType, cause.Type)
+				}
+				if len(cause.Frames()) != tt.causeFrames {
+					t.Errorf("Expected %d cause frames, got %d", tt.causeFrames, len(cause.Frames()))
+				}
+			}
+		})
+	}
+}
+
+func TestParseRuntime_InAppFrames(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "python.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	exceptions, _ := ParseRuntime(string(data))
+	frames := exceptions[0].Frames()
+
+	library := frames[1]
+	if library.InApp == nil || *library.InApp {
+		t.Errorf("Expected site-packages frame to be marked as not in-app, got %v", library.InApp)
+	}
+	if frames[2].InApp != nil {
+		t.Errorf("Expected application frame to be left undecided, got %v", *frames[2].InApp)
+	}
+	if frames[2].ContextLine != "order = repository.get(order_id)" {
+		t.Errorf("Expected context line to be captured, got %q", frames[2].ContextLine)
+	}
+}
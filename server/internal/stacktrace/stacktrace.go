@@ -0,0 +1,251 @@
+// Package stacktrace converts between plain text stack traces and structured
+// exceptions.
+package stacktrace
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"server/internal/models"
+)
+
+// causedByPrefix separates chained exceptions in rendered stack traces
+const causedByPrefix = "Caused by: "
+
+var (
+	// headerPattern matches "Type: value" lines where Type looks like a
+	// (possibly qualified) class name
+	headerPattern = regexp.MustCompile(`^([A-Za-z_$][\w$]*(?:[.:\\/][A-Za-z_$][\w$]*)*): ?(.*)$`)
+
+	// typePattern matches a bare, qualified exception type name
+	typePattern = regexp.MustCompile(`^[A-Za-z_$][\w$]*(?:[.:]+[A-Za-z_$][\w$]*)*(?:Error|Exception|Panic|Failure|Interrupt|Exit|Warning)$`)
+
+	// framePattern matches "at function (file:line:col)" frames
+	framePattern = regexp.MustCompile(`^\s*at (.+?) \((.*?)(?::(\d+))?(?::(\d+))?\)$`)
+
+	// bareFramePattern matches "at file:line:col" frames
+	bareFramePattern = regexp.MustCompile(`^\s*at (.*?)(?::(\d+))?(?::(\d+))?$`)
+)
+
+// runtimeParser recognises and parses the stack traces of one runtime
+type runtimeParser struct {
+	name   string
+	detect func(lines []string) bool
+	parse  func(lines []string) []models.Exception
+}
+
+// runtimeParsers are tried in order, so more distinctive formats come first
+var runtimeParsers = []runtimeParser{
+	{"go", detectGo, parseGo},
+	{"python", detectPython, parsePython},
+	{"ruby", detectRuby, parseRuby},
+	{"dotnet", detectDotNet, parseDotNet},
+	{"java", detectJava, parseJava},
+	{"javascript", detectV8, parseV8},
+	{"javascript", detectSpiderMonkey, parseSpiderMonkey},
+}
+
+// Parse extracts structured exceptions from a plain text stack trace. Go,
+// JavaScript (V8, SpiderMonkey and JavaScriptCore), Python, Java/Kotlin, .NET
+// and Ruby traces are recognised; anything else is parsed as a generic
+// "Type: value" header followed by "at function (file:line)" frames. It
+// returns nil when nothing could be recognised.
+func Parse(text string) []models.Exception {
+	exceptions, _ := ParseRuntime(text)
+	return exceptions
+}
+
+// ParseRuntime is like Parse but also returns the runtime the trace was
+// recognised as, or "generic"
+func ParseRuntime(text string) ([]models.Exception, string) {
+	lines := splitLines(text)
+
+	for _, parser := range runtimeParsers {
+		if !parser.detect(lines) {
+			continue
+		}
+		if exceptions := parser.parse(lines); len(exceptions) > 0 {
+			return exceptions, parser.name
+		}
+	}
+
+	return parseChained(lines, parseGenericFrame), "generic"
+}
+
+// parseChained parses traces made of "Type: value" headers, each followed by
+// its frames with the most recent call first. Causes are introduced by
+// "Caused by:"; "Suppressed:" blocks are skipped.
+func parseChained(lines []string, parseFrame func(line string) (models.StackFrame, bool)) []models.Exception {
+	var exceptions []models.Exception
+	var current *models.Exception
+	suppressed := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		// Suppressed exceptions are nested deeper than the causes of the
+		// exception they belong to
+		if suppressed {
+			if !strings.HasPrefix(line, causedByPrefix) {
+				continue
+			}
+			suppressed = false
+		}
+		if strings.HasPrefix(trimmed, "Suppressed: ") {
+			suppressed = true
+			continue
+		}
+
+		if frame, ok := parseFrame(trimmed); ok {
+			if current == nil {
+				exceptions = append(exceptions, models.Exception{})
+				current = &exceptions[len(exceptions)-1]
+			}
+			if current.Stacktrace == nil {
+				current.Stacktrace = &models.Stacktrace{}
+			}
+			current.Stacktrace.Frames = append(current.Stacktrace.Frames, frame)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "...") {
+			// Elided frames such as "... 12 more"
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, causedByPrefix) || current == nil || current.Stacktrace != nil {
+			exceptions = append(exceptions, parseHeader(strings.TrimPrefix(trimmed, causedByPrefix)))
+			current = &exceptions[len(exceptions)-1]
+			continue
+		}
+
+		// Multi-line exception messages
+		current.Value += "\n" + trimmed
+	}
+
+	// Text traces list the most recent call first
+	for i := range exceptions {
+		reverseFrames(exceptions[i].Frames())
+	}
+
+	if len(exceptions) == 1 && exceptions[0].Type == "" && len(exceptions[0].Frames()) == 0 {
+		return nil
+	}
+	return exceptions
+}
+
+// Format renders exceptions as a plain text stack trace with the most recent
+// call first and causes introduced by "Caused by:"
+func Format(exceptions []models.Exception) string {
+	var builder strings.Builder
+
+	for i, exception := range exceptions {
+		if i > 0 {
+			builder.WriteString(causedByPrefix)
+		}
+		builder.WriteString(exception.Title())
+		builder.WriteString("\n")
+
+		frames := exception.Frames()
+		for j := len(frames) - 1; j >= 0; j-- {
+			builder.WriteString("    at " + FormatFrame(frames[j]) + "\n")
+		}
+	}
+
+	return strings.TrimRight(builder.String(), "\n")
+}
+
+// FormatFrame renders a frame as "function (file:line:column)"
+func FormatFrame(frame models.StackFrame) string {
+	location := frame.Filename
+	if location == "" {
+		location = frame.AbsPath
+	}
+	if location == "" {
+		location = frame.Module
+	}
+	if frame.Lineno > 0 {
+		location = fmt.Sprintf("%s:%d", location, frame.Lineno)
+		if frame.Colno > 0 {
+			location = fmt.Sprintf("%s:%d", location, frame.Colno)
+		}
+	}
+
+	function := frame.Function
+	if function == "" {
+		function = "<anonymous>"
+	}
+	return fmt.Sprintf("%s (%s)", function, location)
+}
+
+// parseHeader parses an exception header line
+func parseHeader(line string) models.Exception {
+	if match := headerPattern.FindStringSubmatch(line); match != nil {
+		return models.Exception{Type: match[1], Value: match[2]}
+	}
+	if typePattern.MatchString(line) {
+		// Exceptions without a message, e.g. "java.lang.NullPointerException"
+		return models.Exception{Type: line}
+	}
+	return models.Exception{Value: line}
+}
+
+// parseGenericFrame parses "at function (file:line:col)" and "at file:line"
+// frames
+func parseGenericFrame(line string) (models.StackFrame, bool) {
+	if !strings.HasPrefix(line, "at ") {
+		return models.StackFrame{}, false
+	}
+
+	if match := framePattern.FindStringSubmatch(line); match != nil {
+		frame := models.StackFrame{Function: match[1], Filename: match[2]}
+		frame.Lineno, _ = strconv.Atoi(match[3])
+		frame.Colno, _ = strconv.Atoi(match[4])
+		if frame.Function == "<anonymous>" {
+			frame.Function = ""
+		}
+		return frame, true
+	}
+
+	match := bareFramePattern.FindStringSubmatch(line)
+	frame := models.StackFrame{Filename: match[1]}
+	frame.Lineno, _ = strconv.Atoi(match[2])
+	frame.Colno, _ = strconv.Atoi(match[3])
+	return frame, true
+}
+
+// splitLines splits text into lines, dropping trailing whitespace
+func splitLines(text string) []string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return lines
+}
+
+// anyLine reports whether any line matches the pattern
+func anyLine(lines []string, pattern *regexp.Regexp) bool {
+	for _, line := range lines {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// inApp returns a pointer to the in-app flag of a frame
+func inApp(value bool) *bool {
+	return &value
+}
+
+// reverseFrames reverses frames in place
+func reverseFrames(frames []models.StackFrame) {
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+}
@@ -0,0 +1,77 @@
+package stacktrace
+
+import (
+	"testing"
+
+	"server/internal/models"
+)
+
+func TestParse_GenericTrace(t *testing.T) {
+	text := "TypeError: Cannot read properties of undefined\n" +
+		"    at handle (src/handlers.js:42:7)\n" +
+		"    at main (src/app.js:10:3)\n" +
+		"Caused by: Error: timeout\n" +
+		"    at src/db.js:5"
+
+	exceptions := Parse(text)
+	if len(exceptions) != 2 {
+		t.Fatalf("Expected 2 exceptions, got %d", len(exceptions))
+	}
+
+	raised := exceptions[0]
+	if raised.Type != "TypeError" || raised.Value != "Cannot read properties of undefined" {
+		t.Errorf("Expected TypeError header, got %q / %q", raised.Type, raised.Value)
+	}
+
+	frames := raised.Frames()
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 frames, got %d", len(frames))
+	}
+	// Frames are stored oldest call first
+	if frames[0].Function != "main" || frames[1].Function != "handle" {
+		t.Errorf("Expected frames ordered main, handle, got %s, %s", frames[0].Function, frames[1].Function)
+	}
+	if frames[1].Filename != "src/handlers.js" || frames[1].Lineno != 42 || frames[1].Colno != 7 {
+		t.Errorf("Expected handlers.js:42:7, got %+v", frames[1])
+	}
+
+	cause := exceptions[1]
+	if cause.Type != "Error" || len(cause.Frames()) != 1 || cause.Frames()[0].Lineno != 5 {
+		t.Errorf("Expected cause with one frame, got %+v", cause)
+	}
+}
+
+func TestParse_UnrecognisedText(t *testing.T) {
+	if exceptions := Parse("something went wrong somewhere"); exceptions != nil {
+		t.Errorf("Expected no exceptions, got %+v", exceptions)
+	}
+}
+
+func TestFormat_RoundTrip(t *testing.T) {
+	exceptions := []models.Exception{
+		{
+			Type:  "ValueError",
+			Value: "bad input",
+			Stacktrace: &models.Stacktrace{Frames: []models.StackFrame{
+				{Filename: "app.py", Function: "main", Lineno: 10},
+				{Filename: "handlers.py", Function: "handle", Lineno: 42},
+			}},
+		},
+		{Type: "KeyError", Value: "'id'"},
+	}
+
+	expected := "ValueError: bad input\n" +
+		"    at handle (handlers.py:42)\n" +
+		"    at main (app.py:10)\n" +
+		"Caused by: KeyError: 'id'"
+
+	text := Format(exceptions)
+	if text != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, text)
+	}
+
+	parsed := Parse(text)
+	if len(parsed) != 2 || len(parsed[0].Frames()) != 2 || parsed[0].Frames()[1].Function != "handle" {
+		t.Errorf("Expected formatted trace to parse back, got %+v", parsed)
+	}
+}
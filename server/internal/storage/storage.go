@@ -0,0 +1,40 @@
+// Package storage stores binary blobs such as release artifacts on the local
+// disk or in an S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned when a key doesn't exist
+var ErrNotFound = errors.New("object not found")
+
+// Store is a key/value blob store
+type Store interface {
+	// Put stores the object under key, replacing any existing object
+	Put(ctx context.Context, key string, body io.Reader, size int64) error
+
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object stored under key. Deleting a missing key is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey reports whether key is a relative, slash separated path without
+// "." or ".." segments
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}